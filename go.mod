@@ -1,12 +1,13 @@
 module devbook
 
-go 1.15
+go 1.16
 
 require (
 	github.com/badoux/checkmail v1.2.1
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/joho/godotenv v1.3.0
 	golang.org/x/crypto v0.0.0-20201217014255-9d1352758620
 )
@@ -1,21 +1,97 @@
 package main
 
 import (
+	"context"
 	"devbook/src/config"
+	"devbook/src/database"
+	"devbook/src/logging"
+	"devbook/src/mail"
+	"devbook/src/persistence"
 	router "devbook/src/router"
+	"devbook/src/webhooks"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout time requests in progress have to complete on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 
 	config.Load()
 
-	router := router.GetRouter()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "role" {
+		role(os.Args[2:])
+		return
+	}
+
+	level, error := logging.ParseLevel(config.LogLevel)
+	if error != nil {
+		log.Fatal(error)
+	}
+	logger := logging.New(os.Stdout, level)
+	logging.SetDefault(logger)
+	log.SetFlags(0)
+	log.SetOutput(logger.Writer(logging.LevelError))
+
+	db, error := database.Connect()
+	if error != nil {
+		log.Fatal(error)
+	}
+	defer db.Close()
+
+	if config.AutoMigrate {
+		migrator, error := database.NewMigrator(db)
+		if error != nil {
+			log.Fatal(error)
+		}
+		if _, error = migrator.Up(); error != nil {
+			log.Fatal(error)
+		}
+	}
+
+	mailer, error := mail.NewMailer()
+	if error != nil {
+		log.Fatal(error)
+	}
+
+	repositories := persistence.NewRepositories(db)
+
+	dispatcher := webhooks.NewDispatcher(repositories.Webhooks, repositories.Users, repositories.Publications)
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.Port),
+		Handler: router.GetRouter(repositories, mailer, dispatcher),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
 
-	log.Fatal(http.ListenAndServe(
-		fmt.Sprintf(":%d", config.Port), router))
+	logger.Info("listening", logging.Fields{"port": config.Port})
 
-	fmt.Sprintf("Listening on port %d", config.Port)
-}
\ No newline at end of file
+	if error = server.ListenAndServe(); error != http.ErrServerClosed {
+		log.Fatal(error)
+	}
+	<-shutdown
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"devbook/src/config"
+	"devbook/src/database"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+const migrateUsage = "usage: devbook migrate up|down|status|baseline [version]|create <name>"
+
+// migrate runs the migrate subcommand. Databases created from the former utils/sql/ddl.sql script
+// already have the schema of migration 1, and must be adopted with migrate baseline, which records
+// migrations up to version, 1 by default, as applied without running them, before migrate up.
+// Migration 23 replaces their like counters with likes per user, keeping the former counts aside
+// in legacy_publication_likes
+func migrate(arguments []string) {
+
+	if len(arguments) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	if arguments[0] == "create" {
+		if len(arguments) != 2 {
+			log.Fatal(migrateUsage)
+		}
+		paths, error := database.CreateMigration(config.MigrationsDirectory, arguments[1])
+		if error != nil {
+			log.Fatal(error)
+		}
+		for _, path := range paths {
+			fmt.Printf("created %s\n", path)
+		}
+		return
+	}
+
+	db, error := database.Connect()
+	if error != nil {
+		log.Fatal(error)
+	}
+	defer db.Close()
+
+	migrator, error := database.NewMigrator(db)
+	if error != nil {
+		log.Fatal(error)
+	}
+
+	switch arguments[0] {
+	case "up":
+		migrations, error := migrator.Up()
+		for _, migration := range migrations {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if error != nil {
+			log.Fatal(error)
+		}
+		if len(migrations) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "baseline":
+		var version uint64 = 1
+		if len(arguments) > 2 {
+			log.Fatal(migrateUsage)
+		}
+		if len(arguments) == 2 {
+			if version, error = strconv.ParseUint(arguments[1], 10, 64); error != nil {
+				log.Fatal(migrateUsage)
+			}
+		}
+		migrations, error := migrator.Baseline(version)
+		if error != nil {
+			log.Fatal(error)
+		}
+		for _, migration := range migrations {
+			fmt.Printf("baselined %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "down":
+		migration, error := migrator.Down()
+		if error != nil {
+			log.Fatal(error)
+		}
+		if migration == nil {
+			fmt.Println("no applied migrations")
+		} else {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, error := migrator.Status()
+		if error != nil {
+			log.Fatal(error)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
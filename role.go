@@ -0,0 +1,49 @@
+package main
+
+import (
+	"devbook/src/database"
+	"devbook/src/persistence"
+	"devbook/src/security"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const roleUsage = "usage: devbook role <email> user|moderator|admin"
+
+// role runs the role subcommand, assigning a role to the user of an email, as needed to
+// bootstrap the first admin
+func role(arguments []string) {
+
+	if len(arguments) != 2 {
+		log.Fatal(roleUsage)
+	}
+
+	role := security.Role(arguments[1])
+	if !role.IsValid() {
+		log.Fatal(roleUsage)
+	}
+
+	db, error := database.Connect()
+	if error != nil {
+		log.Fatal(error)
+	}
+	defer db.Close()
+
+	users := persistence.NewUserRepository(db)
+
+	user, error := users.GetUserByEmail(strings.ToLower(strings.TrimSpace(arguments[0])))
+	if error != nil {
+		log.Fatal(error)
+	}
+
+	if user.ID == 0 {
+		log.Fatalf("no user with email %s", arguments[0])
+	}
+
+	if error = users.UpdateUserRole(user.ID, role); error != nil {
+		log.Fatal(error)
+	}
+
+	fmt.Printf("user %d is now %s\n", user.ID, role)
+}
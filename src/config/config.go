@@ -4,8 +4,18 @@ import (
 	"fmt"
 	"github.com/joho/godotenv"
 	"log"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// EmailVerificationRequiredForLogin blocks login of accounts with unverified email
+	EmailVerificationRequiredForLogin = "login"
+	// EmailVerificationRequiredForPublication blocks publication creation by accounts with unverified email
+	EmailVerificationRequiredForPublication = "publication"
 )
 
 var (
@@ -13,10 +23,101 @@ var (
 	connectionStringPattern = "%s:%s@/%s?charset=utf8&parseTime=True&loc=Local"
 	// ConnectionString database connection string
 	ConnectionString string
+	// DBMaxOpenConnections maximum number of open database connections
+	DBMaxOpenConnections int
+	// DBMaxIdleConnections maximum number of idle database connections
+	DBMaxIdleConnections int
+	// DBConnectionMaxLifetime maximum time a database connection may be reused
+	DBConnectionMaxLifetime time.Duration
+	// AutoMigrate applies pending database migrations on start
+	AutoMigrate bool
+	// MigrationsDirectory directory where new migrations are created
+	MigrationsDirectory string
 	// Port API port number
 	Port int
+	// LogLevel least important level of log entries written: debug, info, warn or error
+	LogLevel string
 	// SecretKey key used to sign token
 	SecretKey []byte
+	// AccessTokenLifetime time an access token remains valid
+	AccessTokenLifetime time.Duration
+	// RefreshTokenLifetime time a refresh token remains valid
+	RefreshTokenLifetime time.Duration
+	// PasswordResetTokenLifetime time a password reset token remains valid
+	PasswordResetTokenLifetime time.Duration
+	// EmailVerificationTokenLifetime time an email verification token remains valid
+	EmailVerificationTokenLifetime time.Duration
+	// EmailVerificationRequiredFor action blocked for unverified accounts, login or publication, none when empty
+	EmailVerificationRequiredFor string
+	// MFAChallengeLifetime time a login awaits the second factor code
+	MFAChallengeLifetime time.Duration
+	// MFAIssuer issuer name shown by authenticator apps
+	MFAIssuer string
+	// LoginMaxFailures failed logins locking an account out
+	LoginMaxFailures int
+	// LoginMaxFailuresPerAddress failed logins locking an IP address out
+	LoginMaxFailuresPerAddress int
+	// LoginBackoff wait imposed after a first failed login, doubling with each further failure
+	LoginBackoff time.Duration
+	// LoginLockoutDuration time an account or address remains locked out
+	LoginLockoutDuration time.Duration
+	// LoginFailureWindow time after which failed logins are forgotten
+	LoginFailureWindow time.Duration
+	// PageSize items listed per page when clients do not ask for a limit, at least one
+	PageSize int
+	// MaxPageSize items listed per page at most, at least one
+	MaxPageSize int
+	// CommentThreadMaxDepth levels of replies returned in a comment thread at most
+	CommentThreadMaxDepth int
+	// CommentRepliesPageSize replies returned per comment of a thread at most, at least one
+	CommentRepliesPageSize int
+	// CommentThreadMaxReplies replies returned in a comment thread at most, besides the page of
+	// replies of its root, comments beyond it being returned without their replies
+	CommentThreadMaxReplies int
+	// TrendingTagsWindow time over which tag usage is compared with the preceding window of the same
+	// length to rank trending tags
+	TrendingTagsWindow time.Duration
+	// TrendingTagsLimit trending tags listed when clients do not ask for a limit
+	TrendingTagsLimit int
+	// StreamHeartbeatInterval time between comments keeping idle event streams open
+	StreamHeartbeatInterval time.Duration
+	// StreamHistorySize recent events kept to resume event streams of reconnecting clients
+	StreamHistorySize int
+	// StreamBufferSize events queued per event stream or WebSocket connection at most, slower clients
+	// being disconnected
+	StreamBufferSize int
+	// MessagingRequiresMutualFollow allows direct messages only between users following each other
+	MessagingRequiresMutualFollow bool
+	// WebSocketPingInterval time between pings checking WebSocket connections are alive, connections
+	// missing two pongs being closed
+	WebSocketPingInterval time.Duration
+	// WebhookTimeout time webhook receivers have to answer a delivery
+	WebhookTimeout time.Duration
+	// WebhookMaxAttempts attempts of a webhook delivery before it is given up as dead
+	WebhookMaxAttempts int
+	// WebhookRetryDelay time before retrying a failed webhook delivery, doubling with each failure
+	WebhookRetryDelay time.Duration
+	// WebhookWorkers webhook deliveries posted at once at most, one per webhook, at least one
+	WebhookWorkers int
+	// WebhookAllowedNetworks networks webhooks may target despite being loopback, link-local or
+	// private, like those of internal tooling, none by default
+	WebhookAllowedNetworks []*net.IPNet
+	// PublicURL base URL of the API used in links sent to users
+	PublicURL string
+	// MailDriver mailer used to send emails, smtp or log
+	MailDriver string
+	// MailFrom sender address of emails
+	MailFrom string
+	// MailLogFile file where the log mailer writes emails, standard output when empty
+	MailLogFile string
+	// SMTPHost SMTP server host
+	SMTPHost string
+	// SMTPPort SMTP server port
+	SMTPPort int
+	// SMTPUsername SMTP authentication user, no authentication when empty
+	SMTPUsername string
+	// SMTPPassword SMTP authentication password
+	SMTPPassword string
 )
 
 // Load inits environment variables
@@ -32,8 +133,204 @@ func Load() {
 		Port = 9000 // assumes default port number
 	}
 
+	LogLevel = os.Getenv("LOG_LEVEL")
+	if LogLevel == "" {
+		LogLevel = "info"
+	}
+
 	ConnectionString = fmt.Sprintf(connectionStringPattern, os.Getenv("DB_USER"),
 		os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
 
+	DBMaxOpenConnections, error = strconv.Atoi(os.Getenv("DB_MAX_OPEN_CONNECTIONS"))
+	if error != nil {
+		DBMaxOpenConnections = 25
+	}
+
+	DBMaxIdleConnections, error = strconv.Atoi(os.Getenv("DB_MAX_IDLE_CONNECTIONS"))
+	if error != nil {
+		DBMaxIdleConnections = 25
+	}
+
+	DBConnectionMaxLifetime, error = time.ParseDuration(os.Getenv("DB_CONNECTION_MAX_LIFETIME"))
+	if error != nil {
+		DBConnectionMaxLifetime = 5 * time.Minute
+	}
+
+	AutoMigrate, error = strconv.ParseBool(os.Getenv("DB_AUTO_MIGRATE"))
+	if error != nil {
+		AutoMigrate = false
+	}
+
+	MigrationsDirectory = os.Getenv("MIGRATIONS_DIRECTORY")
+	if MigrationsDirectory == "" {
+		MigrationsDirectory = "src/database/migrations"
+	}
+
 	SecretKey = []byte(os.Getenv("SECRET_KEY"))
-}
\ No newline at end of file
+
+	AccessTokenLifetime, error = time.ParseDuration(os.Getenv("ACCESS_TOKEN_LIFETIME"))
+	if error != nil {
+		AccessTokenLifetime = 15 * time.Minute
+	}
+
+	RefreshTokenLifetime, error = time.ParseDuration(os.Getenv("REFRESH_TOKEN_LIFETIME"))
+	if error != nil {
+		RefreshTokenLifetime = 30 * 24 * time.Hour
+	}
+
+	PasswordResetTokenLifetime, error = time.ParseDuration(os.Getenv("PASSWORD_RESET_TOKEN_LIFETIME"))
+	if error != nil {
+		PasswordResetTokenLifetime = time.Hour
+	}
+
+	EmailVerificationTokenLifetime, error = time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_TOKEN_LIFETIME"))
+	if error != nil {
+		EmailVerificationTokenLifetime = 24 * time.Hour
+	}
+
+	EmailVerificationRequiredFor = os.Getenv("EMAIL_VERIFICATION_REQUIRED_FOR")
+
+	MFAChallengeLifetime, error = time.ParseDuration(os.Getenv("MFA_CHALLENGE_LIFETIME"))
+	if error != nil {
+		MFAChallengeLifetime = 5 * time.Minute
+	}
+
+	MFAIssuer = os.Getenv("MFA_ISSUER")
+	if MFAIssuer == "" {
+		MFAIssuer = "devbook"
+	}
+
+	LoginMaxFailures, error = strconv.Atoi(os.Getenv("LOGIN_MAX_FAILURES"))
+	if error != nil {
+		LoginMaxFailures = 5
+	}
+
+	LoginMaxFailuresPerAddress, error = strconv.Atoi(os.Getenv("LOGIN_MAX_FAILURES_PER_ADDRESS"))
+	if error != nil {
+		LoginMaxFailuresPerAddress = 20
+	}
+
+	LoginBackoff, error = time.ParseDuration(os.Getenv("LOGIN_BACKOFF"))
+	if error != nil {
+		LoginBackoff = time.Second
+	}
+
+	LoginLockoutDuration, error = time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_DURATION"))
+	if error != nil {
+		LoginLockoutDuration = 15 * time.Minute
+	}
+
+	LoginFailureWindow, error = time.ParseDuration(os.Getenv("LOGIN_FAILURE_WINDOW"))
+	if error != nil {
+		LoginFailureWindow = 15 * time.Minute
+	}
+
+	PageSize, error = strconv.Atoi(os.Getenv("PAGE_SIZE"))
+	if error != nil || PageSize < 1 {
+		PageSize = 20
+	}
+
+	MaxPageSize, error = strconv.Atoi(os.Getenv("MAX_PAGE_SIZE"))
+	if error != nil || MaxPageSize < 1 {
+		MaxPageSize = 100
+	}
+
+	CommentThreadMaxDepth, error = strconv.Atoi(os.Getenv("COMMENT_THREAD_MAX_DEPTH"))
+	if error != nil {
+		CommentThreadMaxDepth = 10
+	}
+
+	CommentRepliesPageSize, error = strconv.Atoi(os.Getenv("COMMENT_REPLIES_PAGE_SIZE"))
+	if error != nil || CommentRepliesPageSize < 1 {
+		CommentRepliesPageSize = 20
+	}
+
+	CommentThreadMaxReplies, error = strconv.Atoi(os.Getenv("COMMENT_THREAD_MAX_REPLIES"))
+	if error != nil || CommentThreadMaxReplies < 1 {
+		CommentThreadMaxReplies = 200
+	}
+
+	TrendingTagsWindow, error = time.ParseDuration(os.Getenv("TRENDING_TAGS_WINDOW"))
+	if error != nil {
+		TrendingTagsWindow = 24 * time.Hour
+	}
+
+	TrendingTagsLimit, error = strconv.Atoi(os.Getenv("TRENDING_TAGS_LIMIT"))
+	if error != nil {
+		TrendingTagsLimit = 10
+	}
+
+	StreamHeartbeatInterval, error = time.ParseDuration(os.Getenv("STREAM_HEARTBEAT_INTERVAL"))
+	if error != nil {
+		StreamHeartbeatInterval = 15 * time.Second
+	}
+
+	StreamHistorySize, error = strconv.Atoi(os.Getenv("STREAM_HISTORY_SIZE"))
+	if error != nil {
+		StreamHistorySize = 1000
+	}
+
+	StreamBufferSize, error = strconv.Atoi(os.Getenv("STREAM_BUFFER_SIZE"))
+	if error != nil {
+		StreamBufferSize = 64
+	}
+
+	MessagingRequiresMutualFollow, error = strconv.ParseBool(os.Getenv("MESSAGING_REQUIRES_MUTUAL_FOLLOW"))
+	if error != nil {
+		MessagingRequiresMutualFollow = true
+	}
+
+	WebSocketPingInterval, error = time.ParseDuration(os.Getenv("WEBSOCKET_PING_INTERVAL"))
+	if error != nil {
+		WebSocketPingInterval = 30 * time.Second
+	}
+
+	WebhookTimeout, error = time.ParseDuration(os.Getenv("WEBHOOK_TIMEOUT"))
+	if error != nil {
+		WebhookTimeout = 10 * time.Second
+	}
+
+	WebhookMaxAttempts, error = strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	if error != nil {
+		WebhookMaxAttempts = 8
+	}
+
+	WebhookRetryDelay, error = time.ParseDuration(os.Getenv("WEBHOOK_RETRY_DELAY"))
+	if error != nil {
+		WebhookRetryDelay = time.Minute
+	}
+
+	WebhookWorkers, error = strconv.Atoi(os.Getenv("WEBHOOK_WORKERS"))
+	if error != nil || WebhookWorkers < 1 {
+		WebhookWorkers = 8
+	}
+
+	WebhookAllowedNetworks = nil
+	for _, cidr := range strings.Split(os.Getenv("WEBHOOK_ALLOWED_NETWORKS"), ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, network, error := net.ParseCIDR(cidr)
+		if error != nil {
+			log.Fatal(error)
+		}
+		WebhookAllowedNetworks = append(WebhookAllowedNetworks, network)
+	}
+
+	PublicURL = os.Getenv("PUBLIC_URL")
+	if PublicURL == "" {
+		PublicURL = fmt.Sprintf("http://localhost:%d", Port)
+	}
+
+	MailDriver = os.Getenv("MAIL_DRIVER")
+	MailFrom = os.Getenv("MAIL_FROM")
+	MailLogFile = os.Getenv("MAIL_LOG_FILE")
+
+	SMTPHost = os.Getenv("SMTP_HOST")
+	SMTPPort, error = strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if error != nil {
+		SMTPPort = 587
+	}
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"errors"
+	"net/http"
+)
+
+// errBlocked is the error of interactions between a user and a user blocking or blocked by it
+var errBlocked = errors.New("Cannot interact with a user blocking or blocked by you")
+
+// respondIfBlocked responds with a forbidden status when either user blocks the other, telling
+// whether it did
+func respondIfBlocked(w http.ResponseWriter, users persistence.UserStore, userId uint64, otherId uint64) bool {
+
+	blocked, error := users.IsBlocked(userId, otherId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return true
+	}
+
+	if blocked {
+		responses.ErrorResponse(w, http.StatusForbidden, errBlocked)
+		return true
+	}
+
+	return false
+}
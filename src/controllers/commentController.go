@@ -0,0 +1,401 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// CommentController handles comment requests
+type CommentController struct {
+	repository   persistence.CommentStore
+	publications persistence.PublicationStore
+	users        persistence.UserStore
+	publisher    events.Publisher
+}
+
+// NewCommentController factory
+func NewCommentController(repository persistence.CommentStore, publications persistence.PublicationStore,
+	users persistence.UserStore, publisher events.Publisher) *CommentController {
+	return &CommentController{repository, publications, users, publisher}
+}
+
+// CreateComment comments on a publication
+func (controller CommentController) CreateComment(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	publicationId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publication, error := controller.publications.GetPublicationById(publicationId, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if publication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if respondIfBlocked(w, controller.users, userId, publication.AuthorId) {
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var comment models.Comment
+	if error = json.Unmarshal(requestBody, &comment); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	comment.PublicationId = publicationId
+	comment.AuthorId = userId
+
+	if error = comment.Prepare(); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	var parent models.Comment
+	if comment.ParentId != 0 {
+		parent, error = controller.repository.GetCommentById(comment.ParentId)
+		if error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+
+		if parent.ID == 0 || parent.PublicationId != publicationId {
+			responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid parent comment"))
+			return
+		}
+
+		if respondIfBlocked(w, controller.users, userId, parent.AuthorId) {
+			return
+		}
+	}
+
+	id, error := controller.repository.CreateComment(comment)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	comment, error = controller.repository.GetCommentById(id)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if parent.ID != 0 {
+		controller.publisher.Publish(events.Event{Type: events.CommentReplied, ActorId: userId,
+			UserId: parent.AuthorId, PublicationId: publicationId, CommentId: id})
+	}
+	if parent.AuthorId != publication.AuthorId {
+		controller.publisher.Publish(events.Event{Type: events.PublicationCommented, ActorId: userId,
+			UserId: publication.AuthorId, PublicationId: publicationId, CommentId: id})
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, comment)
+}
+
+// GetComments lists a page of comments of a publication, oldest first
+func (controller CommentController) GetComments(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	publicationId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publication, error := controller.publications.GetPublicationById(publicationId, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if publication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	comments, error := controller.repository.GetPublicationComments(publicationId, userId, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(comments) > page.Limit {
+		comments = comments[:page.Limit]
+		next = &models.Cursor{ID: comments[page.Limit-1].ID}
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(comments, next))
+}
+
+// GetCommentThread returns the thread of a comment on a publication visible to the authenticated
+// user as a tree, down to the requested depth and with a page of replies per comment, each with
+// the cursor of its next page, the cursor of the request applying to the replies of the thread root
+func (controller CommentController) GetCommentThread(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	publicationId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	commentId, error := strconv.ParseUint(pathParameters["commentId"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	depth, error := intQueryParameter(r, "depth", config.CommentThreadMaxDepth)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if depth > config.CommentThreadMaxDepth {
+		depth = config.CommentThreadMaxDepth
+	}
+
+	page, error := readLimitedPageRequest(r, config.CommentRepliesPageSize, config.CommentRepliesPageSize)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publication, error := controller.publications.GetPublicationById(publicationId, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if publication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	comment, error := controller.repository.GetCommentById(commentId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if comment.ID == 0 || comment.PublicationId != publicationId {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	replies, error := controller.getThreadReplies(comment.ID, userId, depth, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	ids := []uint64{comment.ID}
+	for _, reply := range replies {
+		ids = append(ids, reply.ID)
+	}
+
+	counts, error := controller.repository.CountCommentReplies(ids, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.BuildCommentThread(comment, replies, counts, page.Limit))
+}
+
+// getThreadReplies reads the thread of a comment seen by viewerId a level at a time, a page of
+// replies per comment read so far, down to depth levels below it, reading the replies of no more
+// comments than config.CommentThreadMaxReplies leaves room for
+func (controller CommentController) getThreadReplies(id uint64, viewerId uint64, depth int, page models.PageRequest) ([]models.Comment, error) {
+
+	var replies []models.Comment
+	parentIds := []uint64{id}
+	remaining := config.CommentThreadMaxReplies
+
+	for level := 0; level < depth && len(parentIds) > 0; level++ {
+		levelReplies, error := controller.repository.GetCommentReplies(parentIds, viewerId, page)
+		if error != nil {
+			return nil, error
+		}
+		replies = append(replies, levelReplies...)
+
+		// the reply read past the page of its parent only tells there are more
+		parentIds = nil
+		perParent := make(map[uint64]int)
+		for _, reply := range levelReplies {
+			perParent[reply.ParentId]++
+			if perParent[reply.ParentId] <= page.Limit {
+				parentIds = append(parentIds, reply.ID)
+				remaining--
+			}
+		}
+
+		room := remaining / page.Limit
+		if room < 0 {
+			room = 0
+		}
+		if len(parentIds) > room {
+			parentIds = parentIds[:room]
+		}
+
+		page.After = nil
+	}
+
+	return replies, nil
+}
+
+// UpdateComment updates a comment, by its author or the publication author
+func (controller CommentController) UpdateComment(w http.ResponseWriter, r *http.Request) {
+
+	comment, ok := controller.getManagedComment(w, r)
+	if !ok {
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var update models.Comment
+	if error = json.Unmarshal(requestBody, &update); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	update.PublicationId = comment.PublicationId
+	update.AuthorId = comment.AuthorId
+
+	if error = update.Prepare(); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if error = controller.repository.UpdateComment(comment.ID, update); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// DeleteComment deletes a comment, by its author or the publication author
+func (controller CommentController) DeleteComment(w http.ResponseWriter, r *http.Request) {
+
+	comment, ok := controller.getManagedComment(w, r)
+	if !ok {
+		return
+	}
+
+	if error := controller.repository.DeleteComment(comment.ID); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// getManagedComment gets the comment of the request path, responding with an error unless the
+// authenticated user wrote it, authored its publication or is a moderator
+func (controller CommentController) getManagedComment(w http.ResponseWriter, r *http.Request) (models.Comment, bool) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return models.Comment{}, false
+	}
+
+	role, error := security.ExtractRole(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return models.Comment{}, false
+	}
+
+	pathParameters := mux.Vars(r)
+	publicationId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return models.Comment{}, false
+	}
+
+	commentId, error := strconv.ParseUint(pathParameters["commentId"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return models.Comment{}, false
+	}
+
+	comment, error := controller.repository.GetCommentById(commentId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return models.Comment{}, false
+	}
+
+	if comment.ID == 0 || comment.PublicationId != publicationId {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return models.Comment{}, false
+	}
+
+	publication, error := controller.publications.GetPublicationById(publicationId, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return models.Comment{}, false
+	}
+
+	if comment.AuthorId != userId && publication.AuthorId != userId && !role.Includes(security.RoleModerator) {
+		responses.ErrorResponse(w, http.StatusForbidden,
+			errors.New("Only the comment or publication author can change a comment"))
+		return models.Comment{}, false
+	}
+
+	return comment, true
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// loginAttemptKey is a key counting failed logins, locked out after maxFailures
+type loginAttemptKey struct {
+	key         string
+	maxFailures int
+}
+
+// loginAttemptKeys returns the keys counting failed logins of an account, like the email it
+// logs in with, and of the address the request comes from
+func loginAttemptKeys(r *http.Request, account string) []loginAttemptKey {
+
+	address, _, error := net.SplitHostPort(r.RemoteAddr)
+	if error != nil {
+		address = r.RemoteAddr
+	}
+
+	return []loginAttemptKey{
+		{key: "account:" + account, maxFailures: config.LoginMaxFailures},
+		{key: "address:" + address, maxFailures: config.LoginMaxFailuresPerAddress},
+	}
+}
+
+// countLoginAttempt counts a login attempt under keys before it is checked, telling how long it is
+// refused, zero when it is allowed; the decision is made from the counts the store returns, those
+// of the attempts before this one, concurrent attempts included, and refused attempts are released
+func countLoginAttempt(attempts persistence.LoginAttemptStore, keys []loginAttemptKey) (time.Duration, error) {
+
+	var blockedFor time.Duration
+	now := time.Now()
+
+	for _, key := range keys {
+		counted, error := attempts.CountLoginAttempt(key.key, config.LoginFailureWindow)
+		if error != nil {
+			return 0, error
+		}
+		counted.Failures--
+		blockedUntil := counted.BlockedUntil(key.maxFailures, config.LoginBackoff, config.LoginLockoutDuration)
+		if wait := blockedUntil.Sub(now); wait > blockedFor {
+			blockedFor = wait
+		}
+	}
+
+	if blockedFor > 0 {
+		for _, key := range keys {
+			if error := attempts.ReleaseLoginAttempt(key.key); error != nil {
+				return 0, error
+			}
+		}
+	}
+
+	return blockedFor, nil
+}
+
+// registerLoginFailure records a failed login counted under keys
+func registerLoginFailure(attempts persistence.LoginAttemptStore, keys []loginAttemptKey) error {
+
+	for _, key := range keys {
+		if error := attempts.RegisterLoginFailure(key.key); error != nil {
+			return error
+		}
+	}
+
+	return nil
+}
+
+// registerLoginSuccess clears the login attempts counted under the first of keys, that of the
+// account, and releases the successful one under the others
+func registerLoginSuccess(attempts persistence.LoginAttemptStore, keys []loginAttemptKey) error {
+
+	if error := attempts.ResetLoginAttempts(keys[0].key); error != nil {
+		return error
+	}
+
+	for _, key := range keys[1:] {
+		if error := attempts.ReleaseLoginAttempt(key.key); error != nil {
+			return error
+		}
+	}
+
+	return nil
+}
+
+// respondLoginBlocked refuses a login attempt, telling clients when to retry
+func respondLoginBlocked(w http.ResponseWriter, blockedFor time.Duration) {
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(blockedFor.Seconds()))))
+	responses.ErrorResponse(w, http.StatusTooManyRequests,
+		errors.New("Too many failed login attempts, try again later"))
+}
@@ -1,19 +1,39 @@
 package controllers
 
 import (
-	"devbook/src/database"
+	"devbook/src/config"
 	"devbook/src/models"
 	"devbook/src/persistence"
 	"devbook/src/responses"
 	"devbook/src/security"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 )
 
-// Login authenticates a user
-func Login(w http.ResponseWriter, r *http.Request) {
+// LoginController handles login requests
+type LoginController struct {
+	users         persistence.UserStore
+	refreshTokens persistence.RefreshTokenStore
+	mfa           persistence.MFAStore
+	attempts      persistence.LoginAttemptStore
+}
+
+// errInvalidCredentials is the single error of failed logins, not telling unknown emails apart
+var errInvalidCredentials = errors.New("Invalid email or password")
+
+// NewLoginController factory
+func NewLoginController(users persistence.UserStore, refreshTokens persistence.RefreshTokenStore,
+	mfa persistence.MFAStore, attempts persistence.LoginAttemptStore) *LoginController {
+	return &LoginController{users, refreshTokens, mfa, attempts}
+}
+
+// Login authenticates a user, refusing attempts while its account or address is locked out by
+// previous failures
+func (controller LoginController) Login(w http.ResponseWriter, r *http.Request) {
 
 	requestBody, error := ioutil.ReadAll(r.Body)
 	if error != nil {
@@ -32,34 +52,318 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	attemptKeys := loginAttemptKeys(r, credential.Email)
+	blockedFor, error := countLoginAttempt(controller.attempts, attemptKeys)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	user, error := repository.GetUserByEmail(credential.Email)
+	if blockedFor > 0 {
+		respondLoginBlocked(w, blockedFor)
+		return
+	}
+
+	user, error := controller.users.GetUserByEmail(credential.Email)
 	if error != nil {
-		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
 	if error = security.CheckPassword(user.Password, credential.Password); error != nil {
+		controller.failLogin(w, attemptKeys, errInvalidCredentials)
+		return
+	}
+
+	if error = registerLoginSuccess(controller.attempts, attemptKeys); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if user.IsSuspended() {
+		responses.ErrorResponse(w, http.StatusForbidden, errors.New("Account suspended"))
+		return
+	}
+
+	if config.EmailVerificationRequiredFor == config.EmailVerificationRequiredForLogin &&
+		user.EmailVerifiedAt == nil {
+		responses.ErrorResponse(w, http.StatusForbidden,
+			errors.New("Verify your email address before logging in"))
+		return
+	}
+
+	mfa, error := controller.mfa.GetMFA(user.ID)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if mfa.IsEnabled() {
+		mfaToken, error := security.GetMFAChallengeToken(user.ID)
+		if error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+
+		responses.JsonResponse(w, http.StatusOK, models.MFAChallenge{
+			UserId:      strconv.FormatUint(user.ID, 10),
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	controller.respondWithTokens(w, user, "")
+}
+
+// LoginMFA completes a login awaiting a second factor, exchanging its challenge token and a
+// TOTP or recovery code for tokens
+func (controller LoginController) LoginMFA(w http.ResponseWriter, r *http.Request) {
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var mfaLogin models.MFALogin
+	if error = json.Unmarshal(requestBody, &mfaLogin); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	userId, error := security.ParseMFAChallengeToken(mfaLogin.MFAToken)
+	if error != nil {
 		responses.ErrorResponse(w, http.StatusUnauthorized, error)
 		return
 	}
 
-	token, error := security.GetToken(user.ID, user.Email)
+	mfa, error := controller.mfa.GetMFA(userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	if !mfa.IsEnabled() {
+		responses.ErrorResponse(w, http.StatusUnauthorized,
+			errors.New("Two-factor authentication is not enabled"))
+		return
+	}
+
+	attemptKeys := loginAttemptKeys(r, "mfa:"+strconv.FormatUint(userId, 10))
+	blockedFor, error := countLoginAttempt(controller.attempts, attemptKeys)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if blockedFor > 0 {
+		respondLoginBlocked(w, blockedFor)
+		return
+	}
+
+	valid, error := verifySecondFactor(controller.mfa, mfa, mfaLogin.Code)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if !valid {
+		controller.failLogin(w, attemptKeys, errors.New("Invalid code"))
+		return
+	}
+
+	if error = registerLoginSuccess(controller.attempts, attemptKeys); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	user, error := controller.users.GetUserById(userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if user.ID == 0 {
+		responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Invalid Token"))
+		return
+	}
+
+	if user.IsSuspended() {
+		responses.ErrorResponse(w, http.StatusForbidden, errors.New("Account suspended"))
+		return
+	}
+
+	controller.respondWithTokens(w, user, "")
+}
+
+// RefreshToken exchanges a refresh token for a new pair of access and refresh tokens
+func (controller LoginController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var request models.RefreshTokenRequest
+	if error = json.Unmarshal(requestBody, &request); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if request.RefreshToken == "" {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid refresh token"))
+		return
+	}
+
+	storedToken, error := controller.refreshTokens.GetRefreshTokenByHash(
+		security.HashToken(request.RefreshToken))
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if storedToken.ID == 0 {
+		responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Invalid refresh token"))
+		return
+	}
+
+	if storedToken.RevokedAt != nil {
+		controller.revokeReusedFamily(w, storedToken)
+		return
+	}
+
+	if !storedToken.IsActive(time.Now()) {
+		responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Expired refresh token"))
+		return
+	}
+
+	wasActive, error := controller.refreshTokens.RevokeRefreshToken(storedToken.ID)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if !wasActive {
+		controller.revokeReusedFamily(w, storedToken)
+		return
+	}
+
+	user, error := controller.users.GetUserById(storedToken.UserId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if user.ID == 0 {
+		responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Invalid refresh token"))
+		return
+	}
+
+	if user.IsSuspended() {
+		responses.ErrorResponse(w, http.StatusForbidden, errors.New("Account suspended"))
+		return
+	}
+
+	controller.respondWithTokens(w, user, storedToken.FamilyId)
+}
+
+// Logout revokes a refresh token along with every token rotated from the same login
+func (controller LoginController) Logout(w http.ResponseWriter, r *http.Request) {
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var request models.RefreshTokenRequest
+	if error = json.Unmarshal(requestBody, &request); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if request.RefreshToken == "" {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid refresh token"))
+		return
+	}
+
+	storedToken, error := controller.refreshTokens.GetRefreshTokenByHash(
+		security.HashToken(request.RefreshToken))
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if storedToken.ID != 0 {
+		if error = controller.refreshTokens.RevokeRefreshTokenFamily(storedToken.FamilyId); error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// failLogin records a failed login counted under attemptKeys and responds with loginError
+func (controller LoginController) failLogin(w http.ResponseWriter, attemptKeys []loginAttemptKey, loginError error) {
+
+	if error := registerLoginFailure(controller.attempts, attemptKeys); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.ErrorResponse(w, http.StatusUnauthorized, loginError)
+}
+
+// revokeReusedFamily revokes the family of a refresh token presented after being revoked,
+// since either its owner or whoever stole it holds a newer token of the same family
+func (controller LoginController) revokeReusedFamily(w http.ResponseWriter, storedToken models.RefreshToken) {
+
+	if error := controller.refreshTokens.RevokeRefreshTokenFamily(storedToken.FamilyId); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Refresh token reuse detected"))
+}
+
+// respondWithTokens issues an access token and a refresh token of familyId, starting a new
+// family when familyId is empty
+func (controller LoginController) respondWithTokens(w http.ResponseWriter, user models.User, familyId string) {
+
+	token, error := security.GetToken(user.ID, user.Email, user.Role)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	refreshToken, error := security.GenerateBase64RandomToken()
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	refreshTokenHash := security.HashToken(refreshToken)
+	if familyId == "" {
+		familyId = refreshTokenHash
+	}
+
+	if _, error = controller.refreshTokens.CreateRefreshToken(models.RefreshToken{
+		UserId:    user.ID,
+		FamilyId:  familyId,
+		TokenHash: refreshTokenHash,
+		ExpiresAt: time.Now().Add(config.RefreshTokenLifetime),
+	}); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
 	authToken := models.Token{
-		UserId: strconv.FormatUint(user.ID, 10),
-		Token: token}
+		UserId:       strconv.FormatUint(user.ID, 10),
+		Token:        token,
+		RefreshToken: refreshToken}
 
 	responses.JsonResponse(w, http.StatusOK, authToken)
 }
@@ -0,0 +1,458 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/logging"
+	"devbook/src/messaging"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errCannotMessage is the error of messages to users blocking or blocked by the sender, or not
+// following each other when config.MessagingRequiresMutualFollow is set
+var errCannotMessage = errors.New("Cannot message this user")
+
+// upgrader turns requests into WebSocket connections, from the API origin only
+var upgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+
+// maxFrameSize bytes of a frame read from a WebSocket connection at most, larger frames closing it
+const maxFrameSize = 4096
+
+// MessageController handles direct message requests
+type MessageController struct {
+	repository persistence.MessageStore
+	users      persistence.UserStore
+	hub        *messaging.Hub
+	publisher  events.Publisher
+}
+
+// NewMessageController factory
+func NewMessageController(repository persistence.MessageStore, users persistence.UserStore, hub *messaging.Hub,
+	publisher events.Publisher) *MessageController {
+	return &MessageController{repository, users, hub, publisher}
+}
+
+// StartConversation gets the conversation of the authenticated user with another user, starting
+// it when there is none
+func (controller MessageController) StartConversation(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var request models.ConversationRequest
+	if error = json.Unmarshal(requestBody, &request); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if request.UserId == 0 || request.UserId == userId {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid user"))
+		return
+	}
+
+	other, error := controller.users.GetUserById(request.UserId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if other.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if !controller.respondUnlessAllowed(w, userId, other.ID) {
+		return
+	}
+
+	id, error := controller.repository.GetOrCreateConversation(userId, other.ID)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	conversation, error := controller.repository.GetConversation(id, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, conversation)
+}
+
+// GetConversations lists a page of the conversations of the authenticated user, most recently
+// active first
+func (controller MessageController) GetConversations(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	conversations, error := controller.repository.GetConversations(userId, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(conversations) > page.Limit {
+		conversations = conversations[:page.Limit]
+		cursor := conversations[page.Limit-1].Cursor()
+		next = &cursor
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(conversations, next))
+}
+
+// GetConversation returns a conversation of the authenticated user
+func (controller MessageController) GetConversation(w http.ResponseWriter, r *http.Request) {
+
+	conversation, _, ok := controller.getConversation(w, r)
+	if !ok {
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, conversation)
+}
+
+// GetMessages lists a page of the messages of a conversation of the authenticated user, most
+// recent first
+func (controller MessageController) GetMessages(w http.ResponseWriter, r *http.Request) {
+
+	conversation, _, ok := controller.getConversation(w, r)
+	if !ok {
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	messages, error := controller.repository.GetMessages(conversation.ID, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(messages) > page.Limit {
+		messages = messages[:page.Limit]
+		next = &models.Cursor{ID: messages[page.Limit-1].ID}
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(messages, next))
+}
+
+// SendMessage sends a message in a conversation of the authenticated user, delivered live to both
+// users
+func (controller MessageController) SendMessage(w http.ResponseWriter, r *http.Request) {
+
+	conversation, userId, ok := controller.getConversation(w, r)
+	if !ok {
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var message models.Message
+	if error = json.Unmarshal(requestBody, &message); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	message.ConversationId = conversation.ID
+	message.SenderId = userId
+
+	if error = message.Prepare(); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if !controller.respondUnlessAllowed(w, userId, conversation.Participant.ID) {
+		return
+	}
+
+	id, error := controller.repository.CreateMessage(message)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	message, error = controller.repository.GetMessageById(id)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	controller.publisher.Publish(events.Event{Type: events.MessageSent, ActorId: userId,
+		UserId: conversation.Participant.ID, Data: message})
+
+	responses.JsonResponse(w, http.StatusCreated, message)
+}
+
+// MarkConversationRead marks a conversation of the authenticated user read up to a message, or
+// its last message, sending a read receipt to the participant
+func (controller MessageController) MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+
+	conversation, userId, ok := controller.getConversation(w, r)
+	if !ok {
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var request models.ReadRequest
+	if len(requestBody) > 0 {
+		if error = json.Unmarshal(requestBody, &request); error != nil {
+			responses.ErrorResponse(w, http.StatusBadRequest, error)
+			return
+		}
+	}
+
+	receipt, error := controller.markRead(userId, conversation, request.MessageId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, receipt)
+}
+
+// Connect upgrades the request to a WebSocket connection pushing messages, read receipts and
+// typing indicators of the authenticated user's conversations, and receiving its read and typing
+// frames, until either side closes it
+func (controller MessageController) Connect(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	connection, error := upgrader.Upgrade(w, r, nil)
+	if error != nil {
+		return
+	}
+
+	client := controller.hub.Connect(userId)
+	defer controller.hub.Disconnect(client)
+
+	go controller.writeFrames(connection, client)
+
+	connection.SetReadLimit(maxFrameSize)
+	keepAlive(connection)
+
+	for {
+		var frame messaging.Frame
+		if error := connection.ReadJSON(&frame); error != nil {
+			if websocket.IsUnexpectedCloseError(error, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logging.FromContext(r.Context()).Error("failed to read WebSocket frame",
+					logging.Fields{"user_id": userId, "error": error})
+			}
+			return
+		}
+
+		if error := controller.handleFrame(client, frame); error != nil {
+			controller.hub.Reply(client, messaging.Frame{Type: messaging.FrameError,
+				ConversationId: frame.ConversationId, Data: struct {
+					Error string `json:"error"`
+				}{Error: error.Error()}})
+		}
+	}
+}
+
+// keepAlive makes reads of a connection fail unless it answers the pings of writeFrames
+func keepAlive(connection *websocket.Conn) {
+
+	wait := 2 * config.WebSocketPingInterval
+	connection.SetReadDeadline(time.Now().Add(wait))
+	connection.SetPongHandler(func(string) error {
+		return connection.SetReadDeadline(time.Now().Add(wait))
+	})
+}
+
+// writeFrames writes the frames queued to a client and pings to its connection, closing the
+// connection once the client is disconnected or a write fails
+func (controller MessageController) writeFrames(connection *websocket.Conn, client *messaging.Client) {
+
+	ping := time.NewTicker(config.WebSocketPingInterval)
+	defer ping.Stop()
+	defer connection.Close()
+
+	for {
+		select {
+		case frame, open := <-client.Frames:
+			if !open {
+				connection.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if error := connection.WriteJSON(frame); error != nil {
+				return
+			}
+		case <-ping.C:
+			if error := connection.WriteMessage(websocket.PingMessage, nil); error != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleFrame handles a read or typing frame of a client
+func (controller MessageController) handleFrame(client *messaging.Client, frame messaging.Frame) error {
+
+	if frame.Type != messaging.FrameRead && frame.Type != messaging.FrameTyping {
+		return errors.New("Invalid frame type")
+	}
+
+	conversation, error := controller.repository.GetConversation(frame.ConversationId, client.UserId)
+	if error != nil {
+		return error
+	}
+
+	if conversation.ID == 0 {
+		return errors.New("Invalid conversation")
+	}
+
+	if frame.Type == messaging.FrameRead {
+		_, error = controller.markRead(client.UserId, conversation, frame.MessageId)
+		return error
+	}
+
+	allowed, error := controller.canMessage(client.UserId, conversation.Participant.ID)
+	if error != nil {
+		return error
+	}
+
+	if !allowed {
+		return errCannotMessage
+	}
+
+	controller.hub.Send(conversation.Participant.ID, messaging.Frame{Type: messaging.FrameTyping,
+		ConversationId: conversation.ID,
+		Data:           models.TypingIndicator{ConversationId: conversation.ID, UserId: client.UserId}})
+
+	return nil
+}
+
+// markRead marks a conversation read by a user up to a message, announcing the read receipt
+func (controller MessageController) markRead(userId uint64, conversation models.Conversation,
+	messageId uint64) (models.ReadReceipt, error) {
+
+	receipt, error := controller.repository.MarkConversationRead(conversation.ID, userId, messageId)
+	if error != nil {
+		return receipt, error
+	}
+
+	controller.publisher.Publish(events.Event{Type: events.ConversationRead, ActorId: userId,
+		UserId: conversation.Participant.ID, Data: receipt})
+
+	return receipt, nil
+}
+
+// getConversation gets the conversation of the request path as seen by the authenticated user,
+// responding with an error unless the user takes part in it
+func (controller MessageController) getConversation(w http.ResponseWriter, r *http.Request) (models.Conversation, uint64, bool) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return models.Conversation{}, 0, false
+	}
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return models.Conversation{}, 0, false
+	}
+
+	conversation, error := controller.repository.GetConversation(id, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return models.Conversation{}, 0, false
+	}
+
+	if conversation.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return models.Conversation{}, 0, false
+	}
+
+	return conversation, userId, true
+}
+
+// respondUnlessAllowed responds with a forbidden status unless a user can message another user,
+// telling whether it can
+func (controller MessageController) respondUnlessAllowed(w http.ResponseWriter, userId uint64, otherId uint64) bool {
+
+	allowed, error := controller.canMessage(userId, otherId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return false
+	}
+
+	if !allowed {
+		responses.ErrorResponse(w, http.StatusForbidden, errCannotMessage)
+		return false
+	}
+
+	return true
+}
+
+// canMessage tells if a user can message another user, neither blocking the other and, when
+// config.MessagingRequiresMutualFollow is set, both following each other
+func (controller MessageController) canMessage(userId uint64, otherId uint64) (bool, error) {
+
+	blocked, error := controller.users.IsBlocked(userId, otherId)
+	if error != nil || blocked {
+		return false, error
+	}
+
+	if !config.MessagingRequiresMutualFollow {
+		return true, nil
+	}
+
+	following, error := controller.users.IsFollower(otherId, userId)
+	if error != nil || !following {
+		return false, error
+	}
+
+	return controller.users.IsFollower(userId, otherId)
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// recoveryCodesCount number of recovery codes generated for a user
+const recoveryCodesCount = 10
+
+// MFAController handles second factor enrollment requests
+type MFAController struct {
+	users    persistence.UserStore
+	mfa      persistence.MFAStore
+	attempts persistence.LoginAttemptStore
+}
+
+// NewMFAController factory
+func NewMFAController(users persistence.UserStore, mfa persistence.MFAStore,
+	attempts persistence.LoginAttemptStore) *MFAController {
+	return &MFAController{users, mfa, attempts}
+}
+
+// EnrollMFA generates a TOTP secret for the authenticated user, enabled once confirmed
+func (controller MFAController) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	mfa, error := controller.mfa.GetMFA(userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if mfa.IsEnabled() {
+		responses.ErrorResponse(w, http.StatusConflict,
+			errors.New("Two-factor authentication is already enabled"))
+		return
+	}
+
+	user, error := controller.users.GetUserById(userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	secret, error := security.GenerateTOTPSecret()
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if error = controller.mfa.SaveMFASecret(userId, secret); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.MFAEnrollment{
+		Secret: secret,
+		URI:    security.TOTPURI(config.MFAIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmMFA enables the authenticated user's second factor with a first valid code,
+// returning its recovery codes
+func (controller MFAController) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	code, error := readMFACode(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	mfa, error := controller.mfa.GetMFA(userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if mfa.UserId == 0 {
+		responses.ErrorResponse(w, http.StatusBadRequest,
+			errors.New("Two-factor authentication enrollment was not started"))
+		return
+	}
+
+	if mfa.IsEnabled() {
+		responses.ErrorResponse(w, http.StatusConflict,
+			errors.New("Two-factor authentication is already enabled"))
+		return
+	}
+
+	step, valid := security.ValidateTOTP(mfa.Secret, code, time.Now())
+	if !valid {
+		responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Invalid code"))
+		return
+	}
+
+	if _, error = controller.mfa.UseMFAStep(userId, step); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if error = controller.mfa.EnableMFA(userId); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	controller.respondWithRecoveryCodes(w, userId)
+}
+
+// DisableMFA removes the authenticated user's second factor, given a valid code
+func (controller MFAController) DisableMFA(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	code, error := readMFACode(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if !controller.checkCode(w, r, userId, code) {
+		return
+	}
+
+	if error = controller.mfa.DisableMFA(userId); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// RegenerateRecoveryCodes replaces the authenticated user's recovery codes, given a valid code
+func (controller MFAController) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	code, error := readMFACode(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if !controller.checkCode(w, r, userId, code) {
+		return
+	}
+
+	controller.respondWithRecoveryCodes(w, userId)
+}
+
+// checkCode checks a code against a user's second factor, failures being counted and locked out
+// along with those of the second step of its logins, and responds unless the code is valid
+func (controller MFAController) checkCode(w http.ResponseWriter, r *http.Request, userId uint64, code string) bool {
+
+	mfa, error := controller.mfa.GetMFA(userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return false
+	}
+
+	if !mfa.IsEnabled() {
+		responses.ErrorResponse(w, http.StatusBadRequest,
+			errors.New("Two-factor authentication is not enabled"))
+		return false
+	}
+
+	attemptKeys := loginAttemptKeys(r, "mfa:"+strconv.FormatUint(userId, 10))
+	blockedFor, error := countLoginAttempt(controller.attempts, attemptKeys)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return false
+	}
+
+	if blockedFor > 0 {
+		respondLoginBlocked(w, blockedFor)
+		return false
+	}
+
+	valid, error := verifySecondFactor(controller.mfa, mfa, code)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return false
+	}
+
+	if !valid {
+		if error = registerLoginFailure(controller.attempts, attemptKeys); error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return false
+		}
+		responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Invalid code"))
+		return false
+	}
+
+	if error = registerLoginSuccess(controller.attempts, attemptKeys); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return false
+	}
+
+	return true
+}
+
+// respondWithRecoveryCodes replaces a user's recovery codes and returns them, the only time
+// they are shown in plain text
+func (controller MFAController) respondWithRecoveryCodes(w http.ResponseWriter, userId uint64) {
+
+	codes, error := security.GenerateRecoveryCodes(recoveryCodesCount)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var codeHashes []string
+	for _, code := range codes {
+		codeHashes = append(codeHashes, security.HashToken(code))
+	}
+
+	if error = controller.mfa.ReplaceRecoveryCodes(userId, codeHashes); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.MFARecoveryCodes{RecoveryCodes: codes})
+}
+
+// verifySecondFactor checks a TOTP code, refusing codes already used, or else a recovery code,
+// consuming it
+func verifySecondFactor(store persistence.MFAStore, mfa models.MFA, code string) (bool, error) {
+
+	if step, valid := security.ValidateTOTP(mfa.Secret, code, time.Now()); valid {
+		return store.UseMFAStep(mfa.UserId, step)
+	}
+
+	return store.UseRecoveryCode(mfa.UserId,
+		security.HashToken(security.NormalizeRecoveryCode(code)))
+}
+
+// readMFACode reads the code of a request body
+func readMFACode(r *http.Request) (string, error) {
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		return "", error
+	}
+
+	var mfaCode models.MFACode
+	if error = json.Unmarshal(requestBody, &mfaCode); error != nil {
+		return "", error
+	}
+
+	if mfaCode.Code == "" {
+		return "", errors.New("Invalid code")
+	}
+
+	return mfaCode.Code, nil
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// ModerationController handles moderation of user accounts
+type ModerationController struct {
+	users         persistence.UserStore
+	refreshTokens persistence.RefreshTokenStore
+}
+
+// NewModerationController factory
+func NewModerationController(users persistence.UserStore, refreshTokens persistence.RefreshTokenStore) *ModerationController {
+	return &ModerationController{users, refreshTokens}
+}
+
+// SuspendUser suspends a user and revokes its refresh tokens, so it can neither log in nor renew
+// its access token
+func (controller ModerationController) SuspendUser(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := controller.getModeratedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if error := controller.users.SuspendUser(user.ID); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if error := controller.refreshTokens.RevokeUserRefreshTokens(user.ID); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// UnsuspendUser lifts a user's suspension
+func (controller ModerationController) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+
+	user, ok := controller.getModeratedUser(w, r)
+	if !ok {
+		return
+	}
+
+	if error := controller.users.UnsuspendUser(user.ID); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// UpdateUserRole assigns a role to a user, effective from the user's next access token
+func (controller ModerationController) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+
+	tokenUserId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if id == tokenUserId {
+		responses.ErrorResponse(w, http.StatusForbidden, errors.New("Cannot change own role"))
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var roleUpdate models.RoleUpdate
+	if error = json.Unmarshal(requestBody, &roleUpdate); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if !roleUpdate.Role.IsValid() {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid role"))
+		return
+	}
+
+	user, error := controller.users.GetUserById(id)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if user.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if error = controller.users.UpdateUserRole(id, roleUpdate.Role); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// getModeratedUser gets the user of the request path, responding with an error unless the
+// authenticated user's role outranks it
+func (controller ModerationController) getModeratedUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+
+	role, error := security.ExtractRole(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return models.User{}, false
+	}
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return models.User{}, false
+	}
+
+	user, error := controller.users.GetUserById(id)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return models.User{}, false
+	}
+
+	if user.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return models.User{}, false
+	}
+
+	if !role.Outranks(user.Role) {
+		responses.ErrorResponse(w, http.StatusForbidden,
+			errors.New("Cannot moderate a user of equal or higher role"))
+		return models.User{}, false
+	}
+
+	return user, true
+}
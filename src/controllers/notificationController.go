@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+// NotificationController handles notification requests
+type NotificationController struct {
+	repository persistence.NotificationStore
+}
+
+// NewNotificationController factory
+func NewNotificationController(repository persistence.NotificationStore) *NotificationController {
+	return &NotificationController{repository}
+}
+
+// GetNotifications lists a page of the authenticated user's notifications grouped by type and
+// publication, most recent first, along with the count of unread notifications
+func (controller NotificationController) GetNotifications(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	groups, error := controller.repository.GetNotificationGroups(userId, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	unread, error := controller.repository.CountUnreadNotifications(userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(groups) > page.Limit {
+		groups = groups[:page.Limit]
+		cursor := groups[page.Limit-1].Cursor()
+		next = &cursor
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NotificationPage{
+		Page:        models.NewPage(groups, next),
+		UnreadCount: unread,
+	})
+}
+
+// MarkNotificationRead marks a notification of the authenticated user as read, along with the
+// notifications grouped with it
+func (controller NotificationController) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	found, error := controller.repository.MarkNotificationGroupRead(userId, id)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if !found {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// MarkAllNotificationsRead marks every notification of the authenticated user as read
+func (controller NotificationController) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	if error = controller.repository.MarkAllNotificationsRead(userId); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
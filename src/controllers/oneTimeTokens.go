@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/security"
+	"time"
+)
+
+// issueOneTimeToken stores a new single use token of a purpose for a user, at its current email, and
+// returns it in plain text
+func issueOneTimeToken(tokens persistence.OneTimeTokenStore, user models.User, purpose string, lifetime time.Duration) (string, error) {
+
+	token, error := security.GenerateBase64RandomToken()
+	if error != nil {
+		return "", error
+	}
+
+	if _, error = tokens.CreateOneTimeToken(models.OneTimeToken{
+		UserId:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: security.HashToken(token),
+		ExpiresAt: time.Now().Add(lifetime),
+	}); error != nil {
+		return "", error
+	}
+
+	return token, nil
+}
+
+// redeemOneTimeToken marks a usable token of a purpose as used, returning a zero token when
+// it is unknown, expired or already used
+func redeemOneTimeToken(tokens persistence.OneTimeTokenStore, purpose string, token string) (models.OneTimeToken, error) {
+
+	storedToken, error := tokens.GetOneTimeToken(purpose, security.HashToken(token))
+	if error != nil {
+		return models.OneTimeToken{}, error
+	}
+
+	if storedToken.ID == 0 || !storedToken.IsUsable(time.Now()) {
+		return models.OneTimeToken{}, nil
+	}
+
+	wasUnused, error := tokens.UseOneTimeToken(storedToken.ID)
+	if error != nil || !wasUnused {
+		return models.OneTimeToken{}, error
+	}
+
+	return storedToken, nil
+}
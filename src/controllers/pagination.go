@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/models"
+	"errors"
+	"net/http"
+)
+
+// readPageRequest reads the limit and cursor query parameters of a listing, the limit being at
+// most config.MaxPageSize
+func readPageRequest(r *http.Request) (models.PageRequest, error) {
+	return readLimitedPageRequest(r, config.PageSize, config.MaxPageSize)
+}
+
+// readLimitedPageRequest reads the limit and cursor query parameters of a listing, the limit being
+// defaultLimit when absent and at most maxLimit
+func readLimitedPageRequest(r *http.Request, defaultLimit int, maxLimit int) (models.PageRequest, error) {
+
+	limit, error := intQueryParameter(r, "limit", defaultLimit)
+	if error != nil {
+		return models.PageRequest{}, error
+	}
+
+	if limit == 0 {
+		return models.PageRequest{}, errors.New("Invalid limit")
+	}
+
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	request := models.PageRequest{Limit: limit}
+
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		cursor, error := models.DecodeCursor(value)
+		if error != nil {
+			return models.PageRequest{}, error
+		}
+		request.After = &cursor
+	}
+
+	return request, nil
+}
+
+// usersPage builds the page of users read with request, ordered by id
+func usersPage(users []models.User, request models.PageRequest) models.Page {
+
+	var next *models.Cursor
+	if len(users) > request.Limit {
+		users = users[:request.Limit]
+		next = &models.Cursor{ID: users[request.Limit-1].ID}
+	}
+
+	return models.NewPage(users, next)
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/logging"
+	"devbook/src/mail"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/badoux/checkmail"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// PasswordController handles password recovery requests
+type PasswordController struct {
+	users         persistence.UserStore
+	tokens        persistence.OneTimeTokenStore
+	refreshTokens persistence.RefreshTokenStore
+	mailer        mail.Mailer
+}
+
+// NewPasswordController factory
+func NewPasswordController(users persistence.UserStore, tokens persistence.OneTimeTokenStore,
+	refreshTokens persistence.RefreshTokenStore, mailer mail.Mailer) *PasswordController {
+	return &PasswordController{users, tokens, refreshTokens, mailer}
+}
+
+// ForgotPassword emails a password reset token, answering the same way and as fast whether the email
+// exists or not, the account being looked up and the token sent in the background
+func (controller PasswordController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var passwordForgot models.PasswordForgot
+	if error = json.Unmarshal(requestBody, &passwordForgot); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	email := strings.TrimSpace(passwordForgot.Email)
+	if error = checkmail.ValidateFormat(email); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid email format!"))
+		return
+	}
+
+	logger := logging.FromContext(r.Context())
+	go func() {
+		if error := controller.sendResetTokenTo(email); error != nil {
+			logger.Error("failed to send password reset token", logging.Fields{"error": error})
+		}
+	}()
+
+	responses.JsonResponse(w, http.StatusAccepted, nil)
+}
+
+// ResetPassword sets a new password with a password reset token, signing the user out of every
+// session
+func (controller PasswordController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var passwordReset models.PasswordReset
+	if error = json.Unmarshal(requestBody, &passwordReset); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if passwordReset.NewPassword == "" {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid user password!"))
+		return
+	}
+
+	hashedPassword, error := security.Hash(passwordReset.NewPassword)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest,
+			errors.New("Failed to update password"))
+		return
+	}
+
+	token, error := redeemOneTimeToken(controller.tokens,
+		models.TokenPurposePasswordReset, passwordReset.Token)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if token.ID == 0 {
+		responses.ErrorResponse(w, http.StatusBadRequest,
+			errors.New("Invalid or expired password reset token"))
+		return
+	}
+
+	if error = controller.users.UpdateUserPassword(token.UserId, string(hashedPassword)); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if error = controller.refreshTokens.RevokeUserRefreshTokens(token.UserId); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, nil)
+}
+
+// sendResetTokenTo sends a password reset token to the user with an email, if any
+func (controller PasswordController) sendResetTokenTo(email string) error {
+
+	user, error := controller.users.GetUserByEmail(email)
+	if error != nil || user.ID == 0 {
+		return error
+	}
+
+	return controller.sendResetToken(user)
+}
+
+// sendResetToken stores a new password reset token for user and emails it
+func (controller PasswordController) sendResetToken(user models.User) error {
+
+	token, error := issueOneTimeToken(controller.tokens, user,
+		models.TokenPurposePasswordReset, config.PasswordResetTokenLifetime)
+	if error != nil {
+		return error
+	}
+
+	return controller.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your devbook password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the token below to reset your password. "+
+			"It expires in %s and can be used only once.\n\n%s\n\n"+
+			"If you did not ask for a password reset, ignore this message.",
+			user.Name, config.PasswordResetTokenLifetime, token),
+	})
+}
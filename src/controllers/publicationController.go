@@ -1,7 +1,8 @@
 package controllers
 
 import (
-	"devbook/src/database"
+	"devbook/src/config"
+	"devbook/src/events"
 	"devbook/src/models"
 	"devbook/src/persistence"
 	"devbook/src/responses"
@@ -14,9 +15,22 @@ import (
 	"strconv"
 )
 
+// PublicationController handles publication requests
+type PublicationController struct {
+	repository persistence.PublicationStore
+	users      persistence.UserStore
+	publisher  events.Publisher
+}
+
+// NewPublicationController factory
+func NewPublicationController(repository persistence.PublicationStore, users persistence.UserStore,
+	publisher events.Publisher) *PublicationController {
+	return &PublicationController{repository, users, publisher}
+}
+
 
 // CreatePublication creates a publication
-func CreatePublication(w http.ResponseWriter, r *http.Request) {
+func (controller PublicationController) CreatePublication(w http.ResponseWriter, r *http.Request) {
 
 	requestBody, error := ioutil.ReadAll(r.Body)
 
@@ -44,25 +58,61 @@ func CreatePublication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	if publication.QuotedPublicationId != 0 {
+		quoted, error := controller.repository.GetPublicationById(publication.QuotedPublicationId, userId)
+		if error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+
+		if quoted.ID == 0 {
+			responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid quoted publication"))
+			return
+		}
+
+		if respondIfBlocked(w, controller.users, userId, quoted.AuthorId) {
+			return
+		}
+	}
+
+	if config.EmailVerificationRequiredFor == config.EmailVerificationRequiredForPublication {
+		author, error := controller.users.GetUserById(userId)
+		if error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+
+		if author.EmailVerifiedAt == nil {
+			responses.ErrorResponse(w, http.StatusForbidden,
+				errors.New("Verify your email address before publishing"))
+			return
+		}
+	}
+
+	id, error := controller.repository.CreatePublication(publication)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	publication.ID, error = repository.CreatePublication(publication)
+	publication, error = controller.repository.GetPublicationById(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	controller.publisher.Publish(events.Event{Type: events.PublicationCreated, ActorId: userId, PublicationId: id})
+	if publication.Quoted != nil {
+		controller.publisher.Publish(events.Event{Type: events.PublicationQuoted, ActorId: userId,
+			UserId: publication.Quoted.AuthorId, PublicationId: id})
+	}
+	controller.publishMentions(publication)
+
 	responses.JsonResponse(w, http.StatusCreated, publication)
 }
 
 // UpdatePublication updates a publication
-func UpdatePublication(w http.ResponseWriter, r *http.Request) {
+func (controller PublicationController) UpdatePublication(w http.ResponseWriter, r *http.Request) {
 
 	userId, error := security.ExtractUserId(r)
 	if error != nil {
@@ -77,21 +127,24 @@ func UpdatePublication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	role, error := security.ExtractRole(r)
 	if error != nil {
-		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	storedPublication, error := repository.GetPublicationById(id)
+	storedPublication, error := controller.getManagedPublication(id, userId, role)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	if storedPublication.AuthorId != userId {
+	if storedPublication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if storedPublication.AuthorId != userId && !role.Includes(security.RoleModerator) {
 		responses.ErrorResponse(w, http.StatusUnauthorized,
 			errors.New("A user can edit its own publications, only"))
 		return
@@ -109,24 +162,37 @@ func UpdatePublication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	publication.AuthorId = userId
+	publication.AuthorId = storedPublication.AuthorId
+	if publication.Visibility == "" {
+		publication.Visibility = storedPublication.Visibility
+	}
 
 	if error = publication.Prepare(); error != nil {
 		responses.ErrorResponse(w, http.StatusBadRequest, error)
 		return
 	}
 
-	error = repository.UpdatePublication(id, publication)
+	error = controller.repository.UpdatePublication(id, publication)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	publication, error = controller.repository.GetPublicationById(id, publication.AuthorId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	controller.publisher.Publish(events.Event{Type: events.PublicationUpdated, ActorId: userId,
+		UserId: publication.AuthorId, PublicationId: id})
+	controller.publishMentions(publication)
+
 	responses.JsonResponse(w, http.StatusNoContent, nil)
 }
 
 // DeletePublication deletes a publication
-func DeletePublication(w http.ResponseWriter, r *http.Request) {
+func (controller PublicationController) DeletePublication(w http.ResponseWriter, r *http.Request) {
 
 	userId, error := security.ExtractUserId(r)
 	if error != nil {
@@ -141,37 +207,64 @@ func DeletePublication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	role, error := security.ExtractRole(r)
 	if error != nil {
-		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	storedPublication, error := repository.GetPublicationById(id)
+	storedPublication, error := controller.getManagedPublication(id, userId, role)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	if storedPublication.AuthorId != userId {
+	if storedPublication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if storedPublication.AuthorId != userId && !role.Includes(security.RoleModerator) {
 		responses.ErrorResponse(w, http.StatusUnauthorized,
 			errors.New("A user can delete its own publications, only"))
 		return
 	}
 
-	error = repository.DeletePublication(id)
+	error = controller.repository.DeletePublication(id)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	controller.publisher.Publish(events.Event{Type: events.PublicationDeleted, ActorId: userId,
+		UserId: storedPublication.AuthorId, PublicationId: id, Data: storedPublication})
+
 	responses.JsonResponse(w, http.StatusNoContent, nil)
 }
 
+// getManagedPublication gets a publication as seen by userId, or as seen by its author when userId
+// is a moderator, moderators acting on publications they cannot see
+func (controller PublicationController) getManagedPublication(id uint64, userId uint64, role security.Role) (models.Publication, error) {
+
+	if role.Includes(security.RoleModerator) {
+		authorId, error := controller.repository.GetPublicationAuthorId(id)
+		if error != nil || authorId == 0 {
+			return models.Publication{}, error
+		}
+		return controller.repository.GetPublicationById(id, authorId)
+	}
+
+	return controller.repository.GetPublicationById(id, userId)
+}
+
 // GetPublication get a publication by id
-func GetPublication(w http.ResponseWriter, r *http.Request) {
+func (controller PublicationController) GetPublication(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
 
 	pathParameters := mux.Vars(r)
 	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -180,29 +273,170 @@ func GetPublication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	publication, error := controller.repository.GetPublicationById(id, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if publication.ID != 0 {
+		responses.JsonResponse(w, http.StatusOK, publication)
+	} else {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+	}
+}
+
+// GetPublications get a page of the feed of a user, with publications and reposts of the user and
+// the users he/she follows
+func (controller PublicationController) GetPublications(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publications, error := controller.repository.GetPublicationsForUserId(userId, page)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	publication, error := repository.GetPublicationById(id)
+	var next *models.Cursor
+	if len(publications) > page.Limit {
+		publications = publications[:page.Limit]
+		cursor := publications[page.Limit-1].FeedCursor()
+		next = &cursor
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(publications, next))
+}
+
+// GetUserPublications get a page of publications of a user
+func (controller PublicationController) GetUserPublications(w http.ResponseWriter, r *http.Request) {
+
+	viewerId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publications, error := controller.repository.GetUserPublicationById(id, viewerId, page)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	if publication.ID != 0 {
-		responses.JsonResponse(w, http.StatusOK, publication)
-	} else {
+	var next *models.Cursor
+	if len(publications) > page.Limit {
+		publications = publications[:page.Limit]
+		next = &models.Cursor{ID: publications[page.Limit-1].ID}
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(publications, next))
+}
+
+// GetUserMentions get a page of publications mentioning a user, most recent first
+func (controller PublicationController) GetUserMentions(w http.ResponseWriter, r *http.Request) {
+
+	viewerId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publications, error := controller.repository.GetMentionPublications(id, viewerId, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(publications) > page.Limit {
+		publications = publications[:page.Limit]
+		next = &models.Cursor{ID: publications[page.Limit-1].ID}
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(publications, next))
+}
+
+// LikePublication registers the authenticated user's like in publication
+func (controller PublicationController) LikePublication(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publication, error := controller.repository.GetPublicationById(id, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if publication.ID == 0 {
 		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if respondIfBlocked(w, controller.users, userId, publication.AuthorId) {
+		return
+	}
+
+	error = controller.repository.RegisterPublicationLike(id, userId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
 	}
+
+	controller.publisher.Publish(events.Event{Type: events.PublicationLiked, ActorId: userId,
+		UserId: publication.AuthorId, PublicationId: id})
+
+	responses.JsonResponse(w, http.StatusOK, nil)
 }
 
-// GetPublications get all publications of a user and its followers
-func GetPublications(w http.ResponseWriter, r *http.Request) {
+// UnlikePublication removes the authenticated user's like from publication
+func (controller PublicationController) UnlikePublication(w http.ResponseWriter, r *http.Request) {
 
 	userId, error := security.ExtractUserId(r)
 	if error != nil {
@@ -210,25 +444,41 @@ func GetPublications(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publication, error := controller.repository.GetPublicationById(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	publications, error := repository.GetPublicationsForUserId(userId)
+	if publication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	error = controller.repository.RegisterPublicationUnlike(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	responses.JsonResponse(w, http.StatusOK, publications)
+	responses.JsonResponse(w, http.StatusOK, nil)
 }
 
-// GetUserPublications get publications of a user
-func GetUserPublications(w http.ResponseWriter, r *http.Request) {
+// GetPublicationLikes gets a page of users who liked a publication, by id
+func (controller PublicationController) GetPublicationLikes(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
 
 	pathParameters := mux.Vars(r)
 	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -237,25 +487,40 @@ func GetUserPublications(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	publication, error := controller.repository.GetPublicationById(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	publications, error := repository.GetUserPublicationById(id)
+	if publication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	users, error := controller.repository.GetPublicationLikes(id, userId, page)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	responses.JsonResponse(w, http.StatusOK, publications)
+	responses.JsonResponse(w, http.StatusOK, usersPage(users, page))
 }
 
-// LikePublication registers a like in publication
-func LikePublication(w http.ResponseWriter, r *http.Request) {
+// RepostPublication registers the authenticated user's repost of a publication
+func (controller PublicationController) RepostPublication(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
 
 	pathParameters := mux.Vars(r)
 	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -264,25 +529,41 @@ func LikePublication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	publication, error := controller.repository.GetPublicationById(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	error = repository.RegisterPublicationLike(id)
+	if publication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if respondIfBlocked(w, controller.users, userId, publication.AuthorId) {
+		return
+	}
+
+	error = controller.repository.RegisterRepost(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	controller.publisher.Publish(events.Event{Type: events.PublicationReposted, ActorId: userId,
+		UserId: publication.AuthorId, PublicationId: id})
+
 	responses.JsonResponse(w, http.StatusOK, nil)
 }
 
-// LikePublication registers an unlike in publication
-func UnlikePublication(w http.ResponseWriter, r *http.Request) {
+// UnrepostPublication removes the authenticated user's repost of a publication
+func (controller PublicationController) UnrepostPublication(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
 
 	pathParameters := mux.Vars(r)
 	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -291,19 +572,30 @@ func UnlikePublication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	publication, error := controller.repository.GetPublicationById(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewPublicationRepository(db)
-	error = repository.RegisterPublicationUnlike(id)
+	if publication.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	error = controller.repository.RemoveRepost(id, userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
 	responses.JsonResponse(w, http.StatusOK, nil)
-}
\ No newline at end of file
+}
+
+// publishMentions announces the mentions of users in a publication
+func (controller PublicationController) publishMentions(publication models.Publication) {
+	for _, mention := range publication.Mentions {
+		controller.publisher.Publish(events.Event{Type: events.UserMentioned, ActorId: publication.AuthorId,
+			UserId: mention.UserId, PublicationId: publication.ID})
+	}
+}
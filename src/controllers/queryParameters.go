@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// intQueryParameter reads a non negative integer query parameter, defaultValue when absent
+func intQueryParameter(r *http.Request, name string, defaultValue int) (int, error) {
+
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, error := strconv.Atoi(value)
+	if error != nil || parsed < 0 {
+		return 0, fmt.Errorf("Invalid %s", name)
+	}
+
+	return parsed, nil
+}
+
+// timeQueryParameter reads a time query parameter given in RFC 3339 format or as a date in UTC,
+// nil when absent
+func timeQueryParameter(r *http.Request, name string) (*time.Time, error) {
+
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if parsed, error := time.Parse(layout, value); error == nil {
+			return &parsed, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Invalid %s", name)
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// SearchController handles search requests
+type SearchController struct {
+	engine persistence.SearchEngine
+}
+
+// NewSearchController factory
+func NewSearchController(engine persistence.SearchEngine) *SearchController {
+	return &SearchController{engine}
+}
+
+// SearchPublications get a page of publications matching the q query parameter, most relevant
+// first, optionally filtered by author, tag and a since/until date range
+func (controller SearchController) SearchPublications(w http.ResponseWriter, r *http.Request) {
+
+	viewerId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	search := models.PublicationSearch{
+		Query: r.URL.Query().Get("q"),
+		Tag:   r.URL.Query().Get("tag"),
+	}
+
+	if author := r.URL.Query().Get("author"); author != "" {
+		if search.AuthorId, error = strconv.ParseUint(author, 10, 64); error != nil {
+			responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid author"))
+			return
+		}
+	}
+
+	if search.Since, error = timeQueryParameter(r, "since"); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if search.Until, error = timeQueryParameter(r, "until"); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if error = search.Prepare(); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publications, error := controller.engine.SearchPublications(search, viewerId, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(publications) > page.Limit {
+		publications = publications[:page.Limit]
+		cursor := publications[page.Limit-1].SearchCursor()
+		next = &cursor
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(publications, next))
+}
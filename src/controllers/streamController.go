@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/logging"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"devbook/src/streams"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StreamController handles event stream requests
+type StreamController struct {
+	broker *streams.Broker
+}
+
+// NewStreamController factory
+func NewStreamController(broker *streams.Broker) *StreamController {
+	return &StreamController{broker}
+}
+
+// Stream pushes new feed items and notifications of the authenticated user as server-sent events
+// until the client disconnects, first replaying the events missed after its Last-Event-ID
+func (controller StreamController) Stream(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	var lastEventId uint64
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		if lastEventId, error = strconv.ParseUint(value, 10, 64); error != nil {
+			responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid Last-Event-ID"))
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		responses.ErrorResponse(w, http.StatusInternalServerError, errors.New("Streaming is not supported"))
+		return
+	}
+
+	subscription := controller.broker.Connect(userId, lastEventId)
+	defer controller.broker.Disconnect(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, entry := range subscription.Backlog {
+		if error = controller.push(w, entry, userId); error != nil {
+			logging.FromContext(r.Context()).Error("failed to stream event",
+				logging.Fields{"event_id": entry.ID, "user_id": userId, "error": error})
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(config.StreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, error = fmt.Fprint(w, ": heartbeat\n\n"); error != nil {
+				return
+			}
+		case entry, open := <-subscription.Entries:
+			if !open {
+				return
+			}
+			if error = controller.push(w, entry, userId); error != nil {
+				logging.FromContext(r.Context()).Error("failed to stream event",
+					logging.Fields{"event_id": entry.ID, "user_id": userId, "error": error})
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// push writes the event of an entry concerning a user in server-sent events format
+func (controller StreamController) push(w http.ResponseWriter, entry streams.Entry, userId uint64) error {
+
+	message, concerned, error := controller.broker.Message(entry, userId)
+	if error != nil || !concerned {
+		return error
+	}
+
+	data, error := json.Marshal(message.Data)
+	if error != nil {
+		return error
+	}
+
+	_, error = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.Type, data)
+	return error
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"devbook/src/config"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// TagController handles tag requests
+type TagController struct {
+	publications persistence.PublicationStore
+}
+
+// NewTagController factory
+func NewTagController(publications persistence.PublicationStore) *TagController {
+	return &TagController{publications}
+}
+
+// GetTagPublications get a page of publications with a tag, most recent first
+func (controller TagController) GetTagPublications(w http.ResponseWriter, r *http.Request) {
+
+	viewerId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	pathParameters := mux.Vars(r)
+	tag := models.NormalizeTag(pathParameters["tag"])
+	if tag == "" {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid tag"))
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	publications, error := controller.publications.GetTagPublications(tag, viewerId, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(publications) > page.Limit {
+		publications = publications[:page.Limit]
+		next = &models.Cursor{ID: publications[page.Limit-1].ID}
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(publications, next))
+}
+
+// GetTrendingTags lists the tags whose use grows the fastest, over config.TrendingTagsWindow
+func (controller TagController) GetTrendingTags(w http.ResponseWriter, r *http.Request) {
+
+	limit, error := intQueryParameter(r, "limit", config.TrendingTagsLimit)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if limit == 0 {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid limit"))
+		return
+	}
+
+	if limit > config.MaxPageSize {
+		limit = config.MaxPageSize
+	}
+
+	tags, error := controller.publications.GetTrendingTags(config.TrendingTagsWindow, limit)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if tags == nil {
+		tags = []models.TrendingTag{}
+	}
+
+	responses.JsonResponse(w, http.StatusOK, tags)
+}
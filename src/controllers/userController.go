@@ -1,22 +1,43 @@
 package controllers
 
 import (
-	"devbook/src/database"
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/logging"
+	"devbook/src/mail"
 	"devbook/src/models"
 	"devbook/src/persistence"
 	"devbook/src/responses"
 	"devbook/src/security"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/badoux/checkmail"
 	"github.com/gorilla/mux"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
 
+// UserController handles user requests
+type UserController struct {
+	repository    persistence.UserStore
+	tokens        persistence.OneTimeTokenStore
+	refreshTokens persistence.RefreshTokenStore
+	mailer        mail.Mailer
+	publisher     events.Publisher
+}
+
+// NewUserController factory
+func NewUserController(repository persistence.UserStore, tokens persistence.OneTimeTokenStore,
+	refreshTokens persistence.RefreshTokenStore, mailer mail.Mailer, publisher events.Publisher) *UserController {
+	return &UserController{repository, tokens, refreshTokens, mailer, publisher}
+}
+
 // CreateUser creates a user in database
-func CreateUser(w http.ResponseWriter, r *http.Request) {
+func (controller UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	requestBody, error := ioutil.ReadAll(r.Body)
 	if error != nil {
@@ -35,47 +56,49 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	user.ID, error = controller.repository.Create(user)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	user.ID, error = repository.Create(user)
-	if error != nil {
-		responses.ErrorResponse(w, http.StatusInternalServerError, error)
-		return
+	if error = controller.sendVerificationToken(user); error != nil {
+		logging.FromContext(r.Context()).Error("failed to send verification token",
+			logging.Fields{"user_id": user.ID, "error": error})
 	}
 
 	responses.JsonResponse(w, http.StatusCreated, user)
 }
 
-// ListUsers lists all users
-func ListUsers(w http.ResponseWriter, r *http.Request) {
+// ListUsers lists a page of users
+func (controller UserController) ListUsers(w http.ResponseWriter, r *http.Request) {
 
 	description := strings.ToLower(r.URL.Query().Get("desc"))
 
-	db, error := database.Connect()
+	viewerId, error := security.ExtractUserId(r)
 	if error != nil {
-		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	users, error := repository.ListUsers(description)
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	users, error := controller.repository.ListUsers(description, viewerId, page)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	responses.JsonResponse(w, http.StatusOK, users)
+	responses.JsonResponse(w, http.StatusOK, usersPage(users, page))
 }
 
-// FindUserById find a user by id
-func FindUserById(w http.ResponseWriter, r *http.Request) {
+// FindUserById find a user by id, without its password, its role and suspension shown only to
+// itself and moderators
+func (controller UserController) FindUserById(w http.ResponseWriter, r *http.Request) {
 
 	pathParameters := mux.Vars(r)
 	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -84,20 +107,30 @@ func FindUserById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	tokenUserId, error := security.ExtractUserId(r)
 	if error != nil {
-		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	role, error := security.ExtractRole(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	user, error := repository.GetUserById(id)
+	user, error := controller.repository.GetUserById(id)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	user.Password = ""
+	if id != tokenUserId && !role.Includes(security.RoleModerator) {
+		user.Role = ""
+		user.SuspendedAt = nil
+	}
+
 	if user.ID != 0 {
 		responses.JsonResponse(w, http.StatusOK, user)
 	} else {
@@ -107,7 +140,7 @@ func FindUserById(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateUser updates a user
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
+func (controller UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	pathParameters := mux.Vars(r)
 	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -145,25 +178,36 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	storedUser, error := controller.repository.GetUserById(id)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	error = repository.Update(id, user)
+	error = controller.repository.Update(id, user)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	if storedUser.Email != user.Email {
+		if error = controller.tokens.DeleteOneTimeTokens(id, models.TokenPurposeEmailVerification); error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+
+		user.ID = id
+		if error = controller.sendVerificationToken(user); error != nil {
+			logging.FromContext(r.Context()).Error("failed to send verification token",
+				logging.Fields{"user_id": user.ID, "error": error})
+		}
+	}
+
 	responses.JsonResponse(w, http.StatusNoContent, nil)
 }
 
 // DeleteUser deletes a user
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
+func (controller UserController) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	pathParameters := mux.Vars(r)
 	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -184,15 +228,7 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
-	if error != nil {
-		responses.ErrorResponse(w, http.StatusInternalServerError, error)
-		return
-	}
-	defer db.Close()
-
-	repository := persistence.NewUserRepository(db)
-	error = repository.Delete(id)
+	error = controller.repository.Delete(id)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
@@ -203,7 +239,7 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 
 // FollowUser follows a user
-func FollowUser(w http.ResponseWriter, r *http.Request) {
+func (controller UserController) FollowUser(w http.ResponseWriter, r *http.Request) {
 
 	pathParameters := mux.Vars(r)
 	followedId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -224,26 +260,61 @@ func FollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	followed, error := controller.repository.GetUserById(followedId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	error = repository.FollowUser(followedId, followerId)
+	if followed.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if respondIfBlocked(w, controller.repository, followedId, followerId) {
+		return
+	}
+
+	if followed.IsPrivate {
+		following, error := controller.repository.IsFollower(followedId, followerId)
+		if error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+
+		if !following {
+			if error = controller.repository.CreateFollowRequest(followedId, followerId); error != nil {
+				responses.ErrorResponse(w, http.StatusInternalServerError, error)
+				return
+			}
+
+			controller.publisher.Publish(events.Event{
+				Type: events.FollowRequested, ActorId: followerId, UserId: followedId})
+
+			responses.JsonResponse(w, http.StatusAccepted, nil)
+			return
+		}
+	}
+
+	following, error := controller.repository.FollowUser(followedId, followerId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
+	if !following {
+		responses.ErrorResponse(w, http.StatusForbidden, errBlocked)
+		return
+	}
+
+	controller.publisher.Publish(events.Event{Type: events.UserFollowed, ActorId: followerId, UserId: followedId})
+
 	responses.JsonResponse(w, http.StatusOK, nil)
 }
 
 
 // UnfollowUser unfollows a user
-func UnfollowUser(w http.ResponseWriter, r *http.Request) {
+func (controller UserController) UnfollowUser(w http.ResponseWriter, r *http.Request) {
 
 	pathParameters := mux.Vars(r)
 	followedId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
@@ -264,79 +335,326 @@ func UnfollowUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
+	error = controller.repository.UnfollowUser(followedId, followerId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	error = repository.UnfollowUser(followedId, followerId)
+	responses.JsonResponse(w, http.StatusOK, nil)
+}
+
+// GetUserFollowers lists a page of a user's followers
+func (controller UserController) GetUserFollowers(w http.ResponseWriter, r *http.Request) {
+
+	pathParameters := mux.Vars(r)
+	followedId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	users, error := controller.repository.GetFollowersForUserId(followedId, page)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	responses.JsonResponse(w, http.StatusOK, nil)
+	responses.JsonResponse(w, http.StatusOK, usersPage(users, page))
 }
 
-// GetUserFollowers lists a user's followers
-func GetUserFollowers(w http.ResponseWriter, r *http.Request) {
+// GetFollowedUsers lists a page of users a user follows
+func (controller UserController) GetFollowedUsers(w http.ResponseWriter, r *http.Request) {
 
 	pathParameters := mux.Vars(r)
-	followedId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	followerId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusBadRequest, error)
 		return
 	}
 
-	db, error := database.Connect()
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	users, error := controller.repository.GetFollowedUsersForUserId(followerId, page)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	users, error := repository.GetFollowersForUserId(followedId)
+	responses.JsonResponse(w, http.StatusOK, usersPage(users, page))
+}
+
+// UpdatePrivacy makes the authenticated user's account private or public
+func (controller UserController) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	tokenUserId, error := security.ExtractUserId(r)
 	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	if id != tokenUserId {
+		responses.ErrorResponse(w, http.StatusForbidden,
+			errors.New("Cannot change other user's data"))
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var privacyUpdate models.PrivacyUpdate
+	if error = json.Unmarshal(requestBody, &privacyUpdate); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if error = controller.repository.UpdateUserPrivacy(id, privacyUpdate.IsPrivate); error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	responses.JsonResponse(w, http.StatusOK, users)
+	responses.JsonResponse(w, http.StatusNoContent, nil)
 }
 
-// GetFollowedUsers lists users a user follows
-func GetFollowedUsers(w http.ResponseWriter, r *http.Request) {
+// GetFollowRequests get a page of pending requests to follow the authenticated user
+func (controller UserController) GetFollowRequests(w http.ResponseWriter, r *http.Request) {
 
 	pathParameters := mux.Vars(r)
-	followerId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	tokenUserId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	if id != tokenUserId {
+		responses.ErrorResponse(w, http.StatusForbidden,
+			errors.New("Cannot see other user's follow requests"))
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	requests, error := controller.repository.GetFollowRequests(id, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(requests) > page.Limit {
+		requests = requests[:page.Limit]
+		cursor := requests[page.Limit-1].Cursor()
+		next = &cursor
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(requests, next))
+}
+
+// ApproveFollowRequest makes the author of a request to follow the authenticated user a follower
+func (controller UserController) ApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	controller.resolveFollowRequest(w, r, func(followedId uint64, followerId uint64) (bool, error) {
+
+		found, error := controller.repository.ApproveFollowRequest(followedId, followerId)
+		if found && error == nil {
+			controller.publisher.Publish(events.Event{
+				Type: events.FollowApproved, ActorId: followedId, UserId: followerId})
+		}
+
+		return found, error
+	})
+}
+
+// RejectFollowRequest discards a request to follow the authenticated user
+func (controller UserController) RejectFollowRequest(w http.ResponseWriter, r *http.Request) {
+	controller.resolveFollowRequest(w, r, controller.repository.DeleteFollowRequest)
+}
+
+// resolveFollowRequest resolves a request to follow the authenticated user with resolve, which
+// tells if the request existed
+func (controller UserController) resolveFollowRequest(w http.ResponseWriter, r *http.Request,
+	resolve func(followedId uint64, followerId uint64) (bool, error)) {
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	followerId, error := strconv.ParseUint(pathParameters["followerId"], 10, 64)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusBadRequest, error)
 		return
 	}
 
-	db, error := database.Connect()
+	tokenUserId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	if id != tokenUserId {
+		responses.ErrorResponse(w, http.StatusForbidden,
+			errors.New("Cannot answer other user's follow requests"))
+		return
+	}
+
+	found, error := resolve(id, followerId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
-	defer db.Close()
 
-	repository := persistence.NewUserRepository(db)
-	users, error := repository.GetFollowedUsersForUserId(followerId)
+	if !found {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// BlockUser blocks a user for the authenticated user, ending follows between both users
+func (controller UserController) BlockUser(w http.ResponseWriter, r *http.Request) {
+	controller.relateToUser(w, r, controller.repository.BlockUser, errors.New("User cannot block itself"))
+}
+
+// UnblockUser lifts the authenticated user's block of a user
+func (controller UserController) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	controller.relateToUser(w, r, controller.repository.UnblockUser, errors.New("User cannot unblock itself"))
+}
+
+// MuteUser hides a user from the authenticated user's feed and searches
+func (controller UserController) MuteUser(w http.ResponseWriter, r *http.Request) {
+	controller.relateToUser(w, r, controller.repository.MuteUser, errors.New("User cannot mute itself"))
+}
+
+// UnmuteUser lifts the authenticated user's mute of a user
+func (controller UserController) UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	controller.relateToUser(w, r, controller.repository.UnmuteUser, errors.New("User cannot unmute itself"))
+}
+
+// GetBlockedUsers get a page of users blocked by the authenticated user
+func (controller UserController) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	controller.listOwnRelations(w, r, controller.repository.GetBlockedUsers, errors.New("Cannot see other user's blocks"))
+}
+
+// GetMutedUsers get a page of users muted by the authenticated user
+func (controller UserController) GetMutedUsers(w http.ResponseWriter, r *http.Request) {
+	controller.listOwnRelations(w, r, controller.repository.GetMutedUsers, errors.New("Cannot see other user's mutes"))
+}
+
+// relateToUser registers or removes with relate a relation of the authenticated user to the user
+// of the request path, failing with selfError when they are the same user
+func (controller UserController) relateToUser(w http.ResponseWriter, r *http.Request,
+	relate func(userId uint64, otherId uint64) error, selfError error) {
+
+	pathParameters := mux.Vars(r)
+	otherId, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	if otherId == userId {
+		responses.ErrorResponse(w, http.StatusBadRequest, selfError)
+		return
+	}
+
+	other, error := controller.repository.GetUserById(otherId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusInternalServerError, error)
 		return
 	}
 
-	responses.JsonResponse(w, http.StatusOK, users)
+	if other.ID == 0 {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if error = relate(userId, otherId); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
 }
 
-// UpdatePassword updates a user's password
-func UpdatePassword(w http.ResponseWriter, r *http.Request) {
+// listOwnRelations lists with list a page of users related to the authenticated user, failing
+// with otherError when the request path names another user
+func (controller UserController) listOwnRelations(w http.ResponseWriter, r *http.Request,
+	list func(userId uint64, page models.PageRequest) ([]models.User, error), otherError error) {
+
+	pathParameters := mux.Vars(r)
+	id, error := strconv.ParseUint(pathParameters["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	tokenUserId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	if id != tokenUserId {
+		responses.ErrorResponse(w, http.StatusForbidden, otherError)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	users, error := list(id, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, usersPage(users, page))
+}
+
+// UpdatePassword updates a user's password, signing it out of every session
+func (controller UserController) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 
 	loggedUserId, error := security.ExtractUserId(r)
 	if error != nil {
@@ -369,15 +687,7 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db, error := database.Connect()
-	if error != nil {
-		responses.ErrorResponse(w, http.StatusInternalServerError, error)
-		return
-	}
-	defer db.Close()
-
-	repository := persistence.NewUserRepository(db)
-	currentPassword, error := repository.GetUserPasswordById(userId)
+	currentPassword, error := controller.repository.GetUserPasswordById(userId)
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusBadRequest, error)
 		return
@@ -397,13 +707,118 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	error = repository.UpdateUserPassword(
+	error = controller.repository.UpdateUserPassword(
 		userId, string(hashedPassword))
 	if error != nil {
 		responses.ErrorResponse(w, http.StatusBadRequest, error)
 		return
 	}
 
+	if error = controller.refreshTokens.RevokeUserRefreshTokens(userId); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+
+	responses.JsonResponse(w, http.StatusOK, nil)
+}
+
+// VerifyEmail marks a user's email as verified with the token emailed to it, as long as the user
+// still has the address the token was sent to
+func (controller UserController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+
+	token, error := redeemOneTimeToken(controller.tokens,
+		models.TokenPurposeEmailVerification, r.URL.Query().Get("token"))
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if token.ID == 0 {
+		responses.ErrorResponse(w, http.StatusBadRequest,
+			errors.New("Invalid or expired verification token"))
+		return
+	}
+
+	user, error := controller.repository.GetUserById(token.UserId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if user.ID == 0 || user.Email != token.Email {
+		responses.ErrorResponse(w, http.StatusBadRequest,
+			errors.New("Invalid or expired verification token"))
+		return
+	}
+
+	if error = controller.repository.VerifyUserEmail(token.UserId, token.Email); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
 
 	responses.JsonResponse(w, http.StatusOK, nil)
-}
\ No newline at end of file
+}
+
+// ResendVerification emails a new verification token to an unverified account, answering
+// the same way and as fast whether the email exists or not, the account being looked up and
+// the token sent in the background
+func (controller UserController) ResendVerification(w http.ResponseWriter, r *http.Request) {
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var verificationResend models.VerificationResend
+	if error = json.Unmarshal(requestBody, &verificationResend); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	email := strings.TrimSpace(verificationResend.Email)
+	if error = checkmail.ValidateFormat(email); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, errors.New("Invalid email format!"))
+		return
+	}
+
+	logger := logging.FromContext(r.Context())
+	go func() {
+		if error := controller.resendVerificationTo(email); error != nil {
+			logger.Error("failed to send verification token", logging.Fields{"error": error})
+		}
+	}()
+
+	responses.JsonResponse(w, http.StatusAccepted, nil)
+}
+
+// resendVerificationTo sends a verification token to the unverified user with an email, if any
+func (controller UserController) resendVerificationTo(email string) error {
+
+	user, error := controller.repository.GetUserByEmail(email)
+	if error != nil || user.ID == 0 || user.EmailVerifiedAt != nil {
+		return error
+	}
+
+	return controller.sendVerificationToken(user)
+}
+
+// sendVerificationToken stores a new email verification token for user and emails a link to use it
+func (controller UserController) sendVerificationToken(user models.User) error {
+
+	token, error := issueOneTimeToken(controller.tokens, user,
+		models.TokenPurposeEmailVerification, config.EmailVerificationTokenLifetime)
+	if error != nil {
+		return error
+	}
+
+	return controller.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your devbook email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email address. "+
+			"It expires in %s.\n\n%s/users/verify?token=%s\n\n"+
+			"If you did not create a devbook account, ignore this message.",
+			user.Name, config.EmailVerificationTokenLifetime, config.PublicURL, url.QueryEscape(token)),
+	})
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/responses"
+	"devbook/src/security"
+	"devbook/src/webhooks"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// WebhookController handles webhook requests
+type WebhookController struct {
+	repository persistence.WebhookStore
+	dispatcher *webhooks.Dispatcher
+}
+
+// NewWebhookController factory
+func NewWebhookController(repository persistence.WebhookStore, dispatcher *webhooks.Dispatcher) *WebhookController {
+	return &WebhookController{repository, dispatcher}
+}
+
+// CreateWebhook registers a webhook of the authenticated user, returning the secret signing its
+// payloads this time only
+func (controller WebhookController) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	requestBody, error := ioutil.ReadAll(r.Body)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnprocessableEntity, error)
+		return
+	}
+
+	var webhook models.Webhook
+	if error = json.Unmarshal(requestBody, &webhook); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if error = webhook.Prepare(); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if error = webhooks.CheckURL(r.Context(), webhook.URL); error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	webhook.UserId = userId
+	webhook.Secret, error = security.GenerateBase64RandomToken()
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	id, error := controller.repository.CreateWebhook(webhook)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	webhook, error = controller.repository.GetWebhookById(id)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, webhook)
+}
+
+// GetWebhooks lists a page of the webhooks of the authenticated user, without their secrets
+func (controller WebhookController) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	webhooks, error := controller.repository.GetWebhooks(userId, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(webhooks) > page.Limit {
+		webhooks = webhooks[:page.Limit]
+		next = &models.Cursor{ID: webhooks[page.Limit-1].ID}
+	}
+
+	for index := range webhooks {
+		webhooks[index].Secret = ""
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(webhooks, next))
+}
+
+// DeleteWebhook deletes a webhook of the authenticated user along with its deliveries
+func (controller WebhookController) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+
+	webhook, ok := controller.getWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if error := controller.repository.DeleteWebhook(webhook.ID); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// GetDeliveries lists a page of the deliveries to a webhook of the authenticated user, most recent
+// first
+func (controller WebhookController) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+
+	webhook, ok := controller.getWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	page, error := readPageRequest(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	deliveries, error := controller.repository.GetDeliveries(webhook.ID, page)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	var next *models.Cursor
+	if len(deliveries) > page.Limit {
+		deliveries = deliveries[:page.Limit]
+		next = &models.Cursor{ID: deliveries[page.Limit-1].ID}
+	}
+
+	responses.JsonResponse(w, http.StatusOK, models.NewPage(deliveries, next))
+}
+
+// RetryDelivery queues a dead delivery to a webhook of the authenticated user again
+func (controller WebhookController) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+
+	webhook, ok := controller.getWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryId, error := strconv.ParseUint(mux.Vars(r)["deliveryId"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return
+	}
+
+	delivery, error := controller.repository.GetDeliveryById(deliveryId)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if delivery.ID == 0 || delivery.WebhookId != webhook.ID {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if delivery.Status != models.DeliveryDead {
+		responses.ErrorResponse(w, http.StatusConflict, errors.New("Only dead deliveries can be retried"))
+		return
+	}
+
+	if error = controller.dispatcher.Retry(delivery); error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusAccepted, nil)
+}
+
+// getWebhook gets the webhook of the request path, responding with an error unless it belongs to
+// the authenticated user
+func (controller WebhookController) getWebhook(w http.ResponseWriter, r *http.Request) (models.Webhook, bool) {
+
+	userId, error := security.ExtractUserId(r)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusUnauthorized, error)
+		return models.Webhook{}, false
+	}
+
+	id, error := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusBadRequest, error)
+		return models.Webhook{}, false
+	}
+
+	webhook, error := controller.repository.GetWebhookById(id)
+	if error != nil {
+		responses.ErrorResponse(w, http.StatusInternalServerError, error)
+		return models.Webhook{}, false
+	}
+
+	if webhook.ID == 0 || webhook.UserId != userId {
+		responses.JsonResponse(w, http.StatusNotFound, nil)
+		return models.Webhook{}, false
+	}
+
+	return webhook, true
+}
@@ -6,7 +6,8 @@ import (
 	_ "github.com/go-sql-driver/mysql" // database connection driver
 )
 
-// Connect returns a connection do the database
+// Connect returns a connection pool to the database, meant to be opened once
+// and shared by the whole application
 func Connect() (*sql.DB, error) {
 	db, error := sql.Open("mysql", config.ConnectionString)
 
@@ -14,10 +15,14 @@ func Connect() (*sql.DB, error) {
 		return nil, error
 	}
 
+	db.SetMaxOpenConns(config.DBMaxOpenConnections)
+	db.SetMaxIdleConns(config.DBMaxIdleConnections)
+	db.SetConnMaxLifetime(config.DBConnectionMaxLifetime)
+
 	if error = db.Ping(); error != nil {
 		db.Close()
 		return nil, error
 	}
 
 	return db, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,382 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var embeddedMigrations embed.FS
+
+// migrationFilePattern matches migration file names, like 0001_initial_schema.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration represents a numbered schema change with the scripts to apply and revert it
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus represents a migration and when it was applied, if ever
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads the migrations of a file system, ordered by version
+func LoadMigrations(files fs.FS) ([]Migration, error) {
+
+	paths, error := fs.Glob(files, "*.sql")
+	if error != nil {
+		return nil, error
+	}
+
+	migrationsByVersion := make(map[uint64]*Migration)
+	hasUpScript := make(map[uint64]bool)
+
+	for _, path := range paths {
+		matches := migrationFilePattern.FindStringSubmatch(path)
+		if matches == nil {
+			return nil, fmt.Errorf("Invalid migration file name %s", path)
+		}
+
+		version, error := strconv.ParseUint(matches[1], 10, 64)
+		if error != nil {
+			return nil, error
+		}
+
+		script, error := fs.ReadFile(files, path)
+		if error != nil {
+			return nil, error
+		}
+
+		migration, found := migrationsByVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrationsByVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("Migration version %d used by %s and %s",
+				version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(script)
+			hasUpScript[version] = true
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range migrationsByVersion {
+		if !hasUpScript[migration.Version] {
+			return nil, fmt.Errorf("Migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// CreateMigration writes empty up and down scripts for a new migration in directory
+func CreateMigration(directory string, name string) ([]string, error) {
+
+	if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+		return nil, fmt.Errorf("Invalid migration name %s, use letters, digits and underscores", name)
+	}
+
+	migrations, error := LoadMigrations(os.DirFS(directory))
+	if error != nil {
+		return nil, error
+	}
+
+	var version uint64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(directory, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+		if error = ioutil.WriteFile(path, []byte{}, 0644); error != nil {
+			return nil, error
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// Migrator applies and reverts migrations, tracking them in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// Up applies every pending migration, returning the applied ones, refusing to run on a schema
+// created before migrations were tracked until it is baselined
+func (migrator Migrator) Up() ([]Migration, error) {
+
+	applied, error := migrator.appliedMigrations()
+	if error != nil {
+		return nil, error
+	}
+
+	if len(applied) == 0 {
+		untracked, error := migrator.hasTable("users")
+		if error != nil {
+			return nil, error
+		}
+		if untracked {
+			return nil, errors.New("The database has a schema but no applied migrations, " +
+				"run migrate baseline to adopt it")
+		}
+	}
+
+	var migrated []Migration
+	for _, migration := range migrator.migrations {
+		if _, found := applied[migration.Version]; found {
+			continue
+		}
+
+		if error = migrator.execute(migration.Up); error != nil {
+			return migrated, fmt.Errorf("Migration %d_%s failed: %v", migration.Version, migration.Name, error)
+		}
+
+		if _, error = migrator.db.Exec(
+			"insert into schema_migrations (version, name) values (?, ?)",
+			migration.Version, migration.Name); error != nil {
+			return migrated, error
+		}
+
+		migrated = append(migrated, migration)
+	}
+
+	return migrated, nil
+}
+
+// Baseline records the migrations up to version as applied without running them, adopting a
+// schema created before migrations were tracked, like one created from ddl.sql, which matches
+// version 1
+func (migrator Migrator) Baseline(version uint64) ([]Migration, error) {
+
+	applied, error := migrator.appliedMigrations()
+	if error != nil {
+		return nil, error
+	}
+
+	if len(applied) > 0 {
+		return nil, errors.New("Cannot baseline a database with applied migrations")
+	}
+
+	baselined, error := migrationsUpTo(migrator.migrations, version)
+	if error != nil {
+		return nil, error
+	}
+
+	for _, migration := range baselined {
+		if _, error = migrator.db.Exec(
+			"insert into schema_migrations (version, name) values (?, ?)",
+			migration.Version, migration.Name); error != nil {
+			return nil, error
+		}
+	}
+
+	return baselined, nil
+}
+
+// Down reverts the last applied migration, returning nil when there is none
+func (migrator Migrator) Down() (*Migration, error) {
+
+	applied, error := migrator.appliedMigrations()
+	if error != nil {
+		return nil, error
+	}
+
+	for index := len(migrator.migrations) - 1; index >= 0; index-- {
+		migration := migrator.migrations[index]
+		if _, found := applied[migration.Version]; !found {
+			continue
+		}
+
+		if error = migrator.execute(migration.Down); error != nil {
+			return nil, fmt.Errorf("Migration %d_%s failed: %v", migration.Version, migration.Name, error)
+		}
+
+		if _, error = migrator.db.Exec(
+			"delete from schema_migrations where version = ?", migration.Version); error != nil {
+			return nil, error
+		}
+
+		return &migration, nil
+	}
+
+	return nil, nil
+}
+
+// Status lists every migration and when it was applied
+func (migrator Migrator) Status() ([]MigrationStatus, error) {
+
+	applied, error := migrator.appliedMigrations()
+	if error != nil {
+		return nil, error
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range migrator.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, found := applied[migration.Version]; found {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// appliedMigrations returns the applied migration versions and when they were applied
+func (migrator Migrator) appliedMigrations() (map[uint64]time.Time, error) {
+
+	if _, error := migrator.db.Exec(
+		`create table if not exists schema_migrations (
+				version bigint unsigned primary key,
+				name varchar(255) not null,
+				applied_at timestamp default current_timestamp()
+			) ENGINE=INNODB`); error != nil {
+		return nil, error
+	}
+
+	resultSet, error := migrator.db.Query("select version, applied_at from schema_migrations")
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	applied := make(map[uint64]time.Time)
+
+	for resultSet.Next() {
+		var version uint64
+		var appliedAt time.Time
+		if error = resultSet.Scan(&version, &appliedAt); error != nil {
+			return nil, error
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, resultSet.Err()
+}
+
+// hasTable tells if the database has a table
+func (migrator Migrator) hasTable(name string) (bool, error) {
+
+	resultSet, error := migrator.db.Query(
+		"select 1 from information_schema.tables where table_schema = database() and table_name = ?", name)
+	if error != nil {
+		return false, error
+	}
+	defer resultSet.Close()
+
+	return resultSet.Next(), resultSet.Err()
+}
+
+// migrationsUpTo returns the migrations up to version, which must be the version of one of them
+func migrationsUpTo(migrations []Migration, version uint64) ([]Migration, error) {
+
+	for index, migration := range migrations {
+		if migration.Version == version {
+			return migrations[:index+1], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown migration version %d", version)
+}
+
+// execute runs each statement of a migration script
+func (migrator Migrator) execute(script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, error := migrator.db.Exec(statement); error != nil {
+			return error
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on semicolons outside quotes and comments
+func splitStatements(script string) []string {
+
+	var statements []string
+	var statement strings.Builder
+	var quote rune
+	inComment := false
+
+	flush := func() {
+		if trimmed := strings.TrimSpace(statement.String()); trimmed != "" {
+			statements = append(statements, trimmed)
+		}
+		statement.Reset()
+	}
+
+	runes := []rune(script)
+	for index := 0; index < len(runes); index++ {
+		character := runes[index]
+
+		switch {
+		case inComment:
+			if character == '\n' {
+				inComment = false
+				statement.WriteRune(character)
+			}
+			continue
+		case quote != 0:
+			if character == quote {
+				quote = 0
+			}
+		case character == '\'' || character == '"' || character == '`':
+			quote = character
+		case character == '-' && index+1 < len(runes) && runes[index+1] == '-':
+			inComment = true
+			continue
+		case character == ';':
+			flush()
+			continue
+		}
+
+		statement.WriteRune(character)
+	}
+	flush()
+
+	return statements
+}
+
+// NewMigrator factory, using the migrations embedded in the binary
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+
+	files, error := fs.Sub(embeddedMigrations, "migrations")
+	if error != nil {
+		return nil, error
+	}
+
+	migrations, error := LoadMigrations(files)
+	if error != nil {
+		return nil, error
+	}
+
+	return &Migrator{db, migrations}, nil
+}
@@ -1,7 +1,3 @@
-CREATE DATABASE IF NOT EXISTS devbook;
-
-USE devbook;
-
 DROP TABLE IF EXISTS publications;
 DROP TABLE IF EXISTS followers;
 DROP TABLE IF EXISTS users;
@@ -31,4 +27,4 @@ CREATE TABLE publications (
     likes int default 0,
     created_at timestamp default current_timestamp(),
     FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE
-) ENGINE=INNODB;
\ No newline at end of file
+) ENGINE=INNODB;
@@ -0,0 +1,11 @@
+CREATE TABLE refresh_tokens (
+    id int auto_increment primary key,
+    user_id int not null,
+    family_id char(64) not null,
+    token_hash char(64) not null unique,
+    expires_at timestamp not null,
+    revoked_at timestamp null default null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX (family_id)
+) ENGINE=INNODB;
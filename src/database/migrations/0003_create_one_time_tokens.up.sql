@@ -0,0 +1,10 @@
+CREATE TABLE one_time_tokens (
+    id int auto_increment primary key,
+    user_id int not null,
+    purpose varchar(50) not null,
+    token_hash char(64) not null unique,
+    expires_at timestamp not null,
+    used_at timestamp null default null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=INNODB;
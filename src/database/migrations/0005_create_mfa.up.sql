@@ -0,0 +1,17 @@
+CREATE TABLE user_mfa (
+    user_id int primary key,
+    secret varchar(64) not null,
+    enabled_at timestamp null default null,
+    last_used_step bigint unsigned not null default 0,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=INNODB;
+
+CREATE TABLE mfa_recovery_codes (
+    id int auto_increment primary key,
+    user_id int not null,
+    code_hash char(64) not null,
+    used_at timestamp null default null,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, code_hash)
+) ENGINE=INNODB;
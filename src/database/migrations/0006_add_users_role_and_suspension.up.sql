@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN role varchar(20) not null default 'user' AFTER email_verified_at;
+ALTER TABLE users ADD COLUMN suspended_at timestamp null default null AFTER role;
@@ -0,0 +1,5 @@
+CREATE TABLE login_attempts (
+    attempt_key varchar(255) primary key,
+    failures int unsigned not null,
+    last_failure_at timestamp not null default current_timestamp()
+) ENGINE=INNODB;
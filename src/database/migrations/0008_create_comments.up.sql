@@ -0,0 +1,11 @@
+CREATE TABLE comments (
+    id int auto_increment primary key,
+    publication_id int not null,
+    author_id int not null,
+    content varchar(500) not null,
+    created_at timestamp default current_timestamp(),
+    updated_at timestamp null default null,
+    FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX (publication_id, id)
+) ENGINE=INNODB;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS comment_tree;
+DELETE FROM comments WHERE parent_id IS NOT NULL;
+ALTER TABLE comments DROP INDEX parent_id;
+ALTER TABLE comments DROP COLUMN parent_id;
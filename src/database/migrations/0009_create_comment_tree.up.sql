@@ -0,0 +1,14 @@
+ALTER TABLE comments ADD COLUMN parent_id int null default null AFTER author_id;
+ALTER TABLE comments ADD INDEX (parent_id, id);
+
+CREATE TABLE comment_tree (
+    ancestor_id int not null,
+    descendant_id int not null,
+    depth int not null,
+    FOREIGN KEY (ancestor_id) REFERENCES comments(id) ON DELETE CASCADE,
+    FOREIGN KEY (descendant_id) REFERENCES comments(id) ON DELETE CASCADE,
+    PRIMARY KEY (ancestor_id, descendant_id),
+    INDEX (descendant_id)
+) ENGINE=INNODB;
+
+INSERT INTO comment_tree (ancestor_id, descendant_id, depth) SELECT id, id, 0 FROM comments;
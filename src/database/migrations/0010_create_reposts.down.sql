@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS reposts;
+ALTER TABLE publications DROP INDEX quoted_publication_id;
+ALTER TABLE publications DROP COLUMN quoted_publication_id;
@@ -0,0 +1,12 @@
+ALTER TABLE publications ADD COLUMN quoted_publication_id int null default null AFTER author_id;
+ALTER TABLE publications ADD INDEX (quoted_publication_id);
+
+CREATE TABLE reposts (
+    publication_id int not null,
+    user_id int not null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (publication_id, user_id),
+    INDEX (user_id, created_at)
+) ENGINE=INNODB;
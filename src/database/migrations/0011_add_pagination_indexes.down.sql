@@ -0,0 +1,2 @@
+ALTER TABLE followers DROP INDEX followers_follower_user;
+ALTER TABLE publications DROP INDEX publications_author_created;
@@ -0,0 +1,2 @@
+ALTER TABLE publications ADD INDEX publications_author_created (author_id, created_at, id);
+ALTER TABLE followers ADD INDEX followers_follower_user (follower_id, user_id);
@@ -0,0 +1 @@
+ALTER TABLE publications DROP COLUMN visibility;
@@ -0,0 +1 @@
+ALTER TABLE publications ADD COLUMN visibility varchar(20) not null default 'public' AFTER author_id;
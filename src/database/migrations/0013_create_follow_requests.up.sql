@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN is_private boolean not null default false AFTER suspended_at;
+
+CREATE TABLE follow_requests (
+    user_id int not null,
+    follower_id int not null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, follower_id),
+    INDEX (user_id, created_at, follower_id)
+) ENGINE=INNODB;
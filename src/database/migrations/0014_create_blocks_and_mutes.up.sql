@@ -0,0 +1,18 @@
+CREATE TABLE user_blocks (
+    user_id int not null,
+    blocked_id int not null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, blocked_id),
+    INDEX (blocked_id)
+) ENGINE=INNODB;
+
+CREATE TABLE user_mutes (
+    user_id int not null,
+    muted_id int not null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, muted_id)
+) ENGINE=INNODB;
@@ -0,0 +1,3 @@
+ALTER TABLE publications DROP INDEX publications_created;
+DROP TABLE IF EXISTS publication_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,15 @@
+CREATE TABLE tags (
+    id int auto_increment primary key,
+    name varchar(50) not null unique
+) ENGINE=INNODB;
+
+CREATE TABLE publication_tags (
+    publication_id int not null,
+    tag_id int not null,
+    FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (publication_id, tag_id),
+    INDEX (tag_id, publication_id)
+) ENGINE=INNODB;
+
+ALTER TABLE publications ADD INDEX publications_created (created_at);
@@ -0,0 +1,10 @@
+CREATE TABLE publication_mentions (
+    publication_id int not null,
+    mention_offset int not null,
+    mention_length int not null,
+    user_id int not null,
+    FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (publication_id, mention_offset),
+    INDEX (user_id, publication_id)
+) ENGINE=INNODB;
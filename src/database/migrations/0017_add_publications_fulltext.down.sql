@@ -0,0 +1 @@
+ALTER TABLE publications DROP INDEX publications_fulltext;
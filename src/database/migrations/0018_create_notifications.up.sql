@@ -0,0 +1,16 @@
+CREATE TABLE notifications (
+    id int auto_increment primary key,
+    user_id int not null,
+    type varchar(20) not null,
+    actor_id int not null,
+    publication_id int null default null,
+    comment_id int null default null,
+    read_at timestamp null default null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    INDEX (user_id, type, publication_id, id),
+    INDEX (user_id, read_at)
+) ENGINE=INNODB;
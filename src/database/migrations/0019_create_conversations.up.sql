@@ -0,0 +1,28 @@
+CREATE TABLE conversations (
+    id int auto_increment primary key,
+    direct_key varchar(41) not null unique,
+    created_at timestamp default current_timestamp(),
+    updated_at timestamp default current_timestamp()
+) ENGINE=INNODB;
+
+CREATE TABLE conversation_members (
+    conversation_id int not null,
+    user_id int not null,
+    last_read_message_id int not null default 0,
+    read_at timestamp null default null,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (conversation_id, user_id),
+    INDEX (user_id)
+) ENGINE=INNODB;
+
+CREATE TABLE messages (
+    id int auto_increment primary key,
+    conversation_id int not null,
+    sender_id int not null,
+    content varchar(1000) not null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX (conversation_id, id)
+) ENGINE=INNODB;
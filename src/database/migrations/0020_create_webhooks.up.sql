@@ -0,0 +1,27 @@
+CREATE TABLE webhooks (
+    id int auto_increment primary key,
+    user_id int not null,
+    url varchar(2048) not null,
+    event_types varchar(255) not null,
+    secret varchar(100) not null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX (user_id, id)
+) ENGINE=INNODB;
+
+CREATE TABLE webhook_deliveries (
+    id int auto_increment primary key,
+    webhook_id int not null,
+    event_type varchar(50) not null,
+    payload text not null,
+    status varchar(20) not null default 'pending',
+    attempts int not null default 0,
+    response_status int null default null,
+    last_error varchar(255) null default null,
+    next_attempt_at timestamp null default null,
+    delivered_at timestamp null default null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    INDEX (webhook_id, id),
+    INDEX (status, next_attempt_at)
+) ENGINE=INNODB;
@@ -0,0 +1 @@
+ALTER TABLE notifications DROP INDEX notifications_aggregation_key, DROP COLUMN aggregation_key;
@@ -0,0 +1,12 @@
+ALTER TABLE notifications ADD COLUMN aggregation_key varchar(100) null default null;
+
+DELETE n FROM notifications n JOIN notifications kept ON (
+    kept.user_id = n.user_id AND kept.type = n.type AND kept.actor_id = n.actor_id AND
+    kept.publication_id <=> n.publication_id AND kept.comment_id <=> n.comment_id AND kept.id < n.id
+);
+
+UPDATE notifications SET aggregation_key = concat_ws(':', user_id, type, actor_id,
+    coalesce(publication_id, 0), coalesce(comment_id, 0));
+
+ALTER TABLE notifications MODIFY aggregation_key varchar(100) not null,
+    ADD UNIQUE INDEX notifications_aggregation_key (aggregation_key);
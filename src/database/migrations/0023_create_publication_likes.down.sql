@@ -0,0 +1,10 @@
+ALTER TABLE publications ADD COLUMN likes int default 0;
+
+UPDATE publications p SET likes = (
+    SELECT count(*) FROM publication_likes l WHERE l.publication_id = p.id
+) + coalesce((
+    SELECT legacy.likes FROM legacy_publication_likes legacy WHERE legacy.publication_id = p.id
+), 0);
+
+DROP TABLE IF EXISTS publication_likes;
+DROP TABLE IF EXISTS legacy_publication_likes;
@@ -0,0 +1,22 @@
+-- Likes were a bare counter on publications, without the users who liked them, so they cannot be
+-- turned into rows of publication_likes. Existing counts are kept in legacy_publication_likes,
+-- which the API does not read: like counts restart from zero once this migration is applied.
+CREATE TABLE legacy_publication_likes (
+    publication_id int not null primary key,
+    likes int not null,
+    FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE
+) ENGINE=INNODB;
+
+INSERT INTO legacy_publication_likes (publication_id, likes)
+    SELECT id, likes FROM publications WHERE likes > 0;
+
+CREATE TABLE publication_likes (
+    publication_id int not null,
+    user_id int not null,
+    created_at timestamp default current_timestamp(),
+    FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY(publication_id, user_id)
+) ENGINE=INNODB;
+
+ALTER TABLE publications DROP COLUMN likes;
@@ -0,0 +1 @@
+ALTER TABLE one_time_tokens ADD COLUMN email varchar(100) null default null;
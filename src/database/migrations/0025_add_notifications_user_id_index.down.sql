@@ -0,0 +1 @@
+ALTER TABLE notifications DROP INDEX notifications_user_id;
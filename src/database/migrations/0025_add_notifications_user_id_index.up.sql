@@ -0,0 +1 @@
+ALTER TABLE notifications ADD INDEX notifications_user_id (user_id, id);
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsAreSequential(t *testing.T) {
+	files, error := fs.Sub(embeddedMigrations, "migrations")
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	migrations, error := LoadMigrations(files)
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	for index, migration := range migrations {
+		if migration.Version != uint64(index+1) {
+			t.Errorf("expected migration %s to have version %d, got %d",
+				migration.Name, index+1, migration.Version)
+		}
+		if migration.Down == "" {
+			t.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestInitialSchemaMatchesFormerDDL(t *testing.T) {
+	script, error := fs.ReadFile(embeddedMigrations, "migrations/0001_initial_schema.up.sql")
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	var tables []string
+	for _, statement := range splitStatements(string(script)) {
+		if strings.HasPrefix(statement, "CREATE TABLE ") {
+			tables = append(tables, strings.Fields(statement)[2])
+		}
+	}
+
+	if expected := []string{"users", "followers", "publications"}; !reflect.DeepEqual(tables, expected) ||
+		!strings.Contains(string(script), "likes int default 0") {
+		t.Fatalf("expected the tables of ddl.sql, %v with a likes counter, got %v", expected, tables)
+	}
+}
+
+func TestLoadMigrationsRejectsInvalidFiles(t *testing.T) {
+	invalid := []fstest.MapFS{
+		{"create_users.up.sql": {Data: []byte("create table users (id int)")}},
+		{"0001_create_users.down.sql": {Data: []byte("drop table users")}},
+		{
+			"0001_create_users.up.sql": {Data: []byte("create table users (id int)")},
+			"0001_create_posts.up.sql": {Data: []byte("create table posts (id int)")},
+		},
+	}
+
+	for _, files := range invalid {
+		if _, error := LoadMigrations(files); error == nil {
+			t.Errorf("expected an error loading %v", files)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- creates tables; with a comment
+CREATE TABLE a (id int, name varchar(10) default 'x;y');
+CREATE TABLE b (id int);
+
+`
+	expected := []string{
+		"CREATE TABLE a (id int, name varchar(10) default 'x;y')",
+		"CREATE TABLE b (id int)",
+	}
+
+	if statements := splitStatements(script); !reflect.DeepEqual(statements, expected) {
+		t.Fatalf("expected %q, got %q", expected, statements)
+	}
+}
+
+func TestCreateMigrationUsesNextVersion(t *testing.T) {
+	directory := t.TempDir()
+
+	if _, error := CreateMigration(directory, "create_users"); error != nil {
+		t.Fatal(error)
+	}
+	paths, error := CreateMigration(directory, "create_posts")
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	expected := []string{
+		filepath.Join(directory, "0002_create_posts.up.sql"),
+		filepath.Join(directory, "0002_create_posts.down.sql"),
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("expected %q, got %q", expected, paths)
+	}
+
+	if _, error = CreateMigration(directory, "bad name"); error == nil {
+		t.Fatal("expected an error for an invalid name")
+	}
+}
+
+func TestMigrationsUpTo(t *testing.T) {
+	migrations := []Migration{{Version: 1, Name: "initial_schema"}, {Version: 2, Name: "create_posts"}}
+
+	baselined, error := migrationsUpTo(migrations, 1)
+	if error != nil {
+		t.Fatal(error)
+	}
+	if !reflect.DeepEqual(baselined, migrations[:1]) {
+		t.Fatalf("expected %v, got %v", migrations[:1], baselined)
+	}
+
+	if _, error = migrationsUpTo(migrations, 3); error == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies what happened in an event
+type Type string
+
+const (
+	// PublicationCreated a user published a publication
+	PublicationCreated Type = "publication.created"
+	// PublicationUpdated a publication was edited by its author or a moderator
+	PublicationUpdated Type = "publication.updated"
+	// PublicationDeleted a publication was deleted by its author or a moderator, the publication
+	// being the event data
+	PublicationDeleted Type = "publication.deleted"
+	// UserFollowed a user started following another user
+	UserFollowed Type = "user.followed"
+	// FollowRequested a user asked to follow a private account
+	FollowRequested Type = "follow.requested"
+	// FollowApproved a private account approved a request to follow it
+	FollowApproved Type = "follow.approved"
+	// PublicationLiked a user liked a publication
+	PublicationLiked Type = "publication.liked"
+	// PublicationReposted a user reposted a publication
+	PublicationReposted Type = "publication.reposted"
+	// PublicationQuoted a user quoted a publication in a new one
+	PublicationQuoted Type = "publication.quoted"
+	// PublicationCommented a user commented on a publication
+	PublicationCommented Type = "publication.commented"
+	// CommentReplied a user replied to a comment
+	CommentReplied Type = "comment.replied"
+	// UserMentioned a user was mentioned in a publication
+	UserMentioned Type = "user.mentioned"
+	// NotificationCreated a user was notified, the notification being the event data
+	NotificationCreated Type = "notification.created"
+	// MessageSent a user sent a direct message to another user, the message being the event data
+	MessageSent Type = "message.sent"
+	// ConversationRead a user read a conversation with another user, the read receipt being the
+	// event data
+	ConversationRead Type = "conversation.read"
+)
+
+// Event represents an action of an actor, possibly concerning a user, a publication or a comment,
+// with data specific to its type
+type Event struct {
+	Type          Type
+	ActorId       uint64
+	UserId        uint64
+	PublicationId uint64
+	CommentId     uint64
+	Data          interface{}
+	CreatedAt     time.Time
+}
+
+// Handler reacts to events
+type Handler func(event Event)
+
+// Publisher announces events to whoever is interested in them
+type Publisher interface {
+	// Publish announces an event
+	Publish(event Event)
+}
+
+// Bus is an in-process publisher, calling subscribed handlers in order of subscription
+type Bus struct {
+	mutex    sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus factory
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler called with every event published from now on
+func (bus *Bus) Subscribe(handler Handler) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.handlers = append(bus.handlers, handler)
+}
+
+// Publish calls the subscribed handlers with an event, timestamping it when needed
+func (bus *Bus) Publish(event Event) {
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	bus.mutex.RLock()
+	handlers := bus.handlers
+	bus.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
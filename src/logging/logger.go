@@ -0,0 +1,220 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level tells how important a log entry is
+type Level int
+
+const (
+	// LevelDebug details useful when investigating an issue
+	LevelDebug Level = iota
+	// LevelInfo normal operation, like served requests
+	LevelInfo
+	// LevelWarn unexpected situations the API recovers from
+	LevelWarn
+	// LevelError failures needing attention
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// String returns the name of a level, as written in log entries
+func (level Level) String() string {
+	return levelNames[level]
+}
+
+// ParseLevel returns the level of a name, like debug or error
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("Unknown log level %s", name)
+}
+
+// Fields are the data of a log entry besides its time, level and message
+type Fields map[string]interface{}
+
+// Logger writes log entries of at least a level as JSON lines, with fields added to every entry
+type Logger struct {
+	mutex  *sync.Mutex
+	output io.Writer
+	level  Level
+	fields Fields
+}
+
+// New factory
+func New(output io.Writer, level Level) *Logger {
+	return &Logger{mutex: &sync.Mutex{}, output: output, level: level}
+}
+
+// With returns a logger adding fields to the entries of logger, writing to the same output
+func (logger *Logger) With(fields Fields) *Logger {
+
+	merged := make(Fields, len(logger.fields)+len(fields))
+	for key, value := range logger.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return &Logger{mutex: logger.mutex, output: logger.output, level: logger.level, fields: merged}
+}
+
+// Debug writes a debug entry
+func (logger *Logger) Debug(message string, fields Fields) {
+	logger.Log(LevelDebug, message, fields)
+}
+
+// Info writes an info entry
+func (logger *Logger) Info(message string, fields Fields) {
+	logger.Log(LevelInfo, message, fields)
+}
+
+// Warn writes a warn entry
+func (logger *Logger) Warn(message string, fields Fields) {
+	logger.Log(LevelWarn, message, fields)
+}
+
+// Error writes an error entry
+func (logger *Logger) Error(message string, fields Fields) {
+	logger.Log(LevelError, message, fields)
+}
+
+// Log writes an entry unless its level is below the level of logger, errors among fields being
+// written as their message
+func (logger *Logger) Log(level Level, message string, fields Fields) {
+
+	if level < logger.level {
+		return
+	}
+
+	var entry bytes.Buffer
+	entry.WriteString(`{"time":`)
+	writeValue(&entry, time.Now().UTC().Format(time.RFC3339Nano))
+	entry.WriteString(`,"level":`)
+	writeValue(&entry, level.String())
+	entry.WriteString(`,"message":`)
+	writeValue(&entry, message)
+
+	merged := logger.fields
+	if len(fields) > 0 {
+		merged = logger.With(fields).fields
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry.WriteString(",")
+		writeValue(&entry, key)
+		entry.WriteString(":")
+		writeValue(&entry, merged[key])
+	}
+	entry.WriteString("}\n")
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	logger.output.Write(entry.Bytes())
+}
+
+// Writer returns a writer logging each write as an entry of a level, for packages logging through
+// the standard log package
+func (logger *Logger) Writer(level Level) io.Writer {
+	return writer{logger, level}
+}
+
+// writer logs writes as entries of a level
+type writer struct {
+	logger *Logger
+	level  Level
+}
+
+// Write logs p as the message of an entry
+func (writer writer) Write(p []byte) (int, error) {
+	writer.logger.Log(writer.level, strings.TrimSuffix(string(p), "\n"), nil)
+	return len(p), nil
+}
+
+// writeValue writes a value as JSON, errors as their message and values that cannot be encoded as
+// their default format
+func writeValue(entry *bytes.Buffer, value interface{}) {
+
+	if error, isError := value.(error); isError {
+		value = error.Error()
+	}
+
+	encoded, error := json.Marshal(value)
+	if error != nil {
+		encoded, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	entry.Write(encoded)
+}
+
+var defaultMutex sync.RWMutex
+var defaultLogger = New(os.Stderr, LevelInfo)
+
+// Default returns the logger used when no other is at hand
+func Default() *Logger {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+
+	return defaultLogger
+}
+
+// SetDefault replaces the logger used when no other is at hand
+func SetDefault(logger *Logger) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+
+	defaultLogger = logger
+}
+
+// Info writes an info entry with the default logger
+func Info(message string, fields Fields) {
+	Default().Info(message, fields)
+}
+
+// Error writes an error entry with the default logger
+func Error(message string, fields Fields) {
+	Default().Error(message, fields)
+}
+
+// contextKey is the key of the logger of a request in its context
+type contextKey struct{}
+
+// NewContext returns a context carrying a logger
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by a context, like one adding the id of a request to its
+// entries, or the default logger
+func FromContext(ctx context.Context) *Logger {
+	if logger, found := ctx.Value(contextKey{}).(*Logger); found {
+		return logger
+	}
+	return Default()
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var output bytes.Buffer
+	logger := New(&output, LevelInfo).With(Fields{"request_id": "abc"})
+
+	logger.Debug("ignored", nil)
+	logger.Info("served", Fields{"status": 200})
+	logger.With(Fields{"request_id": "def"}).Error("failed", Fields{"error": errors.New("Boom")})
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two entries, got %q", output.String())
+	}
+
+	var served, failed map[string]interface{}
+	if error := json.Unmarshal([]byte(lines[0]), &served); error != nil {
+		t.Fatal(error)
+	}
+	if error := json.Unmarshal([]byte(lines[1]), &failed); error != nil {
+		t.Fatal(error)
+	}
+
+	if served["level"] != "info" || served["message"] != "served" || served["status"] != float64(200) ||
+		served["request_id"] != "abc" || served["time"] == nil {
+		t.Fatalf("unexpected entry: %s", lines[0])
+	}
+	if failed["level"] != "error" || failed["error"] != "Boom" || failed["request_id"] != "def" {
+		t.Fatalf("unexpected entry: %s", lines[1])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, error := ParseLevel("WARN"); error != nil || level != LevelWarn {
+		t.Fatalf("expected the warn level, got %v %v", level, error)
+	}
+	if _, error := ParseLevel("verbose"); error == nil {
+		t.Fatal("expected an unknown level to be rejected")
+	}
+}
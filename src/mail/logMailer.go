@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogMailer writes messages to a writer instead of sending them, for local development
+type LogMailer struct {
+	mutex  *sync.Mutex
+	writer io.Writer
+}
+
+// Send writes a message
+func (mailer LogMailer) Send(message Message) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+
+	_, error := fmt.Fprintf(mailer.writer, "----- %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), message.To, message.Subject, message.Body)
+
+	return error
+}
+
+// NewLogMailer factory
+func NewLogMailer(writer io.Writer) *LogMailer {
+	return &LogMailer{&sync.Mutex{}, writer}
+}
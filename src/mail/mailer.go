@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"devbook/src/config"
+	"fmt"
+	"os"
+)
+
+// Message represents an email message
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email messages
+type Mailer interface {
+	Send(message Message) error
+}
+
+// NewMailer returns the mailer selected by configuration
+func NewMailer() (Mailer, error) {
+
+	switch config.MailDriver {
+	case "smtp":
+		return NewSMTPMailer(config.SMTPHost, config.SMTPPort,
+			config.SMTPUsername, config.SMTPPassword, config.MailFrom), nil
+	case "log", "":
+		if config.MailLogFile == "" {
+			return NewLogMailer(os.Stdout), nil
+		}
+		file, error := os.OpenFile(config.MailLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if error != nil {
+			return nil, error
+		}
+		return NewLogMailer(file), nil
+	default:
+		return nil, fmt.Errorf("Unknown mail driver %s", config.MailDriver)
+	}
+}
@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPMailer sends messages through an SMTP server
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// Send sends a message
+func (mailer SMTPMailer) Send(message Message) error {
+
+	var auth smtp.Auth
+	if mailer.username != "" {
+		auth = smtp.PlainAuth("", mailer.username, mailer.password, mailer.host)
+	}
+
+	content := strings.Join([]string{
+		"From: " + mailer.from,
+		"To: " + message.To,
+		"Subject: " + message.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		message.Body,
+	}, "\r\n")
+
+	return smtp.SendMail(fmt.Sprintf("%s:%d", mailer.host, mailer.port),
+		auth, mailer.from, []string{message.To}, []byte(content))
+}
+
+// NewSMTPMailer factory
+func NewSMTPMailer(host string, port int, username string, password string, from string) *SMTPMailer {
+	return &SMTPMailer{host, port, username, password, from}
+}
@@ -0,0 +1,138 @@
+package messaging
+
+import (
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/models"
+	"sync"
+)
+
+const (
+	// FrameMessage a message sent in a conversation of the user
+	FrameMessage = "message"
+	// FrameRead a read receipt from a participant of a conversation, or a request of the user to mark
+	// a conversation read
+	FrameRead = "read"
+	// FrameTyping a participant of a conversation typing, or the user telling it is typing
+	FrameTyping = "typing"
+	// FrameError a frame of the user that could not be handled
+	FrameError = "error"
+)
+
+// Frame represents a message exchanged with users over WebSocket connections, in both directions
+type Frame struct {
+	Type           string      `json:"type"`
+	ConversationId uint64      `json:"conversationId,omitempty"`
+	MessageId      uint64      `json:"messageId,omitempty"`
+	Data           interface{} `json:"data,omitempty"`
+}
+
+// Client receives the frames pushed to a connection of a user
+type Client struct {
+	UserId uint64
+	Frames <-chan Frame
+	frames chan Frame
+}
+
+// Hub hands frames to the connections of users
+type Hub struct {
+	mutex   sync.Mutex
+	clients map[uint64]map[*Client]bool
+}
+
+// NewHub factory
+func NewHub() *Hub {
+	return &Hub{clients: make(map[uint64]map[*Client]bool)}
+}
+
+// Subscribe makes the hub deliver the messages and read receipts published on bus
+func (hub *Hub) Subscribe(bus *events.Bus) {
+	bus.Subscribe(hub.Handle)
+}
+
+// Handle delivers messages to both users of their conversation and read receipts to the
+// participant of the user who read
+func (hub *Hub) Handle(event events.Event) {
+
+	switch data := event.Data.(type) {
+	case models.Message:
+		if event.Type == events.MessageSent {
+			frame := Frame{Type: FrameMessage, ConversationId: data.ConversationId, MessageId: data.ID, Data: data}
+			hub.Send(event.UserId, frame)
+			hub.Send(event.ActorId, frame)
+		}
+	case models.ReadReceipt:
+		if event.Type == events.ConversationRead {
+			hub.Send(event.UserId, Frame{Type: FrameRead, ConversationId: data.ConversationId,
+				MessageId: data.MessageId, Data: data})
+		}
+	}
+}
+
+// Connect registers a connection of a user
+func (hub *Hub) Connect(userId uint64) *Client {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	frames := make(chan Frame, config.StreamBufferSize)
+	client := &Client{UserId: userId, Frames: frames, frames: frames}
+
+	if hub.clients[userId] == nil {
+		hub.clients[userId] = make(map[*Client]bool)
+	}
+	hub.clients[userId][client] = true
+
+	return client
+}
+
+// Disconnect removes a connection, closing its frames
+func (hub *Hub) Disconnect(client *Client) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.drop(client)
+}
+
+// Send queues a frame to every connection of a user
+func (hub *Hub) Send(userId uint64, frame Frame) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	for client := range hub.clients[userId] {
+		hub.queue(client, frame)
+	}
+}
+
+// Reply queues a frame to a single connection
+func (hub *Hub) Reply(client *Client, frame Frame) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.clients[client.UserId][client] {
+		hub.queue(client, frame)
+	}
+}
+
+// queue hands a frame to a connection, dropping the connection when its queue is full
+func (hub *Hub) queue(client *Client, frame Frame) {
+	select {
+	case client.frames <- frame:
+	default:
+		hub.drop(client)
+	}
+}
+
+// drop removes a connection, once
+func (hub *Hub) drop(client *Client) {
+
+	clients := hub.clients[client.UserId]
+	if !clients[client] {
+		return
+	}
+
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(hub.clients, client.UserId)
+	}
+	close(client.frames)
+}
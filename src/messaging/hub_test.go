@@ -0,0 +1,64 @@
+package messaging
+
+import (
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/models"
+	"testing"
+)
+
+func TestHubDeliversMessagesAndReadReceipts(t *testing.T) {
+	config.StreamBufferSize = 10
+	hub := NewHub()
+
+	ada := hub.Connect(1)
+	grace := hub.Connect(2)
+	graceElsewhere := hub.Connect(2)
+	linus := hub.Connect(3)
+
+	hub.Handle(events.Event{Type: events.MessageSent, ActorId: 1, UserId: 2,
+		Data: models.Message{ID: 7, ConversationId: 5, SenderId: 1}})
+	hub.Handle(events.Event{Type: events.ConversationRead, ActorId: 2, UserId: 1,
+		Data: models.ReadReceipt{ConversationId: 5, UserId: 2, MessageId: 7}})
+	hub.Handle(events.Event{Type: events.PublicationCreated, ActorId: 1})
+
+	if len(ada.Frames) != 2 || len(grace.Frames) != 1 || len(graceElsewhere.Frames) != 1 || len(linus.Frames) != 0 {
+		t.Fatalf("unexpected frames queued: %d, %d, %d and %d",
+			len(ada.Frames), len(grace.Frames), len(graceElsewhere.Frames), len(linus.Frames))
+	}
+
+	if frame := <-ada.Frames; frame.Type != FrameMessage || frame.ConversationId != 5 || frame.MessageId != 7 {
+		t.Fatalf("unexpected message frame: %+v", frame)
+	}
+	if frame := <-ada.Frames; frame.Type != FrameRead || frame.MessageId != 7 {
+		t.Fatalf("unexpected read frame: %+v", frame)
+	}
+}
+
+func TestHubDropsSlowAndDisconnectedClients(t *testing.T) {
+	config.StreamBufferSize = 1
+	hub := NewHub()
+
+	slow := hub.Connect(1)
+	hub.Send(1, Frame{Type: FrameTyping})
+	hub.Send(1, Frame{Type: FrameTyping})
+
+	if _, open := <-slow.Frames; !open {
+		t.Fatal("expected the queued frame before the client closes")
+	}
+	if _, open := <-slow.Frames; open {
+		t.Fatal("expected a full client to be dropped")
+	}
+
+	gone := hub.Connect(2)
+	hub.Disconnect(gone)
+	hub.Disconnect(gone)
+	hub.Reply(gone, Frame{Type: FrameError})
+
+	if _, open := <-gone.Frames; open {
+		t.Fatal("expected a disconnected client to be closed")
+	}
+	if len(hub.clients) != 0 {
+		t.Fatalf("expected no clients left, got %+v", hub.clients)
+	}
+}
@@ -1,28 +1,147 @@
 package middlewares
 
 import (
+	"crypto/rand"
+	"devbook/src/logging"
+	"devbook/src/persistence"
 	"devbook/src/responses"
 	"devbook/src/security"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"time"
 )
 
+// RequestIDHeader header identifying a request, echoed in its response
+const RequestIDHeader = "X-Request-ID"
 
-// LogRequest logs ai requests
-func LogRequest (next http.HandlerFunc) http.HandlerFunc {
+// requestIdPattern matches request ids accepted from clients
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID identifies a request by the X-Request-ID header of the client, or a new id when missing
+// or malformed, echoing it in the response and adding it to the entries of the request logger
+func RequestID(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("\n %s %s %s ", r.Method, r.RequestURI, r.Host)
-		next(w, r)
+		requestId := r.Header.Get(RequestIDHeader)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = newRequestId()
+		}
+
+		w.Header().Set(RequestIDHeader, requestId)
+		logger := logging.Default().With(logging.Fields{"request_id": requestId})
+		next(w, r.WithContext(logging.NewContext(r.Context(), logger)))
+	}
+}
+
+// LogRequest writes an access log entry of each request once served, with its response status and
+// size, its duration and the authenticated user, if any
+func LogRequest(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next(recorder, r)
+
+		fields := logging.Fields{
+			"method":      r.Method,
+			"uri":         redactedURI(r),
+			"remote_addr": r.RemoteAddr,
+			"status":      recorder.status,
+			"bytes":       recorder.bytes,
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if userId, error := security.ExtractUserId(r); error == nil {
+			fields["user_id"] = userId
+		}
+
+		level := logging.LevelInfo
+		if recorder.status >= http.StatusInternalServerError {
+			level = logging.LevelError
+		}
+		logging.FromContext(r.Context()).Log(level, "request", fields)
+	}
+}
+
+// newRequestId returns a random request id
+func newRequestId() string {
+	id := make([]byte, 16)
+	if _, error := rand.Read(id); error != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+// secretParameters are query parameters carrying secrets, like the access token of WebSocket
+// handshakes and email verification tokens
+var secretParameters = []string{"access_token", "token"}
+
+// redactedURI returns the URI of a request with the values of secret query parameters hidden
+func redactedURI(r *http.Request) string {
+	query := r.URL.Query()
+
+	redacted := false
+	for _, parameter := range secretParameters {
+		if query.Get(parameter) != "" {
+			query.Set(parameter, "redacted")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return r.RequestURI
+	}
+
+	url := *r.URL
+	url.RawQuery = query.Encode()
+	return url.RequestURI()
+}
+
+// CheckAuthenticatedRequest check if a user token is valid for authenticated request and its user
+// is still registered and not suspended, passing the user's current role on to next
+func CheckAuthenticatedRequest(users persistence.UserStore, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, error := security.ExtractUserId(r)
+		if error != nil {
+			responses.ErrorResponse(w, http.StatusUnauthorized, error)
+			return
+		}
+
+		user, error := users.GetUserById(userId)
+		if error != nil {
+			responses.ErrorResponse(w, http.StatusInternalServerError, error)
+			return
+		}
+		if user.ID == 0 {
+			responses.ErrorResponse(w, http.StatusUnauthorized, errors.New("Invalid Token"))
+			return
+		}
+		if user.IsSuspended() {
+			responses.ErrorResponse(w, http.StatusForbidden, errors.New("Account suspended"))
+			return
+		}
+
+		role := user.Role
+		if role == "" {
+			role = security.RoleUser
+		}
+		next(w, security.WithRole(r, role))
 	}
 }
 
-// CheckAuthenticatedRequest check if a user token is valid for authenticated request
-func CheckAuthenticatedRequest(next http.HandlerFunc) http.HandlerFunc {
+// CheckRequiredRole check if the authenticated user's role grants the required role
+func CheckRequiredRole(required security.Role, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if error := security.ValidateToken(r); error != nil{
+		role, error := security.ExtractRole(r)
+		if error != nil {
 			responses.ErrorResponse(w, http.StatusUnauthorized, error)
 			return
 		}
+		if !role.Includes(required) {
+			responses.ErrorResponse(w, http.StatusForbidden,
+				fmt.Errorf("Requires %s role", required))
+			return
+		}
 		next(w, r)
 	}
-}
\ No newline at end of file
+}
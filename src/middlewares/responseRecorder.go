@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// responseRecorder records the status and size of a response, keeping the streaming and WebSocket
+// upgrades of the wrapped writer working
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+// WriteHeader records the status of the response
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the size of the response
+func (recorder *responseRecorder) Write(p []byte) (int, error) {
+	recorder.wroteHeader = true
+	written, error := recorder.ResponseWriter.Write(p)
+	recorder.bytes += written
+	return written, error
+}
+
+// Flush sends buffered data of event streams to the client
+func (recorder *responseRecorder) Flush() {
+	if flusher, ok := recorder.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack takes over the connection of WebSocket upgrades, recorded as switching protocols
+func (recorder *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := recorder.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("Connection cannot be hijacked")
+	}
+
+	connection, readWriter, error := hijacker.Hijack()
+	if error == nil {
+		recorder.status = http.StatusSwitchingProtocols
+		recorder.wroteHeader = true
+	}
+	return connection, readWriter, error
+}
@@ -1,6 +1,12 @@
 package models
 
 type Token struct {
-	UserId string `json:"userId"`
-	Token  string `json:"token"`
+	UserId       string `json:"userId"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+// RefreshTokenRequest represents a request carrying a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken,omitempty"`
 }
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Comment represents a user comment on a publication
+type Comment struct {
+	ID            uint64     `json:"id,omitempty"`
+	PublicationId uint64     `json:"publicationId,omitempty"`
+	AuthorId      uint64     `json:"authorId,omitempty"`
+	ParentId      uint64     `json:"parentId,omitempty"`
+	AuthorNick    string     `json:"authorNick,omitempty"`
+	Content       string     `json:"content,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt,omitempty"`
+	UpdatedAt     *time.Time `json:"updatedAt,omitempty"`
+}
+
+// CommentThread represents a comment with a page of its replies, each a thread itself, and the
+// cursor of the next page of its replies when there are more
+type CommentThread struct {
+	Comment
+	ReplyCount int             `json:"replyCount"`
+	Replies    []CommentThread `json:"replies,omitempty"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+// Prepare validates and formats a comment
+func (comment *Comment) Prepare() error {
+
+	if error := comment.validate(); error != nil {
+		return error
+	}
+	comment.format()
+	return nil
+}
+
+func (comment *Comment) validate() error {
+
+	if strings.TrimSpace(comment.Content) == "" {
+		return errors.New("Invalid content")
+	}
+
+	if utf8.RuneCountInString(comment.Content) > 500 {
+		return errors.New("Content exceeds 500 characters")
+	}
+
+	if comment.PublicationId == 0 {
+		return errors.New("Invalid publication")
+	}
+
+	if comment.AuthorId == 0 {
+		return errors.New("Invalid author")
+	}
+
+	return nil
+}
+
+func (comment *Comment) format() {
+	comment.Content = strings.TrimSpace(comment.Content)
+}
+
+// BuildCommentThread arranges pages of replies into the thread of root, the replies of each comment
+// being read one past limit to tell whether there are more, and counts giving how many replies
+// each comment has
+func BuildCommentThread(root Comment, replies []Comment, counts map[uint64]int, limit int) CommentThread {
+
+	children := make(map[uint64][]Comment)
+	for _, reply := range replies {
+		children[reply.ParentId] = append(children[reply.ParentId], reply)
+	}
+
+	for _, page := range children {
+		sort.Slice(page, func(i, j int) bool {
+			return page[i].ID < page[j].ID
+		})
+	}
+
+	return buildCommentThread(root, children, counts, limit)
+}
+
+func buildCommentThread(comment Comment, children map[uint64][]Comment, counts map[uint64]int, limit int) CommentThread {
+
+	thread := CommentThread{Comment: comment, ReplyCount: counts[comment.ID]}
+
+	page := children[comment.ID]
+	if len(page) > limit {
+		page = page[:limit]
+		thread.NextCursor = Cursor{ID: page[limit-1].ID}.Encode()
+	}
+
+	for _, reply := range page {
+		thread.Replies = append(thread.Replies, buildCommentThread(reply, children, counts, limit))
+	}
+
+	return thread
+}
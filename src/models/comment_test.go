@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommentPrepare(t *testing.T) {
+	comment := Comment{PublicationId: 1, AuthorId: 1, Content: strings.Repeat("評", 500)}
+	if error := comment.Prepare(); error != nil {
+		t.Fatalf("expected 500 characters to be accepted: %v", error)
+	}
+
+	comment.Content = " Nice "
+	if error := comment.Prepare(); error != nil || comment.Content != "Nice" {
+		t.Fatalf("expected the content trimmed: %q %v", comment.Content, error)
+	}
+
+	for _, invalid := range []Comment{
+		{PublicationId: 1, AuthorId: 1, Content: "  "},
+		{PublicationId: 1, AuthorId: 1, Content: strings.Repeat("😀", 501)},
+		{AuthorId: 1, Content: "Nice"},
+		{PublicationId: 1, Content: "Nice"},
+	} {
+		if error := invalid.Prepare(); error == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestBuildCommentThread(t *testing.T) {
+	root := Comment{ID: 1}
+	replies := []Comment{{ID: 4, ParentId: 1}, {ID: 2, ParentId: 1}, {ID: 3, ParentId: 1}, {ID: 5, ParentId: 2}}
+
+	thread := BuildCommentThread(root, replies, map[uint64]int{1: 5, 2: 1}, 2)
+	if thread.ReplyCount != 5 || len(thread.Replies) != 2 || thread.Replies[0].ID != 2 || thread.Replies[1].ID != 3 {
+		t.Fatalf("unexpected thread: %+v", thread)
+	}
+
+	cursor, error := DecodeCursor(thread.NextCursor)
+	if error != nil || cursor.ID != 3 {
+		t.Fatalf("expected the next page after reply 3, got %+v %v", cursor, error)
+	}
+
+	if nested := thread.Replies[0]; nested.ReplyCount != 1 || len(nested.Replies) != 1 || nested.NextCursor != "" {
+		t.Fatalf("unexpected nested thread: %+v", nested)
+	}
+}
@@ -0,0 +1,6 @@
+package models
+
+// VerificationResend represents a request for a new email verification token
+type VerificationResend struct {
+	Email string `json:"email,omitempty"`
+}
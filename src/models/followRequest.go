@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// FollowRequest represents a pending request of a user to follow a private account
+type FollowRequest struct {
+	UserId    uint64    `json:"userId"`
+	Follower  User      `json:"follower"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Cursor returns the position of a follow request in listings, ordered by request time
+func (request FollowRequest) Cursor() Cursor {
+	return Cursor{Time: request.CreatedAt, ID: request.Follower.ID}
+}
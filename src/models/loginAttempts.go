@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// LoginAttempts represents the failed and ongoing logins counted under a key, like an account or an
+// address
+type LoginAttempts struct {
+	Key           string    `json:"key,omitempty"`
+	Failures      int       `json:"failures,omitempty"`
+	LastFailureAt time.Time `json:"lastFailureAt,omitempty"`
+}
+
+// BlockedUntil tells until when new attempts are refused: the wait starts at backoff and doubles
+// with each failure, up to lockout, which is also applied once failures reach maxFailures
+func (attempts LoginAttempts) BlockedUntil(maxFailures int, backoff time.Duration, lockout time.Duration) time.Time {
+
+	if attempts.Failures == 0 {
+		return time.Time{}
+	}
+
+	if attempts.Failures >= maxFailures {
+		return attempts.LastFailureAt.Add(lockout)
+	}
+
+	wait := backoff
+	for failure := 1; failure < attempts.Failures && wait < lockout; failure++ {
+		wait *= 2
+	}
+	if wait > lockout {
+		wait = lockout
+	}
+
+	return attempts.LastFailureAt.Add(wait)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginAttemptsBlockedUntil(t *testing.T) {
+	lastFailureAt := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		failures int
+		wait     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, time.Minute},
+	}
+
+	for _, test := range tests {
+		attempts := LoginAttempts{Failures: test.failures, LastFailureAt: lastFailureAt}
+		blockedUntil := attempts.BlockedUntil(5, time.Second, time.Minute)
+
+		expected := time.Time{}
+		if test.failures > 0 {
+			expected = lastFailureAt.Add(test.wait)
+		}
+		if !blockedUntil.Equal(expected) {
+			t.Errorf("%d failures: expected blocked until %v, got %v", test.failures, expected, blockedUntil)
+		}
+	}
+
+	attempts := LoginAttempts{Failures: 4, LastFailureAt: lastFailureAt}
+	if blockedUntil := attempts.BlockedUntil(10, time.Second, 5*time.Second); !blockedUntil.Equal(lastFailureAt.Add(5 * time.Second)) {
+		t.Errorf("expected backoff capped at lockout, got %v", blockedUntil)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// mentionPattern matches mentions not preceded by a word character, capturing the nick
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@/])@([\p{L}\p{N}_.-]+)`)
+
+// Mention represents a reference to a user in a publication's content, at an offset and with a
+// length counted in characters, the leading @ included
+type Mention struct {
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	UserId uint64 `json:"userId"`
+	Nick   string `json:"-"`
+}
+
+// ParseMentions returns the mentions of content in order of appearance, with the nick they refer
+// to and no user id, ignoring dots and hyphens ending a nick
+func ParseMentions(content string) []Mention {
+
+	var mentions []Mention
+
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		nick := strings.TrimRight(content[match[2]:match[3]], ".-")
+		if nick == "" {
+			continue
+		}
+
+		start := match[2] - 1
+		mentions = append(mentions, Mention{
+			Offset: utf8.RuneCountInString(content[:start]),
+			Length: utf8.RuneCountInString(nick) + 1,
+			Nick:   nick,
+		})
+	}
+
+	return mentions
+}
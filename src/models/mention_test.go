@@ -0,0 +1,25 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []Mention
+	}{
+		{"No mentions", nil},
+		{"@ada and @grace.", []Mention{{0, 4, 0, "ada"}, {9, 6, 0, "grace"}}},
+		{"Olá @josé_silva-", []Mention{{4, 11, 0, "josé_silva"}}},
+		{"mail ada@devbook.com or @@grace, @.", nil},
+		{"(@linus.torvalds)", []Mention{{1, 15, 0, "linus.torvalds"}}},
+	}
+
+	for _, test := range tests {
+		if mentions := ParseMentions(test.content); fmt.Sprint(mentions) != fmt.Sprint(test.expected) {
+			t.Errorf("ParseMentions(%q) = %v, expected %v", test.content, mentions, test.expected)
+		}
+	}
+}
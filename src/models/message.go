@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxMessageLength characters of a direct message at most
+const MaxMessageLength = 1000
+
+// Conversation represents the direct messages between a user and a participant, as seen by the user
+type Conversation struct {
+	ID                           uint64    `json:"id"`
+	Participant                  User      `json:"participant"`
+	LastMessage                  *Message  `json:"lastMessage,omitempty"`
+	UnreadCount                  uint64    `json:"unreadCount"`
+	LastReadMessageId            uint64    `json:"lastReadMessageId"`
+	ParticipantLastReadMessageId uint64    `json:"participantLastReadMessageId"`
+	UpdatedAt                    time.Time `json:"updatedAt"`
+	CreatedAt                    time.Time `json:"createdAt"`
+}
+
+// ConversationRequest represents data for starting a conversation with a user
+type ConversationRequest struct {
+	UserId uint64 `json:"userId"`
+}
+
+// Message represents a direct message sent in a conversation
+type Message struct {
+	ID             uint64    `json:"id,omitempty"`
+	ConversationId uint64    `json:"conversationId,omitempty"`
+	SenderId       uint64    `json:"senderId,omitempty"`
+	Content        string    `json:"content,omitempty"`
+	CreatedAt      time.Time `json:"createdAt,omitempty"`
+}
+
+// ReadReceipt represents a user having read a conversation up to a message
+type ReadReceipt struct {
+	ConversationId uint64    `json:"conversationId"`
+	UserId         uint64    `json:"userId"`
+	MessageId      uint64    `json:"messageId"`
+	ReadAt         time.Time `json:"readAt"`
+}
+
+// ReadRequest represents data for marking a conversation read up to a message, the last one when
+// not given
+type ReadRequest struct {
+	MessageId uint64 `json:"messageId,omitempty"`
+}
+
+// TypingIndicator represents a user typing in a conversation
+type TypingIndicator struct {
+	ConversationId uint64 `json:"conversationId"`
+	UserId         uint64 `json:"userId"`
+}
+
+// Cursor returns the position of a conversation in listings, most recently active first
+func (conversation Conversation) Cursor() Cursor {
+	return Cursor{Time: conversation.UpdatedAt, ID: conversation.ID}
+}
+
+// Prepare validates and formats a message
+func (message *Message) Prepare() error {
+
+	message.Content = strings.TrimSpace(message.Content)
+
+	if message.Content == "" {
+		return errors.New("Invalid content")
+	}
+
+	if len([]rune(message.Content)) > MaxMessageLength {
+		return errors.New("Content exceeds 1000 characters")
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// MFA represents a user's TOTP second factor, enabled once a first code is confirmed
+type MFA struct {
+	UserId       uint64     `json:"userId,omitempty"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabledAt,omitempty"`
+	LastUsedStep uint64     `json:"-"`
+	CreatedAt    time.Time  `json:"createdAt,omitempty"`
+}
+
+// IsEnabled tells if the second factor is required on login
+func (mfa MFA) IsEnabled() bool {
+	return mfa.UserId != 0 && mfa.EnabledAt != nil
+}
+
+// MFAEnrollment represents a TOTP secret to be added to an authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFACode represents a TOTP or recovery code
+type MFACode struct {
+	Code string `json:"code,omitempty"`
+}
+
+// MFARecoveryCodes represents one time codes replacing the authenticator app when it is lost
+type MFARecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// MFAChallenge represents a login awaiting a second factor code
+type MFAChallenge struct {
+	UserId      string `json:"userId"`
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+}
+
+// MFALogin represents the second step of a login, exchanging a challenge and a code for tokens
+type MFALogin struct {
+	MFAToken string `json:"mfaToken,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxNotificationGroupActors actors listed in a notification group at most, the most recent ones
+const MaxNotificationGroupActors = 3
+
+// NotificationType identifies what a notification tells its user
+type NotificationType string
+
+const (
+	// NotificationFollow someone followed the user
+	NotificationFollow NotificationType = "follow"
+	// NotificationFollowRequest someone asked to follow the user's private account
+	NotificationFollowRequest NotificationType = "follow_request"
+	// NotificationFollowApproved someone approved the user's request to follow them
+	NotificationFollowApproved NotificationType = "follow_approved"
+	// NotificationLike someone liked a publication of the user
+	NotificationLike NotificationType = "like"
+	// NotificationRepost someone reposted a publication of the user
+	NotificationRepost NotificationType = "repost"
+	// NotificationQuote someone quoted a publication of the user
+	NotificationQuote NotificationType = "quote"
+	// NotificationComment someone commented on a publication of the user
+	NotificationComment NotificationType = "comment"
+	// NotificationReply someone replied to a comment of the user
+	NotificationReply NotificationType = "reply"
+	// NotificationMention someone mentioned the user in a publication
+	NotificationMention NotificationType = "mention"
+)
+
+// notificationActions describes what the actors of each type of notification did
+var notificationActions = map[NotificationType]string{
+	NotificationFollow:         "followed you",
+	NotificationFollowRequest:  "asked to follow you",
+	NotificationFollowApproved: "accepted your follow request",
+	NotificationLike:           "liked your publication",
+	NotificationRepost:         "reposted your publication",
+	NotificationQuote:          "quoted your publication",
+	NotificationComment:        "commented on your publication",
+	NotificationReply:          "replied to your comment",
+	NotificationMention:        "mentioned you in a publication",
+}
+
+// Notification represents something an actor did that concerns a user, possibly on a publication
+// or a comment
+type Notification struct {
+	ID            uint64           `json:"id,omitempty"`
+	UserId        uint64           `json:"userId,omitempty"`
+	Type          NotificationType `json:"type,omitempty"`
+	ActorId       uint64           `json:"actorId,omitempty"`
+	PublicationId uint64           `json:"publicationId,omitempty"`
+	CommentId     uint64           `json:"commentId,omitempty"`
+	ReadAt        *time.Time       `json:"readAt,omitempty"`
+	CreatedAt     time.Time        `json:"createdAt,omitempty"`
+}
+
+// NotificationGroup represents the notifications of a user of the same type on the same
+// publication, identified by the most recent of them
+type NotificationGroup struct {
+	ID            uint64           `json:"id"`
+	Type          NotificationType `json:"type"`
+	PublicationId uint64           `json:"publicationId,omitempty"`
+	Actors        []User           `json:"actors"`
+	ActorCount    uint64           `json:"actorCount"`
+	UnreadCount   uint64           `json:"unreadCount"`
+	Summary       string           `json:"summary"`
+	UpdatedAt     time.Time        `json:"updatedAt"`
+}
+
+// NotificationPage represents a page of notification groups along with the user's unread
+// notifications count
+type NotificationPage struct {
+	Page
+	UnreadCount uint64 `json:"unreadCount"`
+}
+
+// AggregationKey identifies what a notification tells, the same for notifications of the same actor
+// doing the same thing to the same user, which are registered once
+func (notification Notification) AggregationKey() string {
+	return fmt.Sprintf("%d:%s:%d:%d:%d", notification.UserId, notification.Type, notification.ActorId,
+		notification.PublicationId, notification.CommentId)
+}
+
+// Cursor returns the position of a notification group in listings, most recent first
+func (group NotificationGroup) Cursor() Cursor {
+	return Cursor{ID: group.ID}
+}
+
+// Summarize describes the group in a sentence naming its most recent actors, like
+// "ada and 4 others liked your publication"
+func (group *NotificationGroup) Summarize() {
+
+	action := notificationActions[group.Type]
+
+	switch {
+	case len(group.Actors) == 0:
+		group.Summary = fmt.Sprintf("Someone %s", action)
+	case group.ActorCount <= 1:
+		group.Summary = fmt.Sprintf("%s %s", group.Actors[0].Nick, action)
+	case group.ActorCount == 2 && len(group.Actors) > 1:
+		group.Summary = fmt.Sprintf("%s and %s %s", group.Actors[0].Nick, group.Actors[1].Nick, action)
+	default:
+		group.Summary = fmt.Sprintf("%s and %d others %s", group.Actors[0].Nick, group.ActorCount-1, action)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestNotificationGroupSummarize(t *testing.T) {
+	ada := User{ID: 1, Nick: "ada"}
+	grace := User{ID: 2, Nick: "grace"}
+
+	cases := []struct {
+		group    NotificationGroup
+		expected string
+	}{
+		{NotificationGroup{Type: NotificationFollow, Actors: []User{ada}, ActorCount: 1}, "ada followed you"},
+		{NotificationGroup{Type: NotificationLike, Actors: []User{ada, grace}, ActorCount: 2},
+			"ada and grace liked your publication"},
+		{NotificationGroup{Type: NotificationLike, Actors: []User{ada, grace}, ActorCount: 5},
+			"ada and 4 others liked your publication"},
+		{NotificationGroup{Type: NotificationMention}, "Someone mentioned you in a publication"},
+	}
+
+	for _, c := range cases {
+		c.group.Summarize()
+		if c.group.Summary != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, c.group.Summary)
+		}
+	}
+}
+
+func TestNotificationAggregationKey(t *testing.T) {
+	like := Notification{UserId: 1, Type: NotificationLike, ActorId: 2, PublicationId: 3}
+	if key := like.AggregationKey(); key != "1:like:2:3:0" {
+		t.Fatalf("unexpected aggregation key %q", key)
+	}
+
+	again := like
+	again.ID = 9
+	other := like
+	other.ActorId = 4
+	if again.AggregationKey() != like.AggregationKey() || other.AggregationKey() == like.AggregationKey() {
+		t.Fatal("expected notifications aggregated by user, type, actor, publication and comment")
+	}
+}
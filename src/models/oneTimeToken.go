@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	// TokenPurposePasswordReset purpose of tokens allowing a password reset
+	TokenPurposePasswordReset = "password_reset"
+	// TokenPurposeEmailVerification purpose of tokens proving ownership of an email address
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// OneTimeToken represents a stored, single use and expiring token sent to a user for a purpose, at
+// the email address the user had then
+type OneTimeToken struct {
+	ID        uint64     `json:"id,omitempty"`
+	UserId    uint64     `json:"userId,omitempty"`
+	Purpose   string     `json:"purpose,omitempty"`
+	Email     string     `json:"-"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt,omitempty"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt,omitempty"`
+}
+
+// IsUsable tells if the token was neither used nor expired at the given time
+func (token OneTimeToken) IsUsable(now time.Time) bool {
+	return token.UsedAt == nil && now.Before(token.ExpiresAt)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// Cursor represents the position of the last item of a page in the order of a listing, clients
+// handling it as an opaque string
+type Cursor struct {
+	Score float64   `json:"r,omitempty"`
+	Time  time.Time `json:"t,omitempty"`
+	ID    uint64    `json:"i,omitempty"`
+	Tie   uint64    `json:"s,omitempty"`
+}
+
+// PageRequest represents the page of a listing following the item at After, the first page when
+// After is nil. Stores read one item past Limit when there are more, so callers can tell whether
+// a next page exists
+type PageRequest struct {
+	Limit int
+	After *Cursor
+}
+
+// Page represents a page of a listing, with the cursor of the next page when there are more items
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+// NewPage builds a page of items, followed by the page at next when it is not nil
+func NewPage(items interface{}, next *Cursor) Page {
+
+	if value := reflect.ValueOf(items); value.Kind() == reflect.Slice && value.IsNil() {
+		items = []interface{}{}
+	}
+
+	page := Page{Items: items}
+	if next != nil {
+		page.NextCursor = next.Encode()
+	}
+
+	return page
+}
+
+// Encode returns the cursor in the opaque form handed to clients
+func (cursor Cursor) Encode() string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeCursor reads a cursor handed to clients by Encode
+func DecodeCursor(value string) (Cursor, error) {
+
+	var cursor Cursor
+
+	decoded, error := base64.RawURLEncoding.DecodeString(value)
+	if error != nil {
+		return cursor, errors.New("Invalid cursor")
+	}
+
+	if error = json.Unmarshal(decoded, &cursor); error != nil {
+		return cursor, errors.New("Invalid cursor")
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,12 @@
+package models
+
+// PasswordForgot represents a request for a password reset token
+type PasswordForgot struct {
+	Email string `json:"email,omitempty"`
+}
+
+// PasswordReset represents data for resetting a password with a reset token
+type PasswordReset struct {
+	Token       string `json:"token,omitempty"`
+	NewPassword string `json:"new,omitempty"`
+}
@@ -6,15 +6,62 @@ import (
 	"time"
 )
 
+// Visibility tells who can see a publication
+type Visibility string
+
+const (
+	// VisibilityPublic publication seen by any user
+	VisibilityPublic Visibility = "public"
+	// VisibilityFollowers publication seen by its author and the author's followers
+	VisibilityFollowers Visibility = "followers"
+	// VisibilityPrivate publication seen by its author, only
+	VisibilityPrivate Visibility = "private"
+)
+
+// IsValid tells if visibility is a known visibility level
+func (visibility Visibility) IsValid() bool {
+	return visibility == VisibilityPublic || visibility == VisibilityFollowers || visibility == VisibilityPrivate
+}
+
 // Publication represents a user publication
 type Publication struct {
-	ID         uint64    `json:"id,omitempty"`
-	Title      string    `json:"title,omitempty"`
-	Content    string    `json:"content,omitempty"`
-	AuthorId   uint64    `json:"authorId,omitempty"`
-	AuthorNick string    `json:"authorNick,omitempty"`
-	Likes      uint64    `json:"likes"`
-	CreatedAt  time.Time `json:"createdAt,omitempty"`
+	ID                  uint64       `json:"id,omitempty"`
+	Title               string       `json:"title,omitempty"`
+	Content             string       `json:"content,omitempty"`
+	AuthorId            uint64       `json:"authorId,omitempty"`
+	AuthorNick          string       `json:"authorNick,omitempty"`
+	Visibility          Visibility   `json:"visibility,omitempty"`
+	Tags                []string     `json:"tags,omitempty"`
+	Mentions            []Mention    `json:"mentions,omitempty"`
+	QuotedPublicationId uint64       `json:"quotedPublicationId,omitempty"`
+	Quoted              *Publication `json:"quoted,omitempty"`
+	Likes               uint64       `json:"likes"`
+	LikedByMe           bool         `json:"likedByMe"`
+	CommentCount        uint64       `json:"commentCount"`
+	Reposts             uint64       `json:"reposts"`
+	RepostedById        uint64       `json:"repostedById,omitempty"`
+	RepostedBy          string       `json:"repostedBy,omitempty"`
+	RepostedAt          *time.Time   `json:"repostedAt,omitempty"`
+	Relevance           float64      `json:"relevance,omitempty"`
+	CreatedAt           time.Time    `json:"createdAt,omitempty"`
+}
+
+// ActivityAt tells when a publication entered feeds, when reposted or else created
+func (publication Publication) ActivityAt() time.Time {
+	if publication.RepostedAt != nil {
+		return *publication.RepostedAt
+	}
+	return publication.CreatedAt
+}
+
+// FeedCursor returns the position of a publication in feeds, ordered by activity
+func (publication Publication) FeedCursor() Cursor {
+	return Cursor{Time: publication.ActivityAt(), ID: publication.ID, Tie: publication.RepostedById}
+}
+
+// SearchCursor returns the position of a publication in search results, ordered by relevance
+func (publication Publication) SearchCursor() Cursor {
+	return Cursor{Score: publication.Relevance, ID: publication.ID}
 }
 
 // Prepare validates and formats a publication
@@ -41,10 +88,19 @@ func (publication *Publication) validate() error {
 		return errors.New("Invalid author")
 	}
 
+	if publication.Visibility != "" && !publication.Visibility.IsValid() {
+		return errors.New("Invalid visibility")
+	}
+
 	return nil
 }
 
 func (publication *Publication) format() {
 	publication.Title = strings.TrimSpace(publication.Title)
 	publication.Content = strings.TrimSpace(publication.Content)
-}
\ No newline at end of file
+	if publication.Visibility == "" {
+		publication.Visibility = VisibilityPublic
+	}
+	publication.Tags = ParseHashtags(publication.Title, publication.Content)
+	publication.Mentions = ParseMentions(publication.Content)
+}
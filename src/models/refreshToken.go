@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// RefreshToken represents a stored refresh token; tokens rotated from the same login share a family
+type RefreshToken struct {
+	ID        uint64     `json:"id,omitempty"`
+	UserId    uint64     `json:"userId,omitempty"`
+	FamilyId  string     `json:"familyId,omitempty"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt,omitempty"`
+}
+
+// IsActive tells if the token was neither revoked nor expired at the given time
+func (token RefreshToken) IsActive(now time.Time) bool {
+	return token.RevokedAt == nil && now.Before(token.ExpiresAt)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PublicationSearch represents a full-text search of publications, optionally restricted to an
+// author, a tag and publications created since a time, inclusive, until another, exclusive
+type PublicationSearch struct {
+	Query    string
+	AuthorId uint64
+	Tag      string
+	Since    *time.Time
+	Until    *time.Time
+}
+
+// Prepare validates and formats a search
+func (search *PublicationSearch) Prepare() error {
+
+	search.Query = strings.TrimSpace(search.Query)
+	search.Tag = NormalizeTag(search.Tag)
+
+	if len(search.Terms()) == 0 {
+		return errors.New("Invalid query")
+	}
+
+	if search.Since != nil && search.Until != nil && !search.Since.Before(*search.Until) {
+		return errors.New("Invalid date range")
+	}
+
+	return nil
+}
+
+// Terms returns the lower case words of the query
+func (search PublicationSearch) Terms() []string {
+	return strings.FieldsFunc(strings.ToLower(search.Query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxTagLength characters of a hashtag at most, longer ones not being tags
+const MaxTagLength = 50
+
+// hashtagPattern matches hashtags not preceded by a word character, capturing the tag
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/])#([\p{L}\p{N}_]+)`)
+
+// TrendingTag represents a tag ranked by how fast its usage grows
+type TrendingTag struct {
+	Tag          string  `json:"tag"`
+	Uses         uint64  `json:"uses"`
+	PreviousUses uint64  `json:"previousUses"`
+	Velocity     float64 `json:"velocity"`
+}
+
+// NewTrendingTag builds a trending tag used uses times in the last window and previousUses times in
+// the window before, its velocity being the growth of its uses per hour
+func NewTrendingTag(tag string, uses uint64, previousUses uint64, window time.Duration) TrendingTag {
+	return TrendingTag{
+		Tag:          tag,
+		Uses:         uses,
+		PreviousUses: previousUses,
+		Velocity:     (float64(uses) - float64(previousUses)) / window.Hours(),
+	}
+}
+
+// SortTrendingTags orders tags by velocity, then by uses and name
+func SortTrendingTags(tags []TrendingTag) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Velocity != tags[j].Velocity {
+			return tags[i].Velocity > tags[j].Velocity
+		}
+		if tags[i].Uses != tags[j].Uses {
+			return tags[i].Uses > tags[j].Uses
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+}
+
+// NormalizeTag returns the form tags are stored in, lower case without the leading #
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// ParseHashtags returns the distinct normalized hashtags of texts, sorted, ignoring tags without
+// letters or longer than MaxTagLength
+func ParseHashtags(texts ...string) []string {
+
+	found := make(map[string]bool)
+	var tags []string
+
+	for _, text := range texts {
+		for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+			tag := NormalizeTag(match[1])
+			if found[tag] || len([]rune(tag)) > MaxTagLength || strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+				continue
+			}
+			found[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHashtags(t *testing.T) {
+	tests := []struct {
+		texts    []string
+		expected []string
+	}{
+		{[]string{"No tags here"}, nil},
+		{[]string{"#Go is fun", "Learning #go and #Rust!"}, []string{"go", "rust"}},
+		{[]string{"#café, #naïve_code."}, []string{"café", "naïve_code"}},
+		{[]string{"C#sharp issue#42 &#39; http://host/#anchor"}, nil},
+		{[]string{"Numbers #2021 are not tags, #web3 is"}, []string{"web3"}},
+		{[]string{"#" + strings.Repeat("a", MaxTagLength+1) + " #" + strings.Repeat("b", MaxTagLength)},
+			[]string{strings.Repeat("b", MaxTagLength)}},
+	}
+
+	for _, test := range tests {
+		if tags := ParseHashtags(test.texts...); fmt.Sprint(tags) != fmt.Sprint(test.expected) {
+			t.Errorf("ParseHashtags(%q) = %v, expected %v", test.texts, tags, test.expected)
+		}
+	}
+}
+
+func TestSortTrendingTags(t *testing.T) {
+	tags := []TrendingTag{
+		NewTrendingTag("steady", 10, 10, time.Hour),
+		NewTrendingTag("rising", 4, 1, time.Hour),
+		NewTrendingTag("popular", 12, 9, time.Hour),
+		NewTrendingTag("fading", 1, 5, 2*time.Hour),
+	}
+
+	SortTrendingTags(tags)
+
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Tag)
+	}
+	if fmt.Sprint(names) != "[popular rising steady fading]" || tags[3].Velocity != -2 {
+		t.Fatalf("unexpected order: %+v", tags)
+	}
+}
@@ -10,12 +10,31 @@ import (
 
 // User represents a user of system
 type User struct {
-	ID        uint64    `json:"id,omitempty"`
-	Name      string    `json:"name,omitempty"`
-	Nick      string    `json:"nick,omitempty"`
-	Email     string    `json:"email,omitempty"`
-	Password  string    `json:"password,omitempty"`
-	CreatedAt time.Time `json:"createdAt,omitempty"`
+	ID              uint64        `json:"id,omitempty"`
+	Name            string        `json:"name,omitempty"`
+	Nick            string        `json:"nick,omitempty"`
+	Email           string        `json:"email,omitempty"`
+	Password        string        `json:"password,omitempty"`
+	EmailVerifiedAt *time.Time    `json:"emailVerifiedAt,omitempty"`
+	Role            security.Role `json:"role,omitempty"`
+	SuspendedAt     *time.Time    `json:"suspendedAt,omitempty"`
+	IsPrivate       bool          `json:"isPrivate"`
+	CreatedAt       time.Time     `json:"createdAt,omitempty"`
+}
+
+// RoleUpdate represents data for assigning a role to a user
+type RoleUpdate struct {
+	Role security.Role `json:"role,omitempty"`
+}
+
+// PrivacyUpdate represents data for making a user account private or public
+type PrivacyUpdate struct {
+	IsPrivate bool `json:"isPrivate"`
+}
+
+// IsSuspended tells if the user is suspended
+func (user User) IsSuspended() bool {
+	return user.SuspendedAt != nil
 }
 
 // PrepareCreate validates and formats user data for creation
@@ -89,4 +108,4 @@ func (user *User) format() {
 	user.Name = strings.TrimSpace(user.Name)
 	user.Email = strings.TrimSpace(user.Email)
 	user.Nick = strings.TrimSpace(user.Nick)
-}
\ No newline at end of file
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxWebhookURLLength characters of a webhook URL at most
+const MaxWebhookURLLength = 2048
+
+const (
+	// WebhookPublicationCreated the user published a publication
+	WebhookPublicationCreated = "publication.created"
+	// WebhookPublicationUpdated a publication of the user was edited
+	WebhookPublicationUpdated = "publication.updated"
+	// WebhookPublicationDeleted a publication of the user was deleted
+	WebhookPublicationDeleted = "publication.deleted"
+	// WebhookUserFollowed the user has a new follower
+	WebhookUserFollowed = "user.followed"
+	// WebhookPublicationLiked a publication of the user was liked
+	WebhookPublicationLiked = "publication.liked"
+)
+
+// WebhookEventTypes are the event types webhooks can subscribe to
+var WebhookEventTypes = []string{
+	WebhookPublicationCreated,
+	WebhookPublicationUpdated,
+	WebhookPublicationDeleted,
+	WebhookUserFollowed,
+	WebhookPublicationLiked,
+}
+
+// DeliveryStatus tells where a webhook delivery stands
+type DeliveryStatus string
+
+const (
+	// DeliveryPending delivery waiting for its next attempt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered delivery acknowledged by the receiver
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead delivery given up after its last attempt failed
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// Webhook represents a URL of a user receiving the events it subscribes to, with the secret signing
+// their payloads
+type Webhook struct {
+	ID         uint64    `json:"id,omitempty"`
+	UserId     uint64    `json:"userId,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	EventTypes []string  `json:"eventTypes,omitempty"`
+	Secret     string    `json:"secret,omitempty"`
+	CreatedAt  time.Time `json:"createdAt,omitempty"`
+}
+
+// WebhookPayload represents an event as posted to webhooks
+type WebhookPayload struct {
+	Type        string       `json:"type"`
+	Publication *Publication `json:"publication,omitempty"`
+	User        *User        `json:"user,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+}
+
+// WebhookDelivery represents the posting of an event payload to a webhook, with the outcome of its
+// last attempt
+type WebhookDelivery struct {
+	ID             uint64          `json:"id"`
+	WebhookId      uint64          `json:"webhookId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// Subscribes tells if a webhook receives events of a type
+func (webhook Webhook) Subscribes(eventType string) bool {
+	for _, subscribed := range webhook.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// isWebhookEventType tells if webhooks can subscribe to an event type
+func isWebhookEventType(eventType string) bool {
+	for _, known := range WebhookEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Prepare validates and formats a webhook
+func (webhook *Webhook) Prepare() error {
+
+	webhook.URL = strings.TrimSpace(webhook.URL)
+
+	if error := webhook.validate(); error != nil {
+		return error
+	}
+	webhook.format()
+	return nil
+}
+
+func (webhook *Webhook) validate() error {
+
+	if len(webhook.URL) > MaxWebhookURLLength {
+		return fmt.Errorf("URL exceeds %d characters", MaxWebhookURLLength)
+	}
+
+	parsed, error := url.Parse(webhook.URL)
+	if error != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("Invalid URL")
+	}
+
+	if len(webhook.EventTypes) == 0 {
+		return errors.New("Invalid event types")
+	}
+
+	for _, eventType := range webhook.EventTypes {
+		if !isWebhookEventType(eventType) {
+			return fmt.Errorf("Unknown event type %s", eventType)
+		}
+	}
+
+	return nil
+}
+
+func (webhook *Webhook) format() {
+
+	seen := make(map[string]bool)
+	var eventTypes []string
+	for _, eventType := range webhook.EventTypes {
+		if !seen[eventType] {
+			seen[eventType] = true
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	sort.Strings(eventTypes)
+	webhook.EventTypes = eventTypes
+}
+
+// Succeed records an attempt acknowledged by the receiver with a response status
+func (delivery *WebhookDelivery) Succeed(responseStatus int, at time.Time) {
+	delivery.Attempts++
+	delivery.Status = DeliveryDelivered
+	delivery.ResponseStatus = responseStatus
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+	delivery.DeliveredAt = &at
+}
+
+// Fail records a failed attempt, with the response status if the receiver answered: the next attempt
+// waits retryDelay, doubling with each failure, and the delivery is dead once attempts reach
+// maxAttempts
+func (delivery *WebhookDelivery) Fail(responseStatus int, reason string, at time.Time, maxAttempts int, retryDelay time.Duration) {
+	delivery.Attempts++
+	delivery.ResponseStatus = responseStatus
+	delivery.LastError = reason
+
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = DeliveryDead
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	wait := retryDelay
+	for attempt := 1; attempt < delivery.Attempts; attempt++ {
+		wait *= 2
+	}
+
+	nextAttemptAt := at.Add(wait)
+	delivery.Status = DeliveryPending
+	delivery.NextAttemptAt = &nextAttemptAt
+}
+
+// Retry schedules a dead delivery again, with attempts counted anew
+func (delivery *WebhookDelivery) Retry(at time.Time) {
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &at
+}
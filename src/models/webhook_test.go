@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookPrepare(t *testing.T) {
+	webhook := Webhook{
+		URL:        " https://example.com/hooks ",
+		EventTypes: []string{WebhookPublicationLiked, WebhookPublicationCreated, WebhookPublicationLiked},
+	}
+	if error := webhook.Prepare(); error != nil {
+		t.Fatal(error)
+	}
+	if webhook.URL != "https://example.com/hooks" || len(webhook.EventTypes) != 2 ||
+		webhook.EventTypes[0] != WebhookPublicationCreated || webhook.EventTypes[1] != WebhookPublicationLiked {
+		t.Fatalf("unexpected prepared webhook: %+v", webhook)
+	}
+
+	for _, invalid := range []Webhook{
+		{URL: "example.com", EventTypes: []string{WebhookPublicationCreated}},
+		{URL: "mailto:ada@devbook.com", EventTypes: []string{WebhookPublicationCreated}},
+		{URL: "https://example.com"},
+		{URL: "https://example.com", EventTypes: []string{"user.mentioned"}},
+	} {
+		if error := invalid.Prepare(); error == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestWebhookDeliveryFail(t *testing.T) {
+	at := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	delivery := WebhookDelivery{Status: DeliveryPending}
+
+	for attempt, wait := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		delivery.Fail(500, "Unexpected status 500", at, 4, time.Second)
+		if delivery.Status != DeliveryPending || delivery.Attempts != attempt+1 || !delivery.NextAttemptAt.Equal(at.Add(wait)) {
+			t.Fatalf("attempt %d: unexpected delivery %+v", attempt+1, delivery)
+		}
+	}
+
+	delivery.Fail(0, "timeout", at, 4, time.Second)
+	if delivery.Status != DeliveryDead || delivery.NextAttemptAt != nil || delivery.LastError != "timeout" {
+		t.Fatalf("expected the delivery dead after its last attempt: %+v", delivery)
+	}
+
+	delivery.Retry(at)
+	delivery.Succeed(200, at)
+	if delivery.Status != DeliveryDelivered || delivery.Attempts != 1 || delivery.LastError != "" ||
+		delivery.DeliveredAt == nil || !delivery.DeliveredAt.Equal(at) {
+		t.Fatalf("unexpected delivered delivery: %+v", delivery)
+	}
+}
@@ -0,0 +1,99 @@
+package notifications
+
+import (
+	"devbook/src/events"
+	"devbook/src/logging"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"time"
+)
+
+// notificationTypes maps the events users are notified of to the type of their notifications,
+// new notifications needing only an entry here once their event is published
+var notificationTypes = map[events.Type]models.NotificationType{
+	events.UserFollowed:         models.NotificationFollow,
+	events.FollowRequested:      models.NotificationFollowRequest,
+	events.FollowApproved:       models.NotificationFollowApproved,
+	events.PublicationLiked:     models.NotificationLike,
+	events.PublicationReposted:  models.NotificationRepost,
+	events.PublicationQuoted:    models.NotificationQuote,
+	events.PublicationCommented: models.NotificationComment,
+	events.CommentReplied:       models.NotificationReply,
+	events.UserMentioned:        models.NotificationMention,
+}
+
+// Notifier turns events into notifications of the users they concern, announcing each new
+// notification in turn
+type Notifier struct {
+	notifications persistence.NotificationStore
+	users         persistence.UserStore
+	publications  persistence.PublicationStore
+	publisher     events.Publisher
+}
+
+// NewNotifier factory
+func NewNotifier(notifications persistence.NotificationStore, users persistence.UserStore,
+	publications persistence.PublicationStore, publisher events.Publisher) *Notifier {
+	return &Notifier{notifications, users, publications, publisher}
+}
+
+// Subscribe makes the notifier handle the events published on bus
+func (notifier Notifier) Subscribe(bus *events.Bus) {
+	bus.Subscribe(notifier.Handle)
+}
+
+// Handle notifies the user concerned by an event, logging failures since the action that caused
+// the event already succeeded
+func (notifier Notifier) Handle(event events.Event) {
+	if error := notifier.notify(event); error != nil {
+		logging.Error("failed to notify user", logging.Fields{"user_id": event.UserId, "event": event.Type, "error": error})
+	}
+}
+
+// notify registers the notification of an event, unless the user caused it, blocks or mutes its
+// actor or cannot see its publication
+func (notifier Notifier) notify(event events.Event) error {
+
+	notificationType, found := notificationTypes[event.Type]
+	if !found || event.UserId == 0 || event.UserId == event.ActorId {
+		return nil
+	}
+
+	blocked, error := notifier.users.IsBlocked(event.UserId, event.ActorId)
+	if error != nil || blocked {
+		return error
+	}
+
+	muted, error := notifier.users.IsMuted(event.UserId, event.ActorId)
+	if error != nil || muted {
+		return error
+	}
+
+	if event.PublicationId != 0 {
+		publication, error := notifier.publications.GetPublicationById(event.PublicationId, event.UserId)
+		if error != nil || publication.ID == 0 {
+			return error
+		}
+	}
+
+	notification := models.Notification{
+		UserId:        event.UserId,
+		Type:          notificationType,
+		ActorId:       event.ActorId,
+		PublicationId: event.PublicationId,
+		CommentId:     event.CommentId,
+	}
+
+	id, error := notifier.notifications.CreateNotification(notification)
+	if error != nil || id == 0 {
+		return error
+	}
+
+	notification.ID = id
+	notification.CreatedAt = time.Now()
+	notifier.publisher.Publish(events.Event{Type: events.NotificationCreated, ActorId: event.ActorId,
+		UserId: event.UserId, PublicationId: event.PublicationId, CommentId: event.CommentId,
+		Data: notification})
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+	"strings"
+)
+
+// CommentRepository persists comments on publications
+type CommentRepository struct {
+	db *sql.DB
+}
+
+// CreateComment creates a new comment, linking replies to every ancestor of their parent
+func (repository CommentRepository) CreateComment(comment models.Comment) (uint64, error) {
+
+	transaction, error := repository.db.Begin()
+	if error != nil {
+		return 0, error
+	}
+	defer transaction.Rollback()
+
+	var parentId sql.NullInt64
+	if comment.ParentId != 0 {
+		parentId = sql.NullInt64{Int64: int64(comment.ParentId), Valid: true}
+	}
+
+	insert, error := transaction.Exec(
+		"insert into comments (publication_id, author_id, parent_id, content) values (?, ?, ?, ?)",
+		comment.PublicationId, comment.AuthorId, parentId, comment.Content)
+	if error != nil {
+		return 0, error
+	}
+
+	id, error := insert.LastInsertId()
+	if error != nil {
+		return 0, error
+	}
+
+	if _, error = transaction.Exec(
+		`insert into comment_tree (ancestor_id, descendant_id, depth)
+				select ancestor_id, ?, depth + 1 from comment_tree where descendant_id = ?
+				union all select ?, ?, 0`,
+		id, comment.ParentId, id, id); error != nil {
+		return 0, error
+	}
+
+	if error = transaction.Commit(); error != nil {
+		return 0, error
+	}
+
+	return uint64(id), nil
+}
+
+// GetCommentById gets a specific comment by its id
+func (repository CommentRepository) GetCommentById(id uint64) (models.Comment, error) {
+
+	var comment models.Comment
+
+	resultSet, error := repository.db.Query(
+		`select c.id, c.publication_id, c.author_id, c.parent_id, u.nick, c.content, c.created_at, c.updated_at
+				from comments c
+				join users u on (c.author_id = u.id)
+				where c.id = ?`, id)
+	if error != nil {
+		return comment, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if comment, error = scanComment(resultSet); error != nil {
+			return comment, error
+		}
+	}
+
+	return comment, nil
+}
+
+// GetPublicationComments gets a page of comments of a publication, oldest first, leaving out
+// comments of users viewerId blocks or mutes and of users blocking it
+func (repository CommentRepository) GetPublicationComments(publicationId uint64, viewerId uint64, page models.PageRequest) ([]models.Comment, error) {
+
+	resultSet, error := repository.db.Query(
+		`select c.id, c.publication_id, c.author_id, c.parent_id, u.nick, c.content, c.created_at, c.updated_at
+				from comments c
+				join users u on (c.author_id = u.id)
+				where c.publication_id = ? and not `+silencedBy("c.author_id")+`
+				and c.id > ? order by c.id limit ?`,
+		publicationId, viewerId, viewerId, viewerId, afterId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var comments []models.Comment
+
+	for resultSet.Next() {
+		comment, error := scanComment(resultSet)
+		if error != nil {
+			return nil, error
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+// GetCommentReplies gets a page of replies of each of parentIds, by id, the cursor applying to
+// the replies of every parent, leaving out replies of users viewerId blocks or mutes and of users
+// blocking it
+func (repository CommentRepository) GetCommentReplies(parentIds []uint64, viewerId uint64, page models.PageRequest) ([]models.Comment, error) {
+
+	if len(parentIds) == 0 {
+		return nil, nil
+	}
+
+	queries := make([]string, 0, len(parentIds))
+	args := make([]interface{}, 0, 6*len(parentIds))
+	for _, parentId := range parentIds {
+		queries = append(queries,
+			`(select c.id, c.publication_id, c.author_id, c.parent_id, u.nick, c.content, c.created_at, c.updated_at
+				from comments c
+				join users u on (c.author_id = u.id)
+				where c.parent_id = ? and not `+silencedBy("c.author_id")+`
+				and c.id > ? order by c.id limit ?)`)
+		args = append(args, parentId, viewerId, viewerId, viewerId, afterId(page), page.Limit+1)
+	}
+
+	resultSet, error := repository.db.Query(strings.Join(queries, " union all "), args...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var comments []models.Comment
+
+	for resultSet.Next() {
+		comment, error := scanComment(resultSet)
+		if error != nil {
+			return nil, error
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+// CountCommentReplies counts the replies of each of ids seen by viewerId, leaving out comments
+// without replies
+func (repository CommentRepository) CountCommentReplies(ids []uint64, viewerId uint64) (map[uint64]int, error) {
+
+	counts := make(map[uint64]int)
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+3)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, viewerId, viewerId, viewerId)
+
+	resultSet, error := repository.db.Query(
+		`select c.parent_id, count(*) from comments c
+				where c.parent_id in (?`+strings.Repeat(", ?", len(ids)-1)+`)
+				and not `+silencedBy("c.author_id")+` group by c.parent_id`,
+		args...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	for resultSet.Next() {
+		var parentId uint64
+		var count int
+		if error = resultSet.Scan(&parentId, &count); error != nil {
+			return nil, error
+		}
+		counts[parentId] = count
+	}
+
+	return counts, nil
+}
+
+// UpdateComment updates the content of a comment
+func (repository CommentRepository) UpdateComment(id uint64, comment models.Comment) error {
+
+	stmt, error := repository.db.Prepare(
+		"update comments set content = ?, updated_at = current_timestamp() where id = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(comment.Content, id); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// DeleteComment deletes a comment along with its replies
+func (repository CommentRepository) DeleteComment(id uint64) error {
+
+	stmt, error := repository.db.Prepare(
+		`delete from comments where id in (
+				select descendant_id from (select descendant_id from comment_tree where ancestor_id = ?) as descendants)`)
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(id); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// scanComment reads a comment row with its author nick
+func scanComment(resultSet *sql.Rows) (models.Comment, error) {
+
+	var comment models.Comment
+	var parentId sql.NullInt64
+	var updatedAt sql.NullTime
+
+	if error := resultSet.Scan(&comment.ID, &comment.PublicationId, &comment.AuthorId, &parentId,
+		&comment.AuthorNick, &comment.Content, &comment.CreatedAt, &updatedAt); error != nil {
+		return comment, error
+	}
+	if parentId.Valid {
+		comment.ParentId = uint64(parentId.Int64)
+	}
+	if updatedAt.Valid {
+		comment.UpdatedAt = &updatedAt.Time
+	}
+
+	return comment, nil
+}
+
+// NewCommentRepository factory
+func NewCommentRepository(db *sql.DB) *CommentRepository {
+	return &CommentRepository{db}
+}
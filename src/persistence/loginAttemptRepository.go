@@ -0,0 +1,119 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+	"time"
+)
+
+// LoginAttemptRepository persists failed login counters
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+// CountLoginAttempt atomically counts a login attempt under a key as failed until released,
+// restarting the count when the previous failure is older than window, and returns the attempts
+// counted, this one included, the new count being read back through last_insert_id
+func (repository LoginAttemptRepository) CountLoginAttempt(key string, window time.Duration) (models.LoginAttempts, error) {
+
+	attempts := models.LoginAttempts{Key: key}
+
+	stmt, error := repository.db.Prepare(
+		`insert into login_attempts (attempt_key, failures, last_failure_at) values (?, 1, current_timestamp())
+			on duplicate key update
+				failures = last_insert_id(if(last_failure_at < current_timestamp() - interval ? second, 1, failures + 1)),
+				last_failure_at = if(failures = 1, current_timestamp(), last_failure_at)`)
+	if error != nil {
+		return attempts, error
+	}
+	defer stmt.Close()
+
+	insert, error := stmt.Exec(key, int64(window/time.Second))
+	if error != nil {
+		return attempts, error
+	}
+
+	inserted, error := insert.RowsAffected()
+	if error != nil {
+		return attempts, error
+	}
+
+	attempts.Failures = 1
+	if inserted != 1 {
+		failures, error := insert.LastInsertId()
+		if error != nil {
+			return attempts, error
+		}
+		attempts.Failures = int(failures)
+	}
+
+	resultSet, error := repository.db.Query(
+		"select last_failure_at from login_attempts where attempt_key = ?", key)
+	if error != nil {
+		return attempts, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if error = resultSet.Scan(&attempts.LastFailureAt); error != nil {
+			return attempts, error
+		}
+	}
+
+	return attempts, nil
+}
+
+// RegisterLoginFailure records the time of a failed login counted under a key
+func (repository LoginAttemptRepository) RegisterLoginFailure(key string) error {
+
+	stmt, error := repository.db.Prepare(
+		"update login_attempts set last_failure_at = current_timestamp() where attempt_key = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(key); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// ReleaseLoginAttempt stops counting a login attempt under a key
+func (repository LoginAttemptRepository) ReleaseLoginAttempt(key string) error {
+
+	stmt, error := repository.db.Prepare(
+		"update login_attempts set failures = failures - 1 where attempt_key = ? and failures > 0")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(key); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// ResetLoginAttempts clears the login attempts counted under a key
+func (repository LoginAttemptRepository) ResetLoginAttempts(key string) error {
+
+	stmt, error := repository.db.Prepare("delete from login_attempts where attempt_key = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(key); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// NewLoginAttemptRepository factory
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db}
+}
@@ -0,0 +1,163 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"sort"
+	"time"
+)
+
+// MemoryCommentRepository keeps comments in memory, with the same semantics as CommentRepository
+type MemoryCommentRepository struct {
+	db *memoryDatabase
+}
+
+// CreateComment creates a new comment
+func (repository MemoryCommentRepository) CreateComment(comment models.Comment) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, publicationFound := repository.db.publications[comment.PublicationId]
+	_, authorFound := repository.db.users[comment.AuthorId]
+	if !publicationFound || !authorFound {
+		return 0, errForeignKeyConstraint
+	}
+
+	comment.ID = repository.db.nextId()
+	repository.db.comments[comment.ID] = models.Comment{
+		ID:            comment.ID,
+		PublicationId: comment.PublicationId,
+		AuthorId:      comment.AuthorId,
+		ParentId:      comment.ParentId,
+		Content:       comment.Content,
+		CreatedAt:     time.Now(),
+	}
+
+	return comment.ID, nil
+}
+
+// GetCommentById gets a specific comment by its id
+func (repository MemoryCommentRepository) GetCommentById(id uint64) (models.Comment, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	comment, found := repository.db.comments[id]
+	if !found {
+		return models.Comment{}, nil
+	}
+
+	return repository.view(comment), nil
+}
+
+// GetPublicationComments gets a page of comments of a publication, oldest first, leaving out
+// comments of users viewerId blocks or mutes and of users blocking it
+func (repository MemoryCommentRepository) GetPublicationComments(publicationId uint64, viewerId uint64, page models.PageRequest) ([]models.Comment, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	after := afterId(page)
+	var comments []models.Comment
+	for _, comment := range repository.db.comments {
+		if comment.PublicationId == publicationId && comment.ID > after &&
+			!repository.db.silencedBy(comment.AuthorId, viewerId) {
+			comments = append(comments, repository.view(comment))
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].ID < comments[j].ID
+	})
+
+	if len(comments) > page.Limit+1 {
+		comments = comments[:page.Limit+1]
+	}
+
+	return comments, nil
+}
+
+// GetCommentReplies gets a page of replies of each of parentIds, by id, the cursor applying to
+// the replies of every parent, leaving out replies of users viewerId blocks or mutes and of users
+// blocking it
+func (repository MemoryCommentRepository) GetCommentReplies(parentIds []uint64, viewerId uint64, page models.PageRequest) ([]models.Comment, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var comments []models.Comment
+	after := afterId(page)
+	for _, parentId := range parentIds {
+		var replies []models.Comment
+		for _, comment := range repository.db.comments {
+			if comment.ParentId == parentId && comment.ID > after &&
+				!repository.db.silencedBy(comment.AuthorId, viewerId) {
+				replies = append(replies, repository.view(comment))
+			}
+		}
+
+		sort.Slice(replies, func(i, j int) bool {
+			return replies[i].ID < replies[j].ID
+		})
+
+		if len(replies) > page.Limit+1 {
+			replies = replies[:page.Limit+1]
+		}
+		comments = append(comments, replies...)
+	}
+
+	return comments, nil
+}
+
+// CountCommentReplies counts the replies of each of ids seen by viewerId, leaving out comments
+// without replies
+func (repository MemoryCommentRepository) CountCommentReplies(ids []uint64, viewerId uint64) (map[uint64]int, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	counted := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		counted[id] = true
+	}
+
+	counts := make(map[uint64]int)
+	for _, comment := range repository.db.comments {
+		if comment.ParentId != 0 && counted[comment.ParentId] && !repository.db.silencedBy(comment.AuthorId, viewerId) {
+			counts[comment.ParentId]++
+		}
+	}
+
+	return counts, nil
+}
+
+// UpdateComment updates the content of a comment
+func (repository MemoryCommentRepository) UpdateComment(id uint64, comment models.Comment) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if stored, found := repository.db.comments[id]; found {
+		now := time.Now()
+		stored.Content = comment.Content
+		stored.UpdatedAt = &now
+		repository.db.comments[id] = stored
+	}
+
+	return nil
+}
+
+// DeleteComment deletes a comment
+func (repository MemoryCommentRepository) DeleteComment(id uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.db.deleteComment(id)
+
+	return nil
+}
+
+// view fills the author nick of a comment
+func (repository MemoryCommentRepository) view(comment models.Comment) models.Comment {
+	comment.AuthorNick = repository.db.users[comment.AuthorId].Nick
+	return comment
+}
+
+// newMemoryCommentRepository factory
+func newMemoryCommentRepository(db *memoryDatabase) *MemoryCommentRepository {
+	return &MemoryCommentRepository{db}
+}
@@ -0,0 +1,351 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errForeignKeyConstraint mirrors the error raised by MySQL when a row references a missing parent
+var errForeignKeyConstraint = errors.New(
+	"Error 1452: Cannot add or update a child row: a foreign key constraint fails")
+
+// duplicateEntryError mirrors the error raised by MySQL when a unique key is violated
+func duplicateEntryError(value string, key string) error {
+	return fmt.Errorf("Error 1062: Duplicate entry '%s' for key '%s'", value, key)
+}
+
+// follow is a row of the followers table
+type follow struct {
+	userId     uint64
+	followerId uint64
+}
+
+// block is a row of the user_blocks table
+type block struct {
+	userId    uint64
+	blockedId uint64
+}
+
+// mute is a row of the user_mutes table
+type mute struct {
+	userId  uint64
+	mutedId uint64
+}
+
+// publicationLike is a row of the publication_likes table
+type publicationLike struct {
+	publicationId uint64
+	userId        uint64
+	createdAt     time.Time
+}
+
+// repost is a row of the reposts table
+type repost struct {
+	publicationId uint64
+	userId        uint64
+	createdAt     time.Time
+}
+
+// conversation is a row of the conversations table, with the ids of both its members
+type conversation struct {
+	id        uint64
+	directKey string
+	userIds   [2]uint64
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// conversationMember is a row of the conversation_members table
+type conversationMember struct {
+	lastReadMessageId uint64
+	readAt            *time.Time
+}
+
+// conversationMemberKey identifies a row of the conversation_members table
+type conversationMemberKey struct {
+	conversationId uint64
+	userId         uint64
+}
+
+// memoryDatabase holds the in-memory tables shared by memory repositories
+type memoryDatabase struct {
+	mutex          sync.RWMutex
+	sequence       uint64
+	users          map[uint64]models.User
+	followers      map[follow]bool
+	followRequests map[follow]time.Time
+	blocks         map[block]bool
+	mutes          map[mute]bool
+	publications   map[uint64]models.Publication
+	likes          []publicationLike
+	reposts        []repost
+	comments       map[uint64]models.Comment
+	notifications  map[uint64]models.Notification
+	conversations  map[uint64]conversation
+	members        map[conversationMemberKey]conversationMember
+	messages       map[uint64]models.Message
+	webhooks       map[uint64]models.Webhook
+	deliveries     map[uint64]models.WebhookDelivery
+	claims         map[uint64]time.Time
+	refreshTokens  map[uint64]models.RefreshToken
+	oneTimeTokens  map[uint64]models.OneTimeToken
+	mfa            map[uint64]models.MFA
+	recoveryCodes  map[uint64]map[string]bool
+	loginAttempts  map[string]models.LoginAttempts
+}
+
+func newMemoryDatabase() *memoryDatabase {
+	return &memoryDatabase{
+		users:          make(map[uint64]models.User),
+		followers:      make(map[follow]bool),
+		followRequests: make(map[follow]time.Time),
+		blocks:         make(map[block]bool),
+		mutes:          make(map[mute]bool),
+		publications:   make(map[uint64]models.Publication),
+		comments:       make(map[uint64]models.Comment),
+		notifications:  make(map[uint64]models.Notification),
+		conversations:  make(map[uint64]conversation),
+		members:        make(map[conversationMemberKey]conversationMember),
+		messages:       make(map[uint64]models.Message),
+		webhooks:       make(map[uint64]models.Webhook),
+		deliveries:     make(map[uint64]models.WebhookDelivery),
+		claims:         make(map[uint64]time.Time),
+		refreshTokens:  make(map[uint64]models.RefreshToken),
+		oneTimeTokens:  make(map[uint64]models.OneTimeToken),
+		mfa:            make(map[uint64]models.MFA),
+		recoveryCodes:  make(map[uint64]map[string]bool),
+		loginAttempts:  make(map[string]models.LoginAttempts),
+	}
+}
+
+// nextId returns a new identifier, like an auto increment column
+func (db *memoryDatabase) nextId() uint64 {
+	db.sequence++
+	return db.sequence
+}
+
+// deleteUser removes a user and cascades to the rows referencing it
+func (db *memoryDatabase) deleteUser(id uint64) {
+	delete(db.users, id)
+
+	for key := range db.followers {
+		if key.userId == id || key.followerId == id {
+			delete(db.followers, key)
+		}
+	}
+
+	for key := range db.followRequests {
+		if key.userId == id || key.followerId == id {
+			delete(db.followRequests, key)
+		}
+	}
+
+	for key := range db.blocks {
+		if key.userId == id || key.blockedId == id {
+			delete(db.blocks, key)
+		}
+	}
+
+	for key := range db.mutes {
+		if key.userId == id || key.mutedId == id {
+			delete(db.mutes, key)
+		}
+	}
+
+	for publicationId, publication := range db.publications {
+		if publication.AuthorId == id {
+			db.deletePublication(publicationId)
+		}
+	}
+
+	for publicationId, publication := range db.publications {
+		var mentions []models.Mention
+		for _, mention := range publication.Mentions {
+			if mention.UserId != id {
+				mentions = append(mentions, mention)
+			}
+		}
+		publication.Mentions = mentions
+		db.publications[publicationId] = publication
+	}
+
+	db.likes = filterLikes(db.likes, func(like publicationLike) bool {
+		return like.userId != id
+	})
+
+	db.reposts = filterReposts(db.reposts, func(repost repost) bool {
+		return repost.userId != id
+	})
+
+	for commentId, comment := range db.comments {
+		if comment.AuthorId == id {
+			db.deleteComment(commentId)
+		}
+	}
+
+	for notificationId, notification := range db.notifications {
+		if notification.UserId == id || notification.ActorId == id {
+			delete(db.notifications, notificationId)
+		}
+	}
+
+	for key := range db.members {
+		if key.userId == id {
+			delete(db.members, key)
+		}
+	}
+
+	for messageId, message := range db.messages {
+		if message.SenderId == id {
+			delete(db.messages, messageId)
+		}
+	}
+
+	for webhookId, webhook := range db.webhooks {
+		if webhook.UserId == id {
+			db.deleteWebhook(webhookId)
+		}
+	}
+
+	for tokenId, token := range db.refreshTokens {
+		if token.UserId == id {
+			delete(db.refreshTokens, tokenId)
+		}
+	}
+
+	for tokenId, token := range db.oneTimeTokens {
+		if token.UserId == id {
+			delete(db.oneTimeTokens, tokenId)
+		}
+	}
+
+	db.deleteMFA(id)
+}
+
+// deleteWebhook removes a webhook along with its deliveries
+func (db *memoryDatabase) deleteWebhook(id uint64) {
+	delete(db.webhooks, id)
+
+	for deliveryId, delivery := range db.deliveries {
+		if delivery.WebhookId == id {
+			delete(db.deliveries, deliveryId)
+			delete(db.claims, deliveryId)
+		}
+	}
+}
+
+// claimed tells if a webhook delivery is claimed at now
+func (db *memoryDatabase) claimed(deliveryId uint64, now time.Time) bool {
+	until, found := db.claims[deliveryId]
+	return found && until.After(now)
+}
+
+// deleteMFA removes a user's second factor and recovery codes
+func (db *memoryDatabase) deleteMFA(userId uint64) {
+	delete(db.mfa, userId)
+	delete(db.recoveryCodes, userId)
+}
+
+// deletePublication removes a publication and cascades to the rows referencing it
+func (db *memoryDatabase) deletePublication(id uint64) {
+	delete(db.publications, id)
+
+	db.likes = filterLikes(db.likes, func(like publicationLike) bool {
+		return like.publicationId != id
+	})
+
+	db.reposts = filterReposts(db.reposts, func(repost repost) bool {
+		return repost.publicationId != id
+	})
+
+	for commentId, comment := range db.comments {
+		if comment.PublicationId == id {
+			delete(db.comments, commentId)
+		}
+	}
+
+	for notificationId, notification := range db.notifications {
+		if notification.PublicationId == id {
+			delete(db.notifications, notificationId)
+		}
+	}
+}
+
+// deleteComment removes a comment along with its replies
+func (db *memoryDatabase) deleteComment(id uint64) {
+	delete(db.comments, id)
+
+	for notificationId, notification := range db.notifications {
+		if notification.CommentId == id {
+			delete(db.notifications, notificationId)
+		}
+	}
+
+	for replyId, reply := range db.comments {
+		if reply.ParentId == id {
+			db.deleteComment(replyId)
+		}
+	}
+}
+
+// silencedBy tells if a user is muted or blocked by viewerId, or blocks viewerId
+func (db *memoryDatabase) silencedBy(userId uint64, viewerId uint64) bool {
+	return db.mutes[mute{viewerId, userId}] || db.blocks[block{viewerId, userId}] || db.blocks[block{userId, viewerId}]
+}
+
+// blocking tells if either of two users blocks the other
+func (db *memoryDatabase) blocking(userId uint64, otherId uint64) bool {
+	return db.blocks[block{userId, otherId}] || db.blocks[block{otherId, userId}]
+}
+
+// publicUser returns user columns visible in listings, without password
+func publicUser(user models.User) models.User {
+	return models.User{
+		ID:        user.ID,
+		Name:      user.Name,
+		Nick:      user.Nick,
+		Email:     user.Email,
+		IsPrivate: user.IsPrivate,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// pageUsers returns the page of users by id, reading one user past the page when there are more
+func pageUsers(users []models.User, page models.PageRequest) []models.User {
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	after := afterId(page)
+	var paged []models.User
+	for _, user := range users {
+		if user.ID > after && len(paged) <= page.Limit {
+			paged = append(paged, user)
+		}
+	}
+	return paged
+}
+
+func filterLikes(likes []publicationLike, keep func(publicationLike) bool) []publicationLike {
+	var filtered []publicationLike
+	for _, like := range likes {
+		if keep(like) {
+			filtered = append(filtered, like)
+		}
+	}
+	return filtered
+}
+
+func filterReposts(reposts []repost, keep func(repost) bool) []repost {
+	var filtered []repost
+	for _, repost := range reposts {
+		if keep(repost) {
+			filtered = append(filtered, repost)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,73 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"time"
+)
+
+// MemoryLoginAttemptRepository keeps failed login counters in memory, with the same semantics as LoginAttemptRepository
+type MemoryLoginAttemptRepository struct {
+	db *memoryDatabase
+}
+
+// CountLoginAttempt atomically counts a login attempt under a key as failed until released,
+// restarting the count when the previous failure is older than window, and returns the attempts
+// counted, this one included
+func (repository MemoryLoginAttemptRepository) CountLoginAttempt(key string, window time.Duration) (models.LoginAttempts, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	now := time.Now()
+	attempts, found := repository.db.loginAttempts[key]
+	if !found || attempts.LastFailureAt.Before(now.Add(-window)) {
+		attempts = models.LoginAttempts{Key: key}
+	}
+	attempts.Failures++
+	if attempts.Failures == 1 {
+		attempts.LastFailureAt = now
+	}
+	repository.db.loginAttempts[key] = attempts
+
+	return attempts, nil
+}
+
+// RegisterLoginFailure records the time of a failed login counted under a key
+func (repository MemoryLoginAttemptRepository) RegisterLoginFailure(key string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if attempts, found := repository.db.loginAttempts[key]; found {
+		attempts.LastFailureAt = time.Now()
+		repository.db.loginAttempts[key] = attempts
+	}
+
+	return nil
+}
+
+// ReleaseLoginAttempt stops counting a login attempt under a key
+func (repository MemoryLoginAttemptRepository) ReleaseLoginAttempt(key string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if attempts, found := repository.db.loginAttempts[key]; found && attempts.Failures > 0 {
+		attempts.Failures--
+		repository.db.loginAttempts[key] = attempts
+	}
+
+	return nil
+}
+
+// ResetLoginAttempts clears the login attempts counted under a key
+func (repository MemoryLoginAttemptRepository) ResetLoginAttempts(key string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	delete(repository.db.loginAttempts, key)
+
+	return nil
+}
+
+// newMemoryLoginAttemptRepository factory
+func newMemoryLoginAttemptRepository(db *memoryDatabase) *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{db}
+}
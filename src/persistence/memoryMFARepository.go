@@ -0,0 +1,118 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"time"
+)
+
+// MemoryMFARepository keeps second factors in memory, with the same semantics as MFARepository
+type MemoryMFARepository struct {
+	db *memoryDatabase
+}
+
+// GetMFA gets a user's second factor
+func (repository MemoryMFARepository) GetMFA(userId uint64) (models.MFA, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.mfa[userId], nil
+}
+
+// SaveMFASecret stores a new, not yet enabled, secret for a user replacing any previous one
+func (repository MemoryMFARepository) SaveMFASecret(userId uint64, secret string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if _, found := repository.db.users[userId]; !found {
+		return errForeignKeyConstraint
+	}
+
+	mfa, found := repository.db.mfa[userId]
+	if !found {
+		mfa = models.MFA{UserId: userId, CreatedAt: time.Now()}
+	}
+	mfa.Secret = secret
+	mfa.EnabledAt = nil
+	mfa.LastUsedStep = 0
+	repository.db.mfa[userId] = mfa
+
+	return nil
+}
+
+// EnableMFA enables a user's second factor
+func (repository MemoryMFARepository) EnableMFA(userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if mfa, found := repository.db.mfa[userId]; found && mfa.EnabledAt == nil {
+		now := time.Now()
+		mfa.EnabledAt = &now
+		repository.db.mfa[userId] = mfa
+	}
+
+	return nil
+}
+
+// DisableMFA removes a user's second factor and recovery codes
+func (repository MemoryMFARepository) DisableMFA(userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.db.deleteMFA(userId)
+
+	return nil
+}
+
+// UseMFAStep records a time step as used, telling whether it is newer than the last used one
+func (repository MemoryMFARepository) UseMFAStep(userId uint64, step uint64) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	mfa, found := repository.db.mfa[userId]
+	if !found || mfa.LastUsedStep >= step {
+		return false, nil
+	}
+
+	mfa.LastUsedStep = step
+	repository.db.mfa[userId] = mfa
+
+	return true, nil
+}
+
+// ReplaceRecoveryCodes replaces a user's recovery codes with the given hashes
+func (repository MemoryMFARepository) ReplaceRecoveryCodes(userId uint64, codeHashes []string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if _, found := repository.db.users[userId]; !found {
+		return errForeignKeyConstraint
+	}
+
+	codes := make(map[string]bool)
+	for _, codeHash := range codeHashes {
+		codes[codeHash] = false
+	}
+	repository.db.recoveryCodes[userId] = codes
+
+	return nil
+}
+
+// UseRecoveryCode marks a recovery code as used, telling whether it was valid and unused
+func (repository MemoryMFARepository) UseRecoveryCode(userId uint64, codeHash string) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	used, found := repository.db.recoveryCodes[userId][codeHash]
+	if !found || used {
+		return false, nil
+	}
+
+	repository.db.recoveryCodes[userId][codeHash] = true
+
+	return true, nil
+}
+
+// newMemoryMFARepository factory
+func newMemoryMFARepository(db *memoryDatabase) *MemoryMFARepository {
+	return &MemoryMFARepository{db}
+}
@@ -0,0 +1,225 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"sort"
+	"time"
+)
+
+// MemoryMessageRepository keeps direct messages in memory, with the same semantics as MessageRepository
+type MemoryMessageRepository struct {
+	db *memoryDatabase
+}
+
+// GetOrCreateConversation gets the conversation between two users, starting it when there is none
+func (repository MemoryMessageRepository) GetOrCreateConversation(userId uint64, otherId uint64) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, userFound := repository.db.users[userId]
+	_, otherFound := repository.db.users[otherId]
+	if !userFound || !otherFound {
+		return 0, errForeignKeyConstraint
+	}
+
+	key := directKey(userId, otherId)
+	var found conversation
+	for _, conversation := range repository.db.conversations {
+		if conversation.directKey == key {
+			found = conversation
+		}
+	}
+
+	if found.id == 0 {
+		now := time.Now()
+		found = conversation{repository.db.nextId(), key, [2]uint64{userId, otherId}, now, now}
+		repository.db.conversations[found.id] = found
+	}
+
+	for _, memberId := range []uint64{userId, otherId} {
+		memberKey := conversationMemberKey{found.id, memberId}
+		if _, member := repository.db.members[memberKey]; !member {
+			repository.db.members[memberKey] = conversationMember{}
+		}
+	}
+
+	return found.id, nil
+}
+
+// GetConversation gets a specific conversation by its id as seen by one of its users
+func (repository MemoryMessageRepository) GetConversation(id uint64, userId uint64) (models.Conversation, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	conversation, _ := repository.view(repository.db.conversations[id], userId)
+	return conversation, nil
+}
+
+// GetConversations gets a page of the conversations of a user, most recently active first
+func (repository MemoryMessageRepository) GetConversations(userId uint64, page models.PageRequest) ([]models.Conversation, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var conversations []models.Conversation
+	for _, stored := range repository.db.conversations {
+		conversation, found := repository.view(stored, userId)
+		if found && (page.After == nil || cursorBefore(conversation.Cursor(), *page.After)) {
+			conversations = append(conversations, conversation)
+		}
+	}
+
+	sort.Slice(conversations, func(i, j int) bool {
+		return cursorBefore(conversations[j].Cursor(), conversations[i].Cursor())
+	})
+
+	if len(conversations) > page.Limit+1 {
+		conversations = conversations[:page.Limit+1]
+	}
+
+	return conversations, nil
+}
+
+// CreateMessage sends a message in a conversation, which its sender has read up to it
+func (repository MemoryMessageRepository) CreateMessage(message models.Message) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	conversation, conversationFound := repository.db.conversations[message.ConversationId]
+	_, senderFound := repository.db.users[message.SenderId]
+	if !conversationFound || !senderFound {
+		return 0, errForeignKeyConstraint
+	}
+
+	now := time.Now()
+	message.ID = repository.db.nextId()
+	message.CreatedAt = now
+	repository.db.messages[message.ID] = message
+
+	conversation.updatedAt = now
+	repository.db.conversations[conversation.id] = conversation
+
+	memberKey := conversationMemberKey{conversation.id, message.SenderId}
+	if _, member := repository.db.members[memberKey]; member {
+		repository.db.members[memberKey] = conversationMember{message.ID, &now}
+	}
+
+	return message.ID, nil
+}
+
+// GetMessageById gets a specific message by its id
+func (repository MemoryMessageRepository) GetMessageById(id uint64) (models.Message, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.messages[id], nil
+}
+
+// GetMessages gets a page of the messages of a conversation, most recent first
+func (repository MemoryMessageRepository) GetMessages(conversationId uint64, page models.PageRequest) ([]models.Message, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	before := beforeId(page)
+	var messages []models.Message
+	for _, message := range repository.db.messages {
+		if message.ConversationId == conversationId && message.ID < before {
+			messages = append(messages, message)
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID > messages[j].ID
+	})
+
+	if len(messages) > page.Limit+1 {
+		messages = messages[:page.Limit+1]
+	}
+
+	return messages, nil
+}
+
+// MarkConversationRead marks a conversation read by a user up to a message, or its last message
+// when messageId is zero, never moving back the messages read before
+func (repository MemoryMessageRepository) MarkConversationRead(conversationId uint64, userId uint64, messageId uint64) (models.ReadReceipt, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	receipt := models.ReadReceipt{ConversationId: conversationId, UserId: userId}
+
+	memberKey := conversationMemberKey{conversationId, userId}
+	member, found := repository.db.members[memberKey]
+	if !found {
+		return receipt, nil
+	}
+
+	last := repository.lastMessage(conversationId).ID
+	if messageId == 0 || messageId > last {
+		messageId = last
+	}
+	if messageId > member.lastReadMessageId {
+		member.lastReadMessageId = messageId
+	}
+
+	now := time.Now()
+	member.readAt = &now
+	repository.db.members[memberKey] = member
+
+	receipt.MessageId = member.lastReadMessageId
+	receipt.ReadAt = now
+
+	return receipt, nil
+}
+
+// view returns a conversation as seen by one of its users, telling if both users are members
+func (repository MemoryMessageRepository) view(stored conversation, userId uint64) (models.Conversation, bool) {
+
+	participantId := stored.userIds[0]
+	if participantId == userId {
+		participantId = stored.userIds[1]
+	}
+
+	member, userFound := repository.db.members[conversationMemberKey{stored.id, userId}]
+	participant, participantFound := repository.db.members[conversationMemberKey{stored.id, participantId}]
+	if stored.id == 0 || participantId == userId || !userFound || !participantFound {
+		return models.Conversation{}, false
+	}
+
+	user := repository.db.users[participantId]
+	conversation := models.Conversation{
+		ID:                           stored.id,
+		Participant:                  models.User{ID: user.ID, Name: user.Name, Nick: user.Nick},
+		LastReadMessageId:            member.lastReadMessageId,
+		ParticipantLastReadMessageId: participant.lastReadMessageId,
+		UpdatedAt:                    stored.updatedAt,
+		CreatedAt:                    stored.createdAt,
+	}
+
+	for _, message := range repository.db.messages {
+		if message.ConversationId == stored.id && message.SenderId != userId && message.ID > member.lastReadMessageId {
+			conversation.UnreadCount++
+		}
+	}
+
+	if last := repository.lastMessage(stored.id); last.ID != 0 {
+		conversation.LastMessage = &last
+	}
+
+	return conversation, true
+}
+
+// lastMessage returns the most recent message of a conversation
+func (repository MemoryMessageRepository) lastMessage(conversationId uint64) models.Message {
+
+	var last models.Message
+	for _, message := range repository.db.messages {
+		if message.ConversationId == conversationId && message.ID > last.ID {
+			last = message
+		}
+	}
+	return last
+}
+
+// newMemoryMessageRepository factory
+func newMemoryMessageRepository(db *memoryDatabase) *MemoryMessageRepository {
+	return &MemoryMessageRepository{db}
+}
@@ -0,0 +1,178 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"sort"
+	"time"
+)
+
+// MemoryNotificationRepository keeps notifications in memory, with the same semantics as NotificationRepository
+type MemoryNotificationRepository struct {
+	db *memoryDatabase
+}
+
+// notificationGroupKey identifies the group of a notification
+type notificationGroupKey struct {
+	notificationType models.NotificationType
+	publicationId    uint64
+}
+
+// CreateNotification registers a notification unless the same actor already notified the user of
+// the same thing, marking it unread again then, returning its id or zero when it was still unread
+func (repository MemoryNotificationRepository) CreateNotification(notification models.Notification) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, userFound := repository.db.users[notification.UserId]
+	_, actorFound := repository.db.users[notification.ActorId]
+	_, publicationFound := repository.db.publications[notification.PublicationId]
+	_, commentFound := repository.db.comments[notification.CommentId]
+	if !userFound || !actorFound || notification.PublicationId != 0 && !publicationFound ||
+		notification.CommentId != 0 && !commentFound {
+		return 0, errForeignKeyConstraint
+	}
+
+	for id, existing := range repository.db.notifications {
+		if existing.AggregationKey() != notification.AggregationKey() {
+			continue
+		}
+		if existing.ReadAt == nil {
+			return 0, nil
+		}
+		existing.ReadAt = nil
+		repository.db.notifications[id] = existing
+		return id, nil
+	}
+
+	notification.ID = repository.db.nextId()
+	notification.ReadAt = nil
+	notification.CreatedAt = time.Now()
+	repository.db.notifications[notification.ID] = notification
+
+	return notification.ID, nil
+}
+
+// GetNotificationGroups gets a page of a user's notifications grouped by type and publication,
+// most recently notified first
+func (repository MemoryNotificationRepository) GetNotificationGroups(userId uint64, page models.PageRequest) ([]models.NotificationGroup, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	grouped := make(map[notificationGroupKey][]models.Notification)
+	for _, notification := range repository.db.notifications {
+		if notification.UserId == userId {
+			key := notificationGroupKey{notification.Type, notification.PublicationId}
+			grouped[key] = append(grouped[key], notification)
+		}
+	}
+
+	before := beforeId(page)
+	var groups []models.NotificationGroup
+	for key, notifications := range grouped {
+		sort.Slice(notifications, func(i, j int) bool {
+			return notifications[i].ID > notifications[j].ID
+		})
+
+		if notifications[0].ID >= before {
+			continue
+		}
+
+		group := models.NotificationGroup{
+			ID:            notifications[0].ID,
+			Type:          key.notificationType,
+			PublicationId: key.publicationId,
+			UpdatedAt:     notifications[0].CreatedAt,
+		}
+
+		actors := make(map[uint64]bool)
+		for _, notification := range notifications {
+			if notification.ReadAt == nil {
+				group.UnreadCount++
+			}
+			if actors[notification.ActorId] {
+				continue
+			}
+			actors[notification.ActorId] = true
+			group.ActorCount++
+			if len(group.Actors) < models.MaxNotificationGroupActors {
+				actor := repository.db.users[notification.ActorId]
+				group.Actors = append(group.Actors, models.User{ID: actor.ID, Name: actor.Name, Nick: actor.Nick})
+			}
+		}
+
+		group.Summarize()
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID > groups[j].ID
+	})
+
+	if len(groups) > page.Limit+1 {
+		groups = groups[:page.Limit+1]
+	}
+
+	return groups, nil
+}
+
+// CountUnreadNotifications counts a user's unread notifications
+func (repository MemoryNotificationRepository) CountUnreadNotifications(userId uint64) (uint64, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var count uint64
+	for _, notification := range repository.db.notifications {
+		if notification.UserId == userId && notification.ReadAt == nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// MarkNotificationGroupRead marks as read the notifications of a user of the same type and
+// publication as the notification of the given id, telling if it existed
+func (repository MemoryNotificationRepository) MarkNotificationGroupRead(userId uint64, id uint64) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	marked, found := repository.db.notifications[id]
+	if !found || marked.UserId != userId {
+		return false, nil
+	}
+
+	repository.markRead(func(notification models.Notification) bool {
+		return notification.UserId == userId && notification.Type == marked.Type &&
+			notification.PublicationId == marked.PublicationId
+	})
+
+	return true, nil
+}
+
+// MarkAllNotificationsRead marks every notification of a user as read
+func (repository MemoryNotificationRepository) MarkAllNotificationsRead(userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.markRead(func(notification models.Notification) bool {
+		return notification.UserId == userId
+	})
+
+	return nil
+}
+
+// markRead marks the unread notifications selected by matches as read
+func (repository MemoryNotificationRepository) markRead(matches func(models.Notification) bool) {
+	now := time.Now()
+	for id, notification := range repository.db.notifications {
+		if notification.ReadAt == nil && matches(notification) {
+			notification.ReadAt = &now
+			repository.db.notifications[id] = notification
+		}
+	}
+}
+
+// newMemoryNotificationRepository factory
+func newMemoryNotificationRepository(db *memoryDatabase) *MemoryNotificationRepository {
+	return &MemoryNotificationRepository{db}
+}
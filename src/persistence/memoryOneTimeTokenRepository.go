@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"time"
+)
+
+// MemoryOneTimeTokenRepository keeps single use tokens in memory, with the same semantics as OneTimeTokenRepository
+type MemoryOneTimeTokenRepository struct {
+	db *memoryDatabase
+}
+
+// CreateOneTimeToken stores a new single use token
+func (repository MemoryOneTimeTokenRepository) CreateOneTimeToken(token models.OneTimeToken) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if _, found := repository.db.users[token.UserId]; !found {
+		return 0, errForeignKeyConstraint
+	}
+
+	for _, stored := range repository.db.oneTimeTokens {
+		if stored.TokenHash == token.TokenHash {
+			return 0, duplicateEntryError(token.TokenHash, "token_hash")
+		}
+	}
+
+	token.ID = repository.db.nextId()
+	token.UsedAt = nil
+	token.CreatedAt = time.Now()
+	repository.db.oneTimeTokens[token.ID] = token
+
+	return token.ID, nil
+}
+
+// GetOneTimeToken gets a token of a purpose by its hash
+func (repository MemoryOneTimeTokenRepository) GetOneTimeToken(purpose string, tokenHash string) (models.OneTimeToken, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	for _, token := range repository.db.oneTimeTokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return models.OneTimeToken{}, nil
+}
+
+// UseOneTimeToken marks a token as used, telling whether it was still unused
+func (repository MemoryOneTimeTokenRepository) UseOneTimeToken(id uint64) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	token, found := repository.db.oneTimeTokens[id]
+	if !found || token.UsedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	repository.db.oneTimeTokens[id] = token
+
+	return true, nil
+}
+
+// DeleteOneTimeTokens deletes the tokens of a purpose issued to a user
+func (repository MemoryOneTimeTokenRepository) DeleteOneTimeTokens(userId uint64, purpose string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	for id, token := range repository.db.oneTimeTokens {
+		if token.UserId == userId && token.Purpose == purpose {
+			delete(repository.db.oneTimeTokens, id)
+		}
+	}
+
+	return nil
+}
+
+// newMemoryOneTimeTokenRepository factory
+func newMemoryOneTimeTokenRepository(db *memoryDatabase) *MemoryOneTimeTokenRepository {
+	return &MemoryOneTimeTokenRepository{db}
+}
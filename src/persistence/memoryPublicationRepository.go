@@ -0,0 +1,422 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MemoryPublicationRepository keeps publication data in memory, with the same semantics as PublicationRepository
+type MemoryPublicationRepository struct {
+	db *memoryDatabase
+}
+
+// CreatePublication creates a new publication along with its tags and the mentions of existing users
+func (repository MemoryPublicationRepository) CreatePublication(publication models.Publication) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if _, found := repository.db.users[publication.AuthorId]; !found {
+		return 0, errForeignKeyConstraint
+	}
+
+	publication.ID = repository.db.nextId()
+	publication.CreatedAt = time.Now()
+	repository.db.publications[publication.ID] = models.Publication{
+		ID:                  publication.ID,
+		Title:               publication.Title,
+		Content:             publication.Content,
+		AuthorId:            publication.AuthorId,
+		Visibility:          publication.Visibility,
+		Tags:                publication.Tags,
+		Mentions:            repository.resolveMentions(publication.Mentions),
+		QuotedPublicationId: publication.QuotedPublicationId,
+		CreatedAt:           publication.CreatedAt,
+	}
+
+	return publication.ID, nil
+}
+
+// GetPublicationById gets a specific publication by its id, if visible to viewerId
+func (repository MemoryPublicationRepository) GetPublicationById(id uint64, viewerId uint64) (models.Publication, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	publication, found := repository.db.publications[id]
+	if !found || !repository.visible(publication, viewerId) {
+		return models.Publication{}, nil
+	}
+
+	return repository.view(publication, viewerId), nil
+}
+
+// GetPublicationAuthorId gets the author of a publication whatever its visibility, zero when it
+// does not exist
+func (repository MemoryPublicationRepository) GetPublicationAuthorId(id uint64) (uint64, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.publications[id].AuthorId, nil
+}
+
+// GetPublicationsForUserId gets a page of publications of a user and the users he/she follows,
+// along with their reposts, visible to the user, most recent activity first, leaving out
+// publications and reposts of users the user blocks or mutes and of users blocking the user
+func (repository MemoryPublicationRepository) GetPublicationsForUserId(userId uint64, page models.PageRequest) ([]models.Publication, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	followed := func(authorId uint64) bool {
+		return (authorId == userId || repository.db.followers[follow{authorId, userId}]) &&
+			!repository.db.silencedBy(authorId, userId)
+	}
+
+	var publications []models.Publication
+	for _, publication := range repository.db.publications {
+		if followed(publication.AuthorId) && repository.visible(publication, userId) {
+			publications = append(publications, repository.view(publication, userId))
+		}
+	}
+
+	for _, repost := range repository.db.reposts {
+		reposted := repository.db.publications[repost.publicationId]
+		if followed(repost.userId) && repository.visible(reposted, userId) &&
+			!repository.db.silencedBy(reposted.AuthorId, userId) {
+			publication := repository.view(reposted, userId)
+			repostedAt := repost.createdAt
+			publication.RepostedById = repost.userId
+			publication.RepostedBy = repository.db.users[repost.userId].Nick
+			publication.RepostedAt = &repostedAt
+			publications = append(publications, publication)
+		}
+	}
+
+	sort.Slice(publications, func(i, j int) bool {
+		return cursorBefore(publications[j].FeedCursor(), publications[i].FeedCursor())
+	})
+
+	var paged []models.Publication
+	for _, publication := range publications {
+		if (page.After == nil || cursorBefore(publication.FeedCursor(), *page.After)) && len(paged) <= page.Limit {
+			paged = append(paged, publication)
+		}
+	}
+
+	return paged, nil
+}
+
+// UpdatePublication updates a publication along with its tags and the mentions of existing users
+func (repository MemoryPublicationRepository) UpdatePublication(id uint64, publication models.Publication) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if stored, found := repository.db.publications[id]; found {
+		stored.Title = publication.Title
+		stored.Content = publication.Content
+		stored.Visibility = publication.Visibility
+		stored.Tags = publication.Tags
+		stored.Mentions = repository.resolveMentions(publication.Mentions)
+		repository.db.publications[id] = stored
+	}
+
+	return nil
+}
+
+// DeletePublication deletes a publication
+func (repository MemoryPublicationRepository) DeletePublication(id uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.db.deletePublication(id)
+
+	return nil
+}
+
+// GetUserPublicationById get a page of publications of a specific user visible to viewerId,
+// most recent first
+func (repository MemoryPublicationRepository) GetUserPublicationById(userId uint64, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	before := beforeId(page)
+	publications := repository.list(viewerId, func(publication models.Publication) bool {
+		return publication.AuthorId == userId && publication.ID < before && repository.visible(publication, viewerId)
+	})
+
+	if len(publications) > page.Limit+1 {
+		publications = publications[:page.Limit+1]
+	}
+
+	return publications, nil
+}
+
+// GetTagPublications gets a page of publications with a tag visible to viewerId, most recent
+// first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+func (repository MemoryPublicationRepository) GetTagPublications(tag string, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	before := beforeId(page)
+	publications := repository.list(viewerId, func(publication models.Publication) bool {
+		return publication.ID < before && hasTag(publication, tag) && repository.visible(publication, viewerId) &&
+			!repository.db.silencedBy(publication.AuthorId, viewerId)
+	})
+
+	if len(publications) > page.Limit+1 {
+		publications = publications[:page.Limit+1]
+	}
+
+	return publications, nil
+}
+
+// GetMentionPublications gets a page of publications mentioning a user visible to viewerId, most
+// recent first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+func (repository MemoryPublicationRepository) GetMentionPublications(userId uint64, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	before := beforeId(page)
+	publications := repository.list(viewerId, func(publication models.Publication) bool {
+		return publication.ID < before && mentions(publication, userId) && repository.visible(publication, viewerId) &&
+			!repository.db.silencedBy(publication.AuthorId, viewerId)
+	})
+
+	if len(publications) > page.Limit+1 {
+		publications = publications[:page.Limit+1]
+	}
+
+	return publications, nil
+}
+
+// GetTrendingTags ranks tags of public publications by the growth of their uses in the last
+// window over the window before
+func (repository MemoryPublicationRepository) GetTrendingTags(window time.Duration, limit int) ([]models.TrendingTag, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	windowStart := time.Now().Add(-window)
+	previousWindowStart := windowStart.Add(-window)
+
+	uses := make(map[string]uint64)
+	previousUses := make(map[string]uint64)
+	for _, publication := range repository.db.publications {
+		if publication.Visibility != models.VisibilityPublic || publication.CreatedAt.Before(previousWindowStart) {
+			continue
+		}
+		for _, tag := range publication.Tags {
+			if publication.CreatedAt.Before(windowStart) {
+				previousUses[tag]++
+			} else {
+				uses[tag]++
+			}
+		}
+	}
+
+	var tags []models.TrendingTag
+	for tag, count := range uses {
+		tags = append(tags, models.NewTrendingTag(tag, count, previousUses[tag], window))
+	}
+
+	models.SortTrendingTags(tags)
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
+
+// RegisterPublicationLike registers a user's like in publication, once per user
+func (repository MemoryPublicationRepository) RegisterPublicationLike(id uint64, userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, publicationFound := repository.db.publications[id]
+	_, userFound := repository.db.users[userId]
+	if !publicationFound || !userFound {
+		return nil
+	}
+
+	for _, like := range repository.db.likes {
+		if like.publicationId == id && like.userId == userId {
+			return nil
+		}
+	}
+
+	repository.db.likes = append(repository.db.likes, publicationLike{id, userId, time.Now()})
+
+	return nil
+}
+
+// RegisterPublicationUnlike removes a user's like from publication
+func (repository MemoryPublicationRepository) RegisterPublicationUnlike(id uint64, userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.db.likes = filterLikes(repository.db.likes, func(like publicationLike) bool {
+		return like.publicationId != id || like.userId != userId
+	})
+
+	return nil
+}
+
+// GetPublicationLikes searches a page of users who liked a publication, by id, leaving out users
+// blocked or muted by viewerId and users blocking it
+func (repository MemoryPublicationRepository) GetPublicationLikes(id uint64, viewerId uint64, page models.PageRequest) ([]models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var users []models.User
+	for _, like := range repository.db.likes {
+		if like.publicationId == id && !repository.db.silencedBy(like.userId, viewerId) {
+			users = append(users, publicUser(repository.db.users[like.userId]))
+		}
+	}
+	return pageUsers(users, page), nil
+}
+
+// RegisterRepost registers a user's repost of a publication, once per user
+func (repository MemoryPublicationRepository) RegisterRepost(id uint64, userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, publicationFound := repository.db.publications[id]
+	_, userFound := repository.db.users[userId]
+	if !publicationFound || !userFound {
+		return nil
+	}
+
+	for _, repost := range repository.db.reposts {
+		if repost.publicationId == id && repost.userId == userId {
+			return nil
+		}
+	}
+
+	repository.db.reposts = append(repository.db.reposts, repost{id, userId, time.Now()})
+
+	return nil
+}
+
+// RemoveRepost removes a user's repost of a publication
+func (repository MemoryPublicationRepository) RemoveRepost(id uint64, userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.db.reposts = filterReposts(repository.db.reposts, func(repost repost) bool {
+		return repost.publicationId != id || repost.userId != userId
+	})
+
+	return nil
+}
+
+// view fills the author and like columns of a publication, as seen by viewerId
+func (repository MemoryPublicationRepository) view(publication models.Publication, viewerId uint64) models.Publication {
+	publication.AuthorNick = repository.db.users[publication.AuthorId].Nick
+	publication.Likes = 0
+	publication.LikedByMe = false
+	publication.CommentCount = 0
+	publication.Reposts = 0
+	publication.Quoted = nil
+
+	for _, like := range repository.db.likes {
+		if like.publicationId == publication.ID {
+			publication.Likes++
+			if like.userId == viewerId {
+				publication.LikedByMe = true
+			}
+		}
+	}
+
+	for _, comment := range repository.db.comments {
+		if comment.PublicationId == publication.ID {
+			publication.CommentCount++
+		}
+	}
+
+	for _, repost := range repository.db.reposts {
+		if repost.publicationId == publication.ID {
+			publication.Reposts++
+		}
+	}
+
+	if quoted, found := repository.db.publications[publication.QuotedPublicationId]; found && repository.visible(quoted, viewerId) {
+		publication.Quoted = &models.Publication{
+			ID:         quoted.ID,
+			Title:      quoted.Title,
+			Content:    quoted.Content,
+			AuthorId:   quoted.AuthorId,
+			AuthorNick: repository.db.users[quoted.AuthorId].Nick,
+			CreatedAt:  quoted.CreatedAt,
+		}
+	}
+
+	return publication
+}
+
+// resolveMentions returns the mentions referring to existing users, with their ids
+func (repository MemoryPublicationRepository) resolveMentions(mentions []models.Mention) []models.Mention {
+	var resolved []models.Mention
+	for _, mention := range mentions {
+		for _, user := range repository.db.users {
+			if strings.EqualFold(user.Nick, mention.Nick) {
+				resolved = append(resolved, models.Mention{Offset: mention.Offset, Length: mention.Length, UserId: user.ID})
+				break
+			}
+		}
+	}
+	return resolved
+}
+
+// visible tells if a publication is visible to viewerId: public publications to anyone, followers
+// only ones to the author's followers and all of them to the author
+func (repository MemoryPublicationRepository) visible(publication models.Publication, viewerId uint64) bool {
+	switch publication.Visibility {
+	case models.VisibilityPublic:
+		return true
+	case models.VisibilityFollowers:
+		return publication.AuthorId == viewerId || repository.db.followers[follow{publication.AuthorId, viewerId}]
+	default:
+		return publication.AuthorId == viewerId
+	}
+}
+
+// list returns publications matching filter, most recent first, as seen by viewerId
+func (repository MemoryPublicationRepository) list(viewerId uint64, filter func(models.Publication) bool) []models.Publication {
+	var publications []models.Publication
+	for _, publication := range repository.db.publications {
+		if filter(publication) {
+			publications = append(publications, repository.view(publication, viewerId))
+		}
+	}
+
+	sort.Slice(publications, func(i, j int) bool {
+		return publications[i].ID > publications[j].ID
+	})
+
+	return publications
+}
+
+// hasTag tells if a publication has a tag
+func hasTag(publication models.Publication, tag string) bool {
+	for _, publicationTag := range publication.Tags {
+		if publicationTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions tells if a publication mentions a user
+func mentions(publication models.Publication, userId uint64) bool {
+	for _, mention := range publication.Mentions {
+		if mention.UserId == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// newMemoryPublicationRepository factory
+func newMemoryPublicationRepository(db *memoryDatabase) *MemoryPublicationRepository {
+	return &MemoryPublicationRepository{db}
+}
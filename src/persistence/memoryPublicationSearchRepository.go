@@ -0,0 +1,86 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MemoryPublicationSearchRepository searches publications kept in memory, scoring them by the
+// occurrences of the query terms, those in titles counting twice
+type MemoryPublicationSearchRepository struct {
+	db *memoryDatabase
+}
+
+// SearchPublications gets a page of publications matching a search visible to viewerId, most
+// relevant first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+func (repository MemoryPublicationSearchRepository) SearchPublications(search models.PublicationSearch, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	publications := MemoryPublicationRepository{repository.db}
+	terms := search.Terms()
+
+	var found []models.Publication
+	for _, publication := range repository.db.publications {
+		if !publications.visible(publication, viewerId) || repository.db.silencedBy(publication.AuthorId, viewerId) ||
+			(search.AuthorId != 0 && publication.AuthorId != search.AuthorId) ||
+			(search.Tag != "" && !hasTag(publication, search.Tag)) ||
+			(search.Since != nil && publication.CreatedAt.Before(*search.Since)) ||
+			(search.Until != nil && !publication.CreatedAt.Before(*search.Until)) {
+			continue
+		}
+
+		relevance := 2*countTerms(publication.Title, terms) + countTerms(publication.Content, terms)
+		if relevance == 0 {
+			continue
+		}
+
+		viewed := publications.view(publication, viewerId)
+		viewed.Relevance = float64(relevance)
+		found = append(found, viewed)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return searchCursorBefore(found[j].SearchCursor(), found[i].SearchCursor())
+	})
+
+	var paged []models.Publication
+	for _, publication := range found {
+		if (page.After == nil || searchCursorBefore(publication.SearchCursor(), *page.After)) && len(paged) <= page.Limit {
+			paged = append(paged, publication)
+		}
+	}
+
+	return paged, nil
+}
+
+// countTerms counts the words of text among terms
+func countTerms(text string, terms []string) int {
+	count := 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		for _, term := range terms {
+			if word == term {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// searchCursorBefore tells if first comes before second ordering cursors by score and id, the
+// reverse of the order of search results
+func searchCursorBefore(first models.Cursor, second models.Cursor) bool {
+	if first.Score != second.Score {
+		return first.Score < second.Score
+	}
+	return first.ID < second.ID
+}
+
+// newMemoryPublicationSearchRepository factory
+func newMemoryPublicationSearchRepository(db *memoryDatabase) *MemoryPublicationSearchRepository {
+	return &MemoryPublicationSearchRepository{db}
+}
@@ -0,0 +1,102 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"time"
+)
+
+// MemoryRefreshTokenRepository keeps refresh tokens in memory, with the same semantics as RefreshTokenRepository
+type MemoryRefreshTokenRepository struct {
+	db *memoryDatabase
+}
+
+// CreateRefreshToken stores a new refresh token
+func (repository MemoryRefreshTokenRepository) CreateRefreshToken(token models.RefreshToken) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if _, found := repository.db.users[token.UserId]; !found {
+		return 0, errForeignKeyConstraint
+	}
+
+	for _, stored := range repository.db.refreshTokens {
+		if stored.TokenHash == token.TokenHash {
+			return 0, duplicateEntryError(token.TokenHash, "token_hash")
+		}
+	}
+
+	token.ID = repository.db.nextId()
+	token.RevokedAt = nil
+	token.CreatedAt = time.Now()
+	repository.db.refreshTokens[token.ID] = token
+
+	return token.ID, nil
+}
+
+// GetRefreshTokenByHash gets a refresh token by its hash
+func (repository MemoryRefreshTokenRepository) GetRefreshTokenByHash(tokenHash string) (models.RefreshToken, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	for _, token := range repository.db.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return models.RefreshToken{}, nil
+}
+
+// RevokeRefreshToken revokes a token, telling whether it was still active
+func (repository MemoryRefreshTokenRepository) RevokeRefreshToken(id uint64) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	token, found := repository.db.refreshTokens[id]
+	if !found || token.RevokedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	token.RevokedAt = &now
+	repository.db.refreshTokens[id] = token
+
+	return true, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token of a family
+func (repository MemoryRefreshTokenRepository) RevokeRefreshTokenFamily(familyId string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	now := time.Now()
+	for id, token := range repository.db.refreshTokens {
+		if token.FamilyId == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			repository.db.refreshTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every token of a user
+func (repository MemoryRefreshTokenRepository) RevokeUserRefreshTokens(userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	now := time.Now()
+	for id, token := range repository.db.refreshTokens {
+		if token.UserId == userId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			repository.db.refreshTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// newMemoryRefreshTokenRepository factory
+func newMemoryRefreshTokenRepository(db *memoryDatabase) *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{db}
+}
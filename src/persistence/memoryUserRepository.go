@@ -0,0 +1,495 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"devbook/src/security"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MemoryUserRepository keeps user data in memory, with the same semantics as UserRepository
+type MemoryUserRepository struct {
+	db *memoryDatabase
+}
+
+// Create persists a new user
+func (repository MemoryUserRepository) Create(user models.User) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if error := repository.checkUniqueKeys(0, user); error != nil {
+		return 0, error
+	}
+
+	user.ID = repository.db.nextId()
+	user.EmailVerifiedAt = nil
+	user.Role = security.RoleUser
+	user.SuspendedAt = nil
+	user.IsPrivate = false
+	user.CreatedAt = time.Now()
+	repository.db.users[user.ID] = user
+
+	return user.ID, nil
+}
+
+// ListUsers searches for a page of users with name or nick corresponding to description, by id,
+// leaving out users blocked or muted by viewerId and users blocking it
+func (repository MemoryUserRepository) ListUsers(description string, viewerId uint64, page models.PageRequest) ([]models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	description = strings.ToLower(description)
+
+	var users []models.User
+	for _, user := range repository.db.users {
+		if (strings.Contains(strings.ToLower(user.Name), description) ||
+			strings.Contains(strings.ToLower(user.Nick), description)) && !repository.db.silencedBy(user.ID, viewerId) {
+			users = append(users, publicUser(user))
+		}
+	}
+	return pageUsers(users, page), nil
+}
+
+// GetUserById gets a specific user by its id
+func (repository MemoryUserRepository) GetUserById(id uint64) (models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.users[id], nil
+}
+
+// GetUserByEmail gets a specific user by its email
+func (repository MemoryUserRepository) GetUserByEmail(email string) (models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	for _, user := range repository.db.users {
+		if strings.EqualFold(user.Email, email) {
+			return models.User{
+				ID:              user.ID,
+				Name:            user.Name,
+				Nick:            user.Nick,
+				Email:           user.Email,
+				Password:        user.Password,
+				EmailVerifiedAt: user.EmailVerifiedAt,
+				Role:            user.Role,
+				SuspendedAt:     user.SuspendedAt,
+			}, nil
+		}
+	}
+
+	return models.User{}, nil
+}
+
+// GetUserPasswordById gets a user's password by id
+func (repository MemoryUserRepository) GetUserPasswordById(id uint64) (string, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.users[id].Password, nil
+}
+
+// Update updates user information, unverifying the email when it changes
+func (repository MemoryUserRepository) Update(id uint64, user models.User) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	stored, found := repository.db.users[id]
+	if !found {
+		return nil
+	}
+
+	if error := repository.checkUniqueKeys(id, user); error != nil {
+		return error
+	}
+
+	if stored.Email != user.Email {
+		stored.EmailVerifiedAt = nil
+	}
+	stored.Name = user.Name
+	stored.Nick = user.Nick
+	stored.Email = user.Email
+	repository.db.users[id] = stored
+
+	return nil
+}
+
+// Delete deletes a user with the given id
+func (repository MemoryUserRepository) Delete(id uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.db.deleteUser(id)
+
+	return nil
+}
+
+// FollowUser register a user following another user unless either blocks the other, telling if
+// it follows it, ignoring duplicates and missing users
+func (repository MemoryUserRepository) FollowUser(followedId uint64, followerId uint64) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, followedFound := repository.db.users[followedId]
+	_, followerFound := repository.db.users[followerId]
+	if followedFound && followerFound && !repository.db.blocking(followedId, followerId) {
+		repository.db.followers[follow{followedId, followerId}] = true
+	}
+
+	return repository.db.followers[follow{followedId, followerId}], nil
+}
+
+// UnfollowUser removes a register of a user following another user, or the request to follow it
+func (repository MemoryUserRepository) UnfollowUser(followedId uint64, followerId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	delete(repository.db.followers, follow{followedId, followerId})
+	delete(repository.db.followRequests, follow{followedId, followerId})
+
+	return nil
+}
+
+// IsFollower tells if a user follows another user
+func (repository MemoryUserRepository) IsFollower(followedId uint64, followerId uint64) (bool, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.followers[follow{followedId, followerId}], nil
+}
+
+// GetFollowersAmong gets those of userIds following a user
+func (repository MemoryUserRepository) GetFollowersAmong(followedId uint64, userIds []uint64) ([]uint64, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var followers []uint64
+	for _, userId := range userIds {
+		if repository.db.followers[follow{followedId, userId}] {
+			followers = append(followers, userId)
+		}
+	}
+
+	return followers, nil
+}
+
+// CreateFollowRequest registers a user's request to follow a private account, once per user,
+// unless either blocks the other, ignoring missing users
+func (repository MemoryUserRepository) CreateFollowRequest(followedId uint64, followerId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, followedFound := repository.db.users[followedId]
+	_, followerFound := repository.db.users[followerId]
+	key := follow{followedId, followerId}
+	if _, requested := repository.db.followRequests[key]; followedFound && followerFound && !requested &&
+		!repository.db.blocking(followedId, followerId) {
+		repository.db.followRequests[key] = time.Now()
+	}
+
+	return nil
+}
+
+// GetFollowRequests gets a page of pending requests to follow a user, oldest first
+func (repository MemoryUserRepository) GetFollowRequests(followedId uint64, page models.PageRequest) ([]models.FollowRequest, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var requests []models.FollowRequest
+	for key, createdAt := range repository.db.followRequests {
+		if key.userId == followedId {
+			requests = append(requests, models.FollowRequest{
+				UserId:    key.userId,
+				Follower:  publicUser(repository.db.users[key.followerId]),
+				CreatedAt: createdAt,
+			})
+		}
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return cursorBefore(requests[i].Cursor(), requests[j].Cursor())
+	})
+
+	var paged []models.FollowRequest
+	for _, request := range requests {
+		if (page.After == nil || cursorBefore(*page.After, request.Cursor())) && len(paged) <= page.Limit {
+			paged = append(paged, request)
+		}
+	}
+
+	return paged, nil
+}
+
+// ApproveFollowRequest turns a request to follow a user into a follower, telling if it existed
+// and neither user blocks the other
+func (repository MemoryUserRepository) ApproveFollowRequest(followedId uint64, followerId uint64) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	key := follow{followedId, followerId}
+	if _, found := repository.db.followRequests[key]; !found || repository.db.blocking(followedId, followerId) {
+		return false, nil
+	}
+
+	delete(repository.db.followRequests, key)
+	repository.db.followers[key] = true
+
+	return true, nil
+}
+
+// DeleteFollowRequest rejects a request to follow a user, telling if it existed
+func (repository MemoryUserRepository) DeleteFollowRequest(followedId uint64, followerId uint64) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	key := follow{followedId, followerId}
+	_, found := repository.db.followRequests[key]
+	delete(repository.db.followRequests, key)
+
+	return found, nil
+}
+
+// UpdateUserPrivacy makes a user account private or public, approving pending requests to
+// follow it when it becomes public, but those of users it blocks or is blocked by
+func (repository MemoryUserRepository) UpdateUserPrivacy(userId uint64, isPrivate bool) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	user, found := repository.db.users[userId]
+	if !found {
+		return nil
+	}
+
+	user.IsPrivate = isPrivate
+	repository.db.users[userId] = user
+
+	if !isPrivate {
+		for key := range repository.db.followRequests {
+			if key.userId != userId {
+				continue
+			}
+			delete(repository.db.followRequests, key)
+			if !repository.db.blocking(key.userId, key.followerId) {
+				repository.db.followers[key] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetFollowersForUserId searches a page of followers of a user, by id
+func (repository MemoryUserRepository) GetFollowersForUserId(followedId uint64, page models.PageRequest) ([]models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var users []models.User
+	for key := range repository.db.followers {
+		if key.userId == followedId {
+			users = append(users, publicUser(repository.db.users[key.followerId]))
+		}
+	}
+	return pageUsers(users, page), nil
+}
+
+// GetFollowedUsersForUserId searches a page of users followed by a user, by id
+func (repository MemoryUserRepository) GetFollowedUsersForUserId(followerId uint64, page models.PageRequest) ([]models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var users []models.User
+	for key := range repository.db.followers {
+		if key.followerId == followerId {
+			users = append(users, publicUser(repository.db.users[key.userId]))
+		}
+	}
+	return pageUsers(users, page), nil
+}
+
+// UpdateUserPassword updates a user's password
+func (repository MemoryUserRepository) UpdateUserPassword(userId uint64, password string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if user, found := repository.db.users[userId]; found {
+		user.Password = password
+		repository.db.users[userId] = user
+	}
+
+	return nil
+}
+
+// VerifyUserEmail marks a user's email as verified, unless it is no longer email
+func (repository MemoryUserRepository) VerifyUserEmail(userId uint64, email string) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if user, found := repository.db.users[userId]; found && user.Email == email && user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		repository.db.users[userId] = user
+	}
+
+	return nil
+}
+
+// UpdateUserRole assigns a role to a user
+func (repository MemoryUserRepository) UpdateUserRole(userId uint64, role security.Role) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if user, found := repository.db.users[userId]; found {
+		user.Role = role
+		repository.db.users[userId] = user
+	}
+
+	return nil
+}
+
+// SuspendUser suspends a user, keeping the time of a previous suspension
+func (repository MemoryUserRepository) SuspendUser(userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if user, found := repository.db.users[userId]; found && user.SuspendedAt == nil {
+		now := time.Now()
+		user.SuspendedAt = &now
+		repository.db.users[userId] = user
+	}
+
+	return nil
+}
+
+// UnsuspendUser lifts a user's suspension
+func (repository MemoryUserRepository) UnsuspendUser(userId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if user, found := repository.db.users[userId]; found {
+		user.SuspendedAt = nil
+		repository.db.users[userId] = user
+	}
+
+	return nil
+}
+
+// BlockUser registers a user blocking another user, removing follows and follow requests
+// between both users, ignoring missing users
+func (repository MemoryUserRepository) BlockUser(userId uint64, blockedId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, userFound := repository.db.users[userId]
+	_, blockedFound := repository.db.users[blockedId]
+	if !userFound || !blockedFound {
+		return nil
+	}
+
+	repository.db.blocks[block{userId, blockedId}] = true
+	for _, key := range []follow{{userId, blockedId}, {blockedId, userId}} {
+		delete(repository.db.followers, key)
+		delete(repository.db.followRequests, key)
+	}
+
+	return nil
+}
+
+// UnblockUser removes a register of a user blocking another user
+func (repository MemoryUserRepository) UnblockUser(userId uint64, blockedId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	delete(repository.db.blocks, block{userId, blockedId})
+
+	return nil
+}
+
+// IsBlocked tells if either user blocks the other
+func (repository MemoryUserRepository) IsBlocked(userId uint64, otherId uint64) (bool, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.blocks[block{userId, otherId}] || repository.db.blocks[block{otherId, userId}], nil
+}
+
+// GetBlockedUsers searches a page of users blocked by a user, by id
+func (repository MemoryUserRepository) GetBlockedUsers(userId uint64, page models.PageRequest) ([]models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var users []models.User
+	for key := range repository.db.blocks {
+		if key.userId == userId {
+			users = append(users, publicUser(repository.db.users[key.blockedId]))
+		}
+	}
+	return pageUsers(users, page), nil
+}
+
+// MuteUser registers a user muting another user, once per user, ignoring missing users
+func (repository MemoryUserRepository) MuteUser(userId uint64, mutedId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	_, userFound := repository.db.users[userId]
+	_, mutedFound := repository.db.users[mutedId]
+	if userFound && mutedFound {
+		repository.db.mutes[mute{userId, mutedId}] = true
+	}
+
+	return nil
+}
+
+// UnmuteUser removes a register of a user muting another user
+func (repository MemoryUserRepository) UnmuteUser(userId uint64, mutedId uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	delete(repository.db.mutes, mute{userId, mutedId})
+
+	return nil
+}
+
+// IsMuted tells if a user mutes another user
+func (repository MemoryUserRepository) IsMuted(userId uint64, mutedId uint64) (bool, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.mutes[mute{userId, mutedId}], nil
+}
+
+// GetMutedUsers searches a page of users muted by a user, by id
+func (repository MemoryUserRepository) GetMutedUsers(userId uint64, page models.PageRequest) ([]models.User, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var users []models.User
+	for key := range repository.db.mutes {
+		if key.userId == userId {
+			users = append(users, publicUser(repository.db.users[key.mutedId]))
+		}
+	}
+	return pageUsers(users, page), nil
+}
+
+// checkUniqueKeys enforces unique nick and email among users other than id
+func (repository MemoryUserRepository) checkUniqueKeys(id uint64, user models.User) error {
+	for _, stored := range repository.db.users {
+		if stored.ID == id {
+			continue
+		}
+		if strings.EqualFold(stored.Nick, user.Nick) {
+			return duplicateEntryError(user.Nick, "nick")
+		}
+		if strings.EqualFold(stored.Email, user.Email) {
+			return duplicateEntryError(user.Email, "email")
+		}
+	}
+	return nil
+}
+
+// newMemoryUserRepository factory
+func newMemoryUserRepository(db *memoryDatabase) *MemoryUserRepository {
+	return &MemoryUserRepository{db}
+}
@@ -0,0 +1,221 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"sort"
+	"time"
+)
+
+// MemoryWebhookRepository keeps webhooks and their deliveries in memory, with the same semantics as
+// WebhookRepository
+type MemoryWebhookRepository struct {
+	db *memoryDatabase
+}
+
+// CreateWebhook registers a webhook of a user
+func (repository MemoryWebhookRepository) CreateWebhook(webhook models.Webhook) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if _, found := repository.db.users[webhook.UserId]; !found {
+		return 0, errForeignKeyConstraint
+	}
+
+	webhook.ID = repository.db.nextId()
+	webhook.CreatedAt = time.Now()
+	repository.db.webhooks[webhook.ID] = webhook
+
+	return webhook.ID, nil
+}
+
+// GetWebhookById gets a specific webhook by its id, with its secret
+func (repository MemoryWebhookRepository) GetWebhookById(id uint64) (models.Webhook, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.webhooks[id], nil
+}
+
+// GetWebhooks gets a page of the webhooks of a user, by id
+func (repository MemoryWebhookRepository) GetWebhooks(userId uint64, page models.PageRequest) ([]models.Webhook, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	after := afterId(page)
+	var webhooks []models.Webhook
+	for _, webhook := range repository.sorted() {
+		if webhook.UserId == userId && webhook.ID > after && len(webhooks) <= page.Limit {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	return webhooks, nil
+}
+
+// GetSubscribedWebhooks gets the webhooks of a user subscribed to an event type, with their secrets
+func (repository MemoryWebhookRepository) GetSubscribedWebhooks(userId uint64, eventType string) ([]models.Webhook, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	var webhooks []models.Webhook
+	for _, webhook := range repository.sorted() {
+		if webhook.UserId == userId && webhook.Subscribes(eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook along with its deliveries
+func (repository MemoryWebhookRepository) DeleteWebhook(id uint64) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	repository.db.deleteWebhook(id)
+	return nil
+}
+
+// CreateDelivery queues a delivery to a webhook
+func (repository MemoryWebhookRepository) CreateDelivery(delivery models.WebhookDelivery) (uint64, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	if _, found := repository.db.webhooks[delivery.WebhookId]; !found {
+		return 0, errForeignKeyConstraint
+	}
+
+	delivery.ID = repository.db.nextId()
+	delivery.Attempts = 0
+	delivery.ResponseStatus = 0
+	delivery.LastError = ""
+	delivery.DeliveredAt = nil
+	delivery.CreatedAt = time.Now()
+	repository.db.deliveries[delivery.ID] = delivery
+
+	return delivery.ID, nil
+}
+
+// GetDeliveryById gets a specific delivery by its id
+func (repository MemoryWebhookRepository) GetDeliveryById(id uint64) (models.WebhookDelivery, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	return repository.db.deliveries[id], nil
+}
+
+// GetDeliveries gets a page of the deliveries to a webhook, most recent first
+func (repository MemoryWebhookRepository) GetDeliveries(webhookId uint64, page models.PageRequest) ([]models.WebhookDelivery, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	before := beforeId(page)
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range repository.db.deliveries {
+		if delivery.WebhookId == webhookId && delivery.ID < before {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID > deliveries[j].ID
+	})
+
+	if len(deliveries) > page.Limit+1 {
+		deliveries = deliveries[:page.Limit+1]
+	}
+
+	return deliveries, nil
+}
+
+// GetPendingDeliveries gets pending deliveries unclaimed at now, except those to skipped webhooks,
+// soonest next attempt first, up to limit
+func (repository MemoryWebhookRepository) GetPendingDeliveries(now time.Time, skippedWebhookIds []uint64,
+	limit int) ([]models.WebhookDelivery, error) {
+	repository.db.mutex.RLock()
+	defer repository.db.mutex.RUnlock()
+
+	skipped := make(map[uint64]bool)
+	for _, webhookId := range skippedWebhookIds {
+		skipped[webhookId] = true
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range repository.db.deliveries {
+		if delivery.Status == models.DeliveryPending && !skipped[delivery.WebhookId] &&
+			!repository.db.claimed(delivery.ID, now) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		first, second := deliveries[i].NextAttemptAt, deliveries[j].NextAttemptAt
+		if first != nil && second != nil && !first.Equal(*second) {
+			return first.Before(*second)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDelivery claims a pending delivery until a time unless it is claimed at now, telling whether
+// it was claimed
+func (repository MemoryWebhookRepository) ClaimDelivery(id uint64, now time.Time, until time.Time) (bool, error) {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	delivery, found := repository.db.deliveries[id]
+	if !found || delivery.Status != models.DeliveryPending || repository.db.claimed(id, now) {
+		return false, nil
+	}
+
+	repository.db.claims[id] = until
+	return true, nil
+}
+
+// UpdateDelivery records the outcome of an attempt of a delivery, releasing its claim
+func (repository MemoryWebhookRepository) UpdateDelivery(delivery models.WebhookDelivery) error {
+	repository.db.mutex.Lock()
+	defer repository.db.mutex.Unlock()
+
+	stored, found := repository.db.deliveries[delivery.ID]
+	if !found {
+		return nil
+	}
+
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.ResponseStatus = delivery.ResponseStatus
+	stored.LastError = delivery.LastError
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.DeliveredAt = delivery.DeliveredAt
+	repository.db.deliveries[delivery.ID] = stored
+	delete(repository.db.claims, delivery.ID)
+
+	return nil
+}
+
+// sorted returns every webhook by id
+func (repository MemoryWebhookRepository) sorted() []models.Webhook {
+
+	var webhooks []models.Webhook
+	for _, webhook := range repository.db.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+
+	return webhooks
+}
+
+// newMemoryWebhookRepository factory
+func newMemoryWebhookRepository(db *memoryDatabase) *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{db}
+}
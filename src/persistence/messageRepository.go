@@ -0,0 +1,300 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+	"fmt"
+	"math"
+)
+
+// conversationColumns are the columns read by scanConversation, the first parameter being the user
+// seeing the conversation
+const conversationColumns = `c.id, u.id, u.name, u.nick,
+		(select count(*) from messages x
+			where x.conversation_id = c.id and x.sender_id <> m.user_id and x.id > m.last_read_message_id),
+		m.last_read_message_id, o.last_read_message_id,
+		l.id, l.sender_id, l.content, l.created_at,
+		c.updated_at, c.created_at`
+
+// conversationTables are the tables read by scanConversation, the first parameter being the user
+// seeing the conversation
+const conversationTables = `conversation_members m
+		join conversations c on (c.id = m.conversation_id)
+		join conversation_members o on (o.conversation_id = c.id and o.user_id <> m.user_id)
+		join users u on (u.id = o.user_id)
+		left join messages l on (l.id = (select max(y.id) from messages y where y.conversation_id = c.id))`
+
+// MessageRepository persists direct messages between users
+type MessageRepository struct {
+	db *sql.DB
+}
+
+// GetOrCreateConversation gets the conversation between two users, starting it when there is none
+func (repository MessageRepository) GetOrCreateConversation(userId uint64, otherId uint64) (uint64, error) {
+
+	transaction, error := repository.db.Begin()
+	if error != nil {
+		return 0, error
+	}
+	defer transaction.Rollback()
+
+	key := directKey(userId, otherId)
+
+	if _, error = transaction.Exec("insert ignore into conversations (direct_key) values (?)", key); error != nil {
+		return 0, error
+	}
+
+	resultSet, error := transaction.Query("select id from conversations where direct_key = ?", key)
+	if error != nil {
+		return 0, error
+	}
+
+	var id uint64
+	if resultSet.Next() {
+		if error = resultSet.Scan(&id); error != nil {
+			resultSet.Close()
+			return 0, error
+		}
+	}
+	resultSet.Close()
+
+	if _, error = transaction.Exec(
+		"insert ignore into conversation_members (conversation_id, user_id) values (?, ?), (?, ?)",
+		id, userId, id, otherId); error != nil {
+		return 0, error
+	}
+
+	if error = transaction.Commit(); error != nil {
+		return 0, error
+	}
+
+	return id, nil
+}
+
+// GetConversation gets a specific conversation by its id as seen by one of its users
+func (repository MessageRepository) GetConversation(id uint64, userId uint64) (models.Conversation, error) {
+
+	var conversation models.Conversation
+
+	resultSet, error := repository.db.Query(
+		"select "+conversationColumns+" from "+conversationTables+" where c.id = ? and m.user_id = ?",
+		id, userId)
+	if error != nil {
+		return conversation, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if conversation, error = scanConversation(resultSet); error != nil {
+			return conversation, error
+		}
+	}
+
+	return conversation, nil
+}
+
+// GetConversations gets a page of the conversations of a user, most recently active first
+func (repository MessageRepository) GetConversations(userId uint64, page models.PageRequest) ([]models.Conversation, error) {
+
+	after := "true"
+	args := []interface{}{userId}
+	if page.After != nil {
+		after = "(c.updated_at, c.id) < (?, ?)"
+		args = append(args, page.After.Time, page.After.ID)
+	}
+	args = append(args, page.Limit+1)
+
+	resultSet, error := repository.db.Query(
+		"select "+conversationColumns+" from "+conversationTables+
+			" where m.user_id = ? and "+after+" order by c.updated_at desc, c.id desc limit ?",
+		args...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var conversations []models.Conversation
+
+	for resultSet.Next() {
+		conversation, error := scanConversation(resultSet)
+		if error != nil {
+			return nil, error
+		}
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, nil
+}
+
+// CreateMessage sends a message in a conversation, which its sender has read up to it
+func (repository MessageRepository) CreateMessage(message models.Message) (uint64, error) {
+
+	transaction, error := repository.db.Begin()
+	if error != nil {
+		return 0, error
+	}
+	defer transaction.Rollback()
+
+	insert, error := transaction.Exec(
+		"insert into messages (conversation_id, sender_id, content) values (?, ?, ?)",
+		message.ConversationId, message.SenderId, message.Content)
+	if error != nil {
+		return 0, error
+	}
+
+	id, error := insert.LastInsertId()
+	if error != nil {
+		return 0, error
+	}
+
+	if _, error = transaction.Exec(
+		"update conversations set updated_at = current_timestamp() where id = ?", message.ConversationId); error != nil {
+		return 0, error
+	}
+
+	if _, error = transaction.Exec(
+		`update conversation_members set last_read_message_id = ?, read_at = current_timestamp()
+				where conversation_id = ? and user_id = ?`,
+		id, message.ConversationId, message.SenderId); error != nil {
+		return 0, error
+	}
+
+	if error = transaction.Commit(); error != nil {
+		return 0, error
+	}
+
+	return uint64(id), nil
+}
+
+// GetMessageById gets a specific message by its id
+func (repository MessageRepository) GetMessageById(id uint64) (models.Message, error) {
+
+	var message models.Message
+
+	resultSet, error := repository.db.Query(
+		"select id, conversation_id, sender_id, content, created_at from messages where id = ?", id)
+	if error != nil {
+		return message, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if error = resultSet.Scan(&message.ID, &message.ConversationId, &message.SenderId,
+			&message.Content, &message.CreatedAt); error != nil {
+			return message, error
+		}
+	}
+
+	return message, nil
+}
+
+// GetMessages gets a page of the messages of a conversation, most recent first
+func (repository MessageRepository) GetMessages(conversationId uint64, page models.PageRequest) ([]models.Message, error) {
+
+	resultSet, error := repository.db.Query(
+		`select id, conversation_id, sender_id, content, created_at from messages
+				where conversation_id = ? and id < ? order by id desc limit ?`,
+		conversationId, beforeId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var messages []models.Message
+
+	for resultSet.Next() {
+		var message models.Message
+		if error = resultSet.Scan(&message.ID, &message.ConversationId, &message.SenderId,
+			&message.Content, &message.CreatedAt); error != nil {
+			return nil, error
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// MarkConversationRead marks a conversation read by a user up to a message, or its last message
+// when messageId is zero, never moving back the messages read before
+func (repository MessageRepository) MarkConversationRead(conversationId uint64, userId uint64, messageId uint64) (models.ReadReceipt, error) {
+
+	receipt := models.ReadReceipt{ConversationId: conversationId, UserId: userId}
+
+	if messageId == 0 {
+		messageId = math.MaxInt64
+	}
+
+	stmt, error := repository.db.Prepare(
+		`update conversation_members m set
+				m.last_read_message_id = greatest(m.last_read_message_id, least(?,
+					(select coalesce(max(x.id), 0) from messages x where x.conversation_id = m.conversation_id))),
+				m.read_at = current_timestamp()
+				where m.conversation_id = ? and m.user_id = ?`)
+	if error != nil {
+		return receipt, error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(messageId, conversationId, userId); error != nil {
+		return receipt, error
+	}
+
+	resultSet, error := repository.db.Query(
+		`select last_read_message_id, read_at from conversation_members
+				where conversation_id = ? and user_id = ?`, conversationId, userId)
+	if error != nil {
+		return receipt, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		var readAt sql.NullTime
+		if error = resultSet.Scan(&receipt.MessageId, &readAt); error != nil {
+			return receipt, error
+		}
+		receipt.ReadAt = readAt.Time
+	}
+
+	return receipt, nil
+}
+
+// directKey identifies the conversation between two users, whatever their order
+func directKey(userId uint64, otherId uint64) string {
+	if otherId < userId {
+		userId, otherId = otherId, userId
+	}
+	return fmt.Sprintf("%d:%d", userId, otherId)
+}
+
+func scanConversation(resultSet *sql.Rows) (models.Conversation, error) {
+
+	var conversation models.Conversation
+	var lastId, lastSenderId sql.NullInt64
+	var lastContent sql.NullString
+	var lastCreatedAt sql.NullTime
+
+	if error := resultSet.Scan(&conversation.ID, &conversation.Participant.ID,
+		&conversation.Participant.Name, &conversation.Participant.Nick, &conversation.UnreadCount,
+		&conversation.LastReadMessageId, &conversation.ParticipantLastReadMessageId,
+		&lastId, &lastSenderId, &lastContent, &lastCreatedAt,
+		&conversation.UpdatedAt, &conversation.CreatedAt); error != nil {
+		return conversation, error
+	}
+
+	if lastId.Valid {
+		conversation.LastMessage = &models.Message{
+			ID:             uint64(lastId.Int64),
+			ConversationId: conversation.ID,
+			SenderId:       uint64(lastSenderId.Int64),
+			Content:        lastContent.String,
+			CreatedAt:      lastCreatedAt.Time,
+		}
+	}
+
+	return conversation, nil
+}
+
+// NewMessageRepository factory
+func NewMessageRepository(db *sql.DB) *MessageRepository {
+	return &MessageRepository{db}
+}
@@ -0,0 +1,174 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+)
+
+// MFARepository persists second factor data
+type MFARepository struct {
+	db *sql.DB
+}
+
+// GetMFA gets a user's second factor
+func (repository MFARepository) GetMFA(userId uint64) (models.MFA, error) {
+
+	var mfa models.MFA
+
+	resultSet, error := repository.db.Query(
+		`select m.user_id, m.secret, m.enabled_at, m.last_used_step, m.created_at 
+				from user_mfa m where m.user_id = ?`, userId)
+	if error != nil {
+		return mfa, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		var enabledAt sql.NullTime
+		if error = resultSet.Scan(&mfa.UserId, &mfa.Secret, &enabledAt,
+			&mfa.LastUsedStep, &mfa.CreatedAt); error != nil {
+			return mfa, error
+		}
+		if enabledAt.Valid {
+			mfa.EnabledAt = &enabledAt.Time
+		}
+	}
+
+	return mfa, nil
+}
+
+// SaveMFASecret stores a new, not yet enabled, secret for a user replacing any previous one
+func (repository MFARepository) SaveMFASecret(userId uint64, secret string) error {
+
+	stmt, error := repository.db.Prepare(
+		`insert into user_mfa (user_id, secret) values (?, ?) 
+				on duplicate key update secret = values(secret), enabled_at = null, last_used_step = 0`)
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId, secret); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// EnableMFA enables a user's second factor
+func (repository MFARepository) EnableMFA(userId uint64) error {
+
+	stmt, error := repository.db.Prepare(
+		"update user_mfa set enabled_at = current_timestamp() where user_id = ? and enabled_at is null")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// DisableMFA removes a user's second factor and recovery codes
+func (repository MFARepository) DisableMFA(userId uint64) error {
+
+	transaction, error := repository.db.Begin()
+	if error != nil {
+		return error
+	}
+	defer transaction.Rollback()
+
+	if _, error = transaction.Exec("delete from mfa_recovery_codes where user_id = ?", userId); error != nil {
+		return error
+	}
+
+	if _, error = transaction.Exec("delete from user_mfa where user_id = ?", userId); error != nil {
+		return error
+	}
+
+	return transaction.Commit()
+}
+
+// UseMFAStep records a time step as used, telling whether it is newer than the last used one
+func (repository MFARepository) UseMFAStep(userId uint64, step uint64) (bool, error) {
+
+	stmt, error := repository.db.Prepare(
+		"update user_mfa set last_used_step = ? where user_id = ? and last_used_step < ?")
+	if error != nil {
+		return false, error
+	}
+	defer stmt.Close()
+
+	update, error := stmt.Exec(step, userId, step)
+	if error != nil {
+		return false, error
+	}
+
+	rows, error := update.RowsAffected()
+	if error != nil {
+		return false, error
+	}
+
+	return rows == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces a user's recovery codes with the given hashes
+func (repository MFARepository) ReplaceRecoveryCodes(userId uint64, codeHashes []string) error {
+
+	transaction, error := repository.db.Begin()
+	if error != nil {
+		return error
+	}
+	defer transaction.Rollback()
+
+	if _, error = transaction.Exec("delete from mfa_recovery_codes where user_id = ?", userId); error != nil {
+		return error
+	}
+
+	stmt, error := transaction.Prepare(
+		"insert into mfa_recovery_codes (user_id, code_hash) values (?, ?)")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	for _, codeHash := range codeHashes {
+		if _, error = stmt.Exec(userId, codeHash); error != nil {
+			return error
+		}
+	}
+
+	return transaction.Commit()
+}
+
+// UseRecoveryCode marks a recovery code as used, telling whether it was valid and unused
+func (repository MFARepository) UseRecoveryCode(userId uint64, codeHash string) (bool, error) {
+
+	stmt, error := repository.db.Prepare(
+		`update mfa_recovery_codes set used_at = current_timestamp() 
+				where user_id = ? and code_hash = ? and used_at is null`)
+	if error != nil {
+		return false, error
+	}
+	defer stmt.Close()
+
+	update, error := stmt.Exec(userId, codeHash)
+	if error != nil {
+		return false, error
+	}
+
+	rows, error := update.RowsAffected()
+	if error != nil {
+		return false, error
+	}
+
+	return rows == 1, nil
+}
+
+// NewMFARepository factory
+func NewMFARepository(db *sql.DB) *MFARepository {
+	return &MFARepository{db}
+}
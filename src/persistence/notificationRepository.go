@@ -0,0 +1,209 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+)
+
+// NotificationRepository persists notifications of users
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+// CreateNotification registers a notification unless the same actor already notified the user of
+// the same thing, marking it unread again then, returning its id or zero when it was still unread
+func (repository NotificationRepository) CreateNotification(notification models.Notification) (uint64, error) {
+
+	publicationId := nullableId(notification.PublicationId)
+	commentId := nullableId(notification.CommentId)
+
+	stmt, error := repository.db.Prepare(
+		`insert into notifications (user_id, type, actor_id, publication_id, comment_id, aggregation_key)
+				values (?, ?, ?, ?, ?, ?) on duplicate key update id = last_insert_id(id), read_at = null`)
+	if error != nil {
+		return 0, error
+	}
+	defer stmt.Close()
+
+	insert, error := stmt.Exec(notification.UserId, notification.Type, notification.ActorId,
+		publicationId, commentId, notification.AggregationKey())
+	if error != nil {
+		return 0, error
+	}
+
+	inserted, error := insert.RowsAffected()
+	if error != nil || inserted == 0 {
+		return 0, error
+	}
+
+	id, error := insert.LastInsertId()
+	if error != nil {
+		return 0, error
+	}
+
+	return uint64(id), nil
+}
+
+// GetNotificationGroups gets a page of a user's notifications grouped by type and publication,
+// most recently notified first, walking the latest notification of each group by id
+func (repository NotificationRepository) GetNotificationGroups(userId uint64, page models.PageRequest) ([]models.NotificationGroup, error) {
+
+	resultSet, error := repository.db.Query(
+		`select n.id, n.type, n.publication_id,
+				(select count(distinct g.actor_id) from notifications g
+					where g.user_id = n.user_id and g.type = n.type and g.publication_id <=> n.publication_id),
+				(select count(*) from notifications g
+					where g.user_id = n.user_id and g.type = n.type and g.publication_id <=> n.publication_id
+						and g.read_at is null),
+				n.created_at
+				from notifications n
+				where n.user_id = ? and n.id < ? and not exists (
+					select 1 from notifications later
+						where later.user_id = n.user_id and later.type = n.type
+							and later.publication_id <=> n.publication_id and later.id > n.id)
+				order by n.id desc
+				limit ?`, userId, beforeId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var groups []models.NotificationGroup
+
+	for resultSet.Next() {
+		var group models.NotificationGroup
+		var publicationId sql.NullInt64
+
+		if error = resultSet.Scan(&group.ID, &group.Type, &publicationId, &group.ActorCount,
+			&group.UnreadCount, &group.UpdatedAt); error != nil {
+			return nil, error
+		}
+
+		group.PublicationId = uint64(publicationId.Int64)
+		groups = append(groups, group)
+	}
+
+	if error = resultSet.Err(); error != nil {
+		return nil, error
+	}
+
+	for index := range groups {
+		if groups[index].Actors, error = repository.getGroupActors(userId, groups[index]); error != nil {
+			return nil, error
+		}
+		groups[index].Summarize()
+	}
+
+	return groups, nil
+}
+
+// getGroupActors gets the most recent actors of a group of notifications
+func (repository NotificationRepository) getGroupActors(userId uint64, group models.NotificationGroup) ([]models.User, error) {
+
+	resultSet, error := repository.db.Query(
+		`select u.id, u.name, u.nick
+				from notifications n
+				join users u on (n.actor_id = u.id)
+				where n.user_id = ? and n.type = ? and n.publication_id <=> ?
+				group by u.id, u.name, u.nick
+				order by max(n.id) desc
+				limit ?`, userId, group.Type, nullableId(group.PublicationId), models.MaxNotificationGroupActors)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var actors []models.User
+
+	for resultSet.Next() {
+		var actor models.User
+		if error = resultSet.Scan(&actor.ID, &actor.Name, &actor.Nick); error != nil {
+			return nil, error
+		}
+		actors = append(actors, actor)
+	}
+
+	return actors, resultSet.Err()
+}
+
+// CountUnreadNotifications counts a user's unread notifications
+func (repository NotificationRepository) CountUnreadNotifications(userId uint64) (uint64, error) {
+
+	var count uint64
+
+	resultSet, error := repository.db.Query(
+		"select count(*) from notifications where user_id = ? and read_at is null", userId)
+	if error != nil {
+		return 0, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if error = resultSet.Scan(&count); error != nil {
+			return 0, error
+		}
+	}
+
+	return count, nil
+}
+
+// MarkNotificationGroupRead marks as read the notifications of a user of the same type and
+// publication as the notification of the given id, telling if it existed
+func (repository NotificationRepository) MarkNotificationGroupRead(userId uint64, id uint64) (bool, error) {
+
+	var notificationType string
+	var publicationId sql.NullInt64
+
+	resultSet, error := repository.db.Query(
+		"select type, publication_id from notifications where id = ? and user_id = ?", id, userId)
+	if error != nil {
+		return false, error
+	}
+	defer resultSet.Close()
+
+	if !resultSet.Next() {
+		return false, resultSet.Err()
+	}
+
+	if error = resultSet.Scan(&notificationType, &publicationId); error != nil {
+		return false, error
+	}
+
+	stmt, error := repository.db.Prepare(
+		`update notifications set read_at = current_timestamp()
+				where user_id = ? and type = ? and publication_id <=> ? and read_at is null`)
+	if error != nil {
+		return false, error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId, notificationType, publicationId); error != nil {
+		return false, error
+	}
+
+	return true, nil
+}
+
+// MarkAllNotificationsRead marks every notification of a user as read
+func (repository NotificationRepository) MarkAllNotificationsRead(userId uint64) error {
+
+	stmt, error := repository.db.Prepare(
+		"update notifications set read_at = current_timestamp() where user_id = ? and read_at is null")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	_, error = stmt.Exec(userId)
+	return error
+}
+
+// nullableId returns an optional reference to a row, null when id is zero
+func nullableId(id uint64) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// NewNotificationRepository factory
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db}
+}
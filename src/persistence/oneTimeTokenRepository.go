@@ -0,0 +1,105 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+)
+
+// OneTimeTokenRepository persists single use token data
+type OneTimeTokenRepository struct {
+	db *sql.DB
+}
+
+// CreateOneTimeToken stores a new single use token
+func (repository OneTimeTokenRepository) CreateOneTimeToken(token models.OneTimeToken) (uint64, error) {
+
+	stmt, error := repository.db.Prepare(
+		"insert into one_time_tokens (user_id, purpose, email, token_hash, expires_at) values (?, ?, ?, ?, ?)")
+	if error != nil {
+		return 0, error
+	}
+	defer stmt.Close()
+
+	insert, error := stmt.Exec(token.UserId, token.Purpose, token.Email, token.TokenHash, token.ExpiresAt)
+	if error != nil {
+		return 0, error
+	}
+
+	id, error := insert.LastInsertId()
+	if error != nil {
+		return 0, error
+	}
+
+	return uint64(id), nil
+}
+
+// GetOneTimeToken gets a token of a purpose by its hash
+func (repository OneTimeTokenRepository) GetOneTimeToken(purpose string, tokenHash string) (models.OneTimeToken, error) {
+
+	var token models.OneTimeToken
+
+	resultSet, error := repository.db.Query(
+		`select t.id, t.user_id, t.purpose, coalesce(t.email, ''), t.token_hash, t.expires_at, t.used_at, t.created_at
+				from one_time_tokens t where t.purpose = ? and t.token_hash = ?`, purpose, tokenHash)
+	if error != nil {
+		return token, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		var usedAt sql.NullTime
+		if error = resultSet.Scan(&token.ID, &token.UserId, &token.Purpose, &token.Email, &token.TokenHash,
+			&token.ExpiresAt, &usedAt, &token.CreatedAt); error != nil {
+			return token, error
+		}
+		if usedAt.Valid {
+			token.UsedAt = &usedAt.Time
+		}
+	}
+
+	return token, nil
+}
+
+// UseOneTimeToken marks a token as used, telling whether it was still unused
+func (repository OneTimeTokenRepository) UseOneTimeToken(id uint64) (bool, error) {
+
+	stmt, error := repository.db.Prepare(
+		"update one_time_tokens set used_at = current_timestamp() where id = ? and used_at is null")
+	if error != nil {
+		return false, error
+	}
+	defer stmt.Close()
+
+	update, error := stmt.Exec(id)
+	if error != nil {
+		return false, error
+	}
+
+	rows, error := update.RowsAffected()
+	if error != nil {
+		return false, error
+	}
+
+	return rows == 1, nil
+}
+
+// DeleteOneTimeTokens deletes the tokens of a purpose issued to a user
+func (repository OneTimeTokenRepository) DeleteOneTimeTokens(userId uint64, purpose string) error {
+
+	stmt, error := repository.db.Prepare("delete from one_time_tokens where user_id = ? and purpose = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId, purpose); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// NewOneTimeTokenRepository factory
+func NewOneTimeTokenRepository(db *sql.DB) *OneTimeTokenRepository {
+	return &OneTimeTokenRepository{db}
+}
@@ -0,0 +1,34 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"math"
+)
+
+// afterId returns the id listings ordered by ascending id start after
+func afterId(page models.PageRequest) uint64 {
+	if page.After == nil {
+		return 0
+	}
+	return page.After.ID
+}
+
+// beforeId returns the id listings ordered by descending id start before
+func beforeId(page models.PageRequest) uint64 {
+	if page.After == nil {
+		return math.MaxInt64
+	}
+	return page.After.ID
+}
+
+// cursorBefore tells if first comes before second ordering cursors by time, id and tie, the
+// reverse of the order of listings with most recent items first
+func cursorBefore(first models.Cursor, second models.Cursor) bool {
+	if !first.Time.Equal(second.Time) {
+		return first.Time.Before(second.Time)
+	}
+	if first.ID != second.ID {
+		return first.ID < second.ID
+	}
+	return first.Tie < second.Tie
+}
@@ -3,6 +3,9 @@ package persistence
 import (
 	"database/sql"
 	"devbook/src/models"
+	"fmt"
+	"strings"
+	"time"
 )
 
 // PublicationRepository persists publication data
@@ -10,17 +13,60 @@ type PublicationRepository struct {
 	db *sql.DB
 }
 
-// CreatePublication creates a new publication
+// publicationColumns selects publication p by author u quoting publication q by author qu, as
+// seen by the viewer bound to its single parameter
+const publicationColumns = `p.id, p.title, p.content, p.author_id, u.nick, p.visibility,
+		(select group_concat(t.name order by t.name separator ' ') 
+			from publication_tags pt join tags t on (t.id = pt.tag_id) where pt.publication_id = p.id),
+		(select group_concat(concat(m.mention_offset, ':', m.mention_length, ':', m.user_id) 
+				order by m.mention_offset separator ' ') 
+			from publication_mentions m where m.publication_id = p.id),
+		(select count(*) from publication_likes l where l.publication_id = p.id),
+		exists(select 1 from publication_likes l where l.publication_id = p.id and l.user_id = ?),
+		(select count(*) from comments c where c.publication_id = p.id),
+		(select count(*) from reposts r where r.publication_id = p.id),
+		p.quoted_publication_id, q.id, q.title, q.content, q.author_id, qu.nick, q.created_at,
+		p.created_at`
+
+// publicationTables joins the tables read by publicationColumns, the quoted publication being
+// joined when visible to the viewer bound to its two parameters
+var publicationTables = `publications p
+		join users u on (p.author_id = u.id)
+		left join publications q on (p.quoted_publication_id = q.id and ` + visibleTo("q") + `)
+		left join users qu on (q.author_id = qu.id)`
+
+// visibleTo tells if the publication aliased as table is visible to the viewer bound to its two
+// parameters: public publications to anyone, followers only ones to the author's followers and all
+// of them to the author
+func visibleTo(table string) string {
+	return fmt.Sprintf(`(%[1]s.visibility = 'public' or %[1]s.author_id = ? or (%[1]s.visibility = 'followers' and
+		exists(select 1 from followers vf where vf.user_id = %[1]s.author_id and vf.follower_id = ?)))`, table)
+}
+
+// viewerArgs binds viewerId to the parameters of publicationColumns and publicationTables,
+// followed by args
+func viewerArgs(viewerId uint64, args ...interface{}) []interface{} {
+	return append([]interface{}{viewerId, viewerId, viewerId}, args...)
+}
+
+// CreatePublication creates a new publication along with its tags and the mentions of existing users
 func (repository PublicationRepository) CreatePublication(publication models.Publication) (uint64, error) {
 
-	stmt, error := repository.db.Prepare(
-		"insert into publications (title, content, author_id) values (?, ?, ?)")
+	transaction, error := repository.db.Begin()
 	if error != nil {
 		return 0, error
 	}
-	defer stmt.Close()
+	defer transaction.Rollback()
 
-	insert, error := stmt.Exec(publication.Title, publication.Content, publication.AuthorId)
+	var quotedPublicationId sql.NullInt64
+	if publication.QuotedPublicationId != 0 {
+		quotedPublicationId = sql.NullInt64{Int64: int64(publication.QuotedPublicationId), Valid: true}
+	}
+
+	insert, error := transaction.Exec(
+		"insert into publications (title, content, author_id, visibility, quoted_publication_id) values (?, ?, ?, ?, ?)",
+		publication.Title, publication.Content, publication.AuthorId,
+		string(publication.Visibility), quotedPublicationId)
 	if error != nil {
 		return 0, error
 	}
@@ -30,19 +76,25 @@ func (repository PublicationRepository) CreatePublication(publication models.Pub
 		return 0, error
 	}
 
-	return uint64(id), nil;
+	if error = savePublicationTags(transaction, uint64(id), publication.Tags); error != nil {
+		return 0, error
+	}
+
+	if error = savePublicationMentions(transaction, uint64(id), publication.Mentions); error != nil {
+		return 0, error
+	}
+
+	return uint64(id), transaction.Commit()
 }
 
-// GetPublicationById gets a specific publication by its id
-func (repository PublicationRepository) GetPublicationById(id uint64) (models.Publication, error) {
+// GetPublicationById gets a specific publication by its id, if visible to viewerId
+func (repository PublicationRepository) GetPublicationById(id uint64, viewerId uint64) (models.Publication, error) {
 
 	var publication models.Publication
 
 	resultSet, error := repository.db.Query(
-		`select p.id, p.title, p.content, p.author_id, u.nick, p.likes, p.created_at 
-				from publications p 
-				join users u on (p.author_id = u.id) 
-				where p.id = ?`, id)
+		"select "+publicationColumns+" from "+publicationTables+" where p.id = ? and "+visibleTo("p"),
+		viewerArgs(viewerId, id, viewerId, viewerId)...)
 
 	if error != nil {
 		return publication, error
@@ -50,11 +102,7 @@ func (repository PublicationRepository) GetPublicationById(id uint64) (models.Pu
 	defer resultSet.Close()
 
 	if resultSet.Next() {
-		if error = resultSet.Scan(
-			&publication.ID, &publication.Title,
-			&publication.Content, &publication.AuthorId,
-			&publication.AuthorNick, &publication.Likes,
-			&publication.CreatedAt,); error != nil {
+		if publication, error = scanPublication(resultSet); error != nil {
 			return publication, error
 		}
 	}
@@ -62,15 +110,68 @@ func (repository PublicationRepository) GetPublicationById(id uint64) (models.Pu
 	return publication, nil
 }
 
-// GetPublicationsForUserId gets publications of a user and the users he/she follows
-func (repository PublicationRepository) GetPublicationsForUserId(userId uint64) ([]models.Publication, error) {
+// GetPublicationAuthorId gets the author of a publication whatever its visibility, zero when it
+// does not exist
+func (repository PublicationRepository) GetPublicationAuthorId(id uint64) (uint64, error) {
+
+	var authorId uint64
+
+	resultSet, error := repository.db.Query("select author_id from publications where id = ?", id)
+	if error != nil {
+		return 0, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if error = resultSet.Scan(&authorId); error != nil {
+			return 0, error
+		}
+	}
+
+	return authorId, nil
+}
+
+// GetPublicationsForUserId gets a page of publications of a user and the users he/she follows,
+// along with their reposts, visible to the user, most recent activity first, leaving out
+// publications and reposts of users the user blocks or mutes and of users blocking the user
+func (repository PublicationRepository) GetPublicationsForUserId(userId uint64, page models.PageRequest) ([]models.Publication, error) {
+
+	// entries are ordered by activity time, publication id and reposter id, zero for publications
+	publicationsAfter, repostsAfter := "true", "true"
+	var publicationsAfterArgs, repostsAfterArgs []interface{}
+	if page.After != nil {
+		publicationsAfter = "(p.created_at, p.id, 0) < (?, ?, ?)"
+		repostsAfter = "(r.created_at, p.id, r.user_id) < (?, ?, ?)"
+		publicationsAfterArgs = []interface{}{page.After.Time, page.After.ID, page.After.Tie}
+		repostsAfterArgs = publicationsAfterArgs
+	}
+
+	args := viewerArgs(userId, userId, userId, userId, userId, userId, userId, userId)
+	args = append(args, publicationsAfterArgs...)
+	args = append(args, page.Limit+1)
+	args = append(args, viewerArgs(userId, userId, userId, userId, userId, userId, userId, userId,
+		userId, userId, userId)...)
+	args = append(args, repostsAfterArgs...)
+	args = append(args, page.Limit+1, page.Limit+1)
 
 	resultSet, error := repository.db.Query(
-		`select p.id, p.title, p.content, p.author_id, u.nick, p.likes, p.created_at 
-				from publications p 
-				join users u on (p.author_id = u.id) 
-				where u.id = ? or u.id in (select f.user_id from followers f where f.follower_id = ?) order by 1 desc`,
-		userId, userId)
+		`(select `+publicationColumns+`, null, null, p.created_at, 0
+				from `+publicationTables+`
+				where (u.id = ? or u.id in (select f.user_id from followers f where f.follower_id = ?))
+				and `+visibleTo("p")+` and not `+silencedBy("p.author_id")+`
+				and `+publicationsAfter+`
+				order by p.created_at desc, p.id desc limit ?)
+			union all
+			(select `+publicationColumns+`, ru.nick, r.created_at, r.created_at, r.user_id
+				from `+publicationTables+`
+				join reposts r on (r.publication_id = p.id)
+				join users ru on (r.user_id = ru.id)
+				where (ru.id = ? or ru.id in (select f.user_id from followers f where f.follower_id = ?))
+				and `+visibleTo("p")+` and not `+silencedBy("p.author_id")+` and not `+silencedBy("r.user_id")+`
+				and `+repostsAfter+`
+				order by r.created_at desc, p.id desc, r.user_id desc limit ?)
+			order by 23 desc, 1 desc, 24 desc limit ?`,
+		args...)
 	if error != nil {
 		return nil, error
 	}
@@ -79,35 +180,148 @@ func (repository PublicationRepository) GetPublicationsForUserId(userId uint64)
 	var publications []models.Publication
 
 	for resultSet.Next() {
-		var publication models.Publication
-		if error = resultSet.Scan(
-			&publication.ID, &publication.Title,
-			&publication.Content, &publication.AuthorId,
-			&publication.AuthorNick, &publication.Likes,
-			&publication.CreatedAt,); error != nil {
+		var repostedBy sql.NullString
+		var repostedAt, activityAt sql.NullTime
+		var repostedById uint64
+		publication, error := scanPublication(resultSet, &repostedBy, &repostedAt, &activityAt, &repostedById)
+		if error != nil {
 			return nil, error
 		}
+		if repostedBy.Valid {
+			publication.RepostedById = repostedById
+			publication.RepostedBy = repostedBy.String
+			publication.RepostedAt = &repostedAt.Time
+		}
 		publications = append(publications, publication)
 	}
 
 	return publications, nil
 }
 
-// UpdatePublication updates a publication
+// UpdatePublication updates a publication along with its tags and the mentions of existing users
 func (repository PublicationRepository) UpdatePublication(id uint64, publication models.Publication) error {
 
-	stmt, error := repository.db.Prepare(
-		"update publications set title = ?, content = ? where id = ? ")
+	transaction, error := repository.db.Begin()
 	if error != nil {
 		return error
 	}
-	defer stmt.Close()
+	defer transaction.Rollback()
 
-	if _, error = stmt.Exec(publication.Title, publication.Content, id); error != nil  {
+	if _, error = transaction.Exec("update publications set title = ?, content = ?, visibility = ? where id = ? ",
+		publication.Title, publication.Content, string(publication.Visibility), id); error != nil  {
 		return error
 	}
 
-	return nil
+	if _, error = transaction.Exec("delete from publication_tags where publication_id = ?", id); error != nil {
+		return error
+	}
+
+	if error = savePublicationTags(transaction, id, publication.Tags); error != nil {
+		return error
+	}
+
+	if _, error = transaction.Exec("delete from publication_mentions where publication_id = ?", id); error != nil {
+		return error
+	}
+
+	if error = savePublicationMentions(transaction, id, publication.Mentions); error != nil {
+		return error
+	}
+
+	return transaction.Commit()
+}
+
+// GetTagPublications gets a page of publications with a tag visible to viewerId, most recent
+// first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+func (repository PublicationRepository) GetTagPublications(tag string, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
+
+	resultSet, error := repository.db.Query(
+		"select "+publicationColumns+" from "+publicationTables+
+			` where p.id in (select pt.publication_id from publication_tags pt join tags t on (t.id = pt.tag_id) 
+					where t.name = ?)
+				and p.id < ? and `+visibleTo("p")+` and not `+silencedBy("p.author_id")+`
+				order by p.id desc limit ?`,
+		viewerArgs(viewerId, tag, beforeId(page), viewerId, viewerId, viewerId, viewerId, viewerId, page.Limit+1)...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var publications []models.Publication
+
+	for resultSet.Next() {
+		publication, error := scanPublication(resultSet)
+		if error != nil {
+			return nil, error
+		}
+		publications = append(publications, publication)
+	}
+
+	return publications, nil
+}
+
+// GetMentionPublications gets a page of publications mentioning a user visible to viewerId, most
+// recent first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+func (repository PublicationRepository) GetMentionPublications(userId uint64, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
+
+	resultSet, error := repository.db.Query(
+		"select "+publicationColumns+" from "+publicationTables+
+			` where p.id in (select m.publication_id from publication_mentions m where m.user_id = ?)
+				and p.id < ? and `+visibleTo("p")+` and not `+silencedBy("p.author_id")+`
+				order by p.id desc limit ?`,
+		viewerArgs(viewerId, userId, beforeId(page), viewerId, viewerId, viewerId, viewerId, viewerId, page.Limit+1)...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var publications []models.Publication
+
+	for resultSet.Next() {
+		publication, error := scanPublication(resultSet)
+		if error != nil {
+			return nil, error
+		}
+		publications = append(publications, publication)
+	}
+
+	return publications, nil
+}
+
+// GetTrendingTags ranks tags of public publications by the growth of their uses in the last
+// window over the window before
+func (repository PublicationRepository) GetTrendingTags(window time.Duration, limit int) ([]models.TrendingTag, error) {
+
+	seconds := int64(window / time.Second)
+
+	resultSet, error := repository.db.Query(
+		`select t.name,
+					sum(p.created_at >= timestampadd(second, -?, current_timestamp())) uses,
+					sum(p.created_at < timestampadd(second, -?, current_timestamp())) previous_uses
+				from tags t 
+				join publication_tags pt on (pt.tag_id = t.id) 
+				join publications p on (p.id = pt.publication_id)
+				where p.created_at >= timestampadd(second, -?, current_timestamp()) and p.visibility = 'public'
+				group by t.id, t.name having uses > 0
+				order by uses - previous_uses desc, uses desc, t.name limit ?`,
+		seconds, seconds, 2*seconds, limit)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var tags []models.TrendingTag
+
+	for resultSet.Next() {
+		var name string
+		var uses, previousUses uint64
+		if error = resultSet.Scan(&name, &uses, &previousUses); error != nil {
+			return nil, error
+		}
+		tags = append(tags, models.NewTrendingTag(name, uses, previousUses, window))
+	}
+
+	return tags, nil
 }
 
 // DeletePublication deletes a publication
@@ -127,15 +341,14 @@ func (repository PublicationRepository) DeletePublication(id uint64) error {
 	return nil
 }
 
-// GetUserPublicationById get all publications of a specific user
-func (repository PublicationRepository) GetUserPublicationById(userId uint64) ([]models.Publication, error) {
+// GetUserPublicationById get a page of publications of a specific user visible to viewerId,
+// most recent first
+func (repository PublicationRepository) GetUserPublicationById(userId uint64, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
 
 	resultSet, error := repository.db.Query(
-		`select p.id, p.title, p.content, p.author_id, u.nick, p.likes, p.created_at 
-				from publications p 
-				join users u on (p.author_id = u.id) 
-				where u.id = ? order by 1 desc`,
-			userId)
+		"select "+publicationColumns+" from "+publicationTables+
+			" where p.author_id = ? and p.id < ? and "+visibleTo("p")+" order by p.id desc limit ?",
+		viewerArgs(viewerId, userId, beforeId(page), viewerId, viewerId, page.Limit+1)...)
 	if error != nil {
 		return nil, error
 	}
@@ -144,12 +357,8 @@ func (repository PublicationRepository) GetUserPublicationById(userId uint64) ([
 	var publications []models.Publication
 
 	for resultSet.Next() {
-		var publication models.Publication
-		if error = resultSet.Scan(
-			&publication.ID, &publication.Title,
-			&publication.Content, &publication.AuthorId,
-			&publication.AuthorNick, &publication.Likes,
-			&publication.CreatedAt,); error != nil {
+		publication, error := scanPublication(resultSet)
+		if error != nil {
 			return nil, error
 		}
 		publications = append(publications, publication)
@@ -158,38 +367,184 @@ func (repository PublicationRepository) GetUserPublicationById(userId uint64) ([
 	return publications, nil
 }
 
-// RegisterPublicationLike registers a like in publication
-func (repository PublicationRepository) RegisterPublicationLike(id uint64) error {
+// RegisterPublicationLike registers a user's like in publication, once per user
+func (repository PublicationRepository) RegisterPublicationLike(id uint64, userId uint64) error {
+	stmt, error := repository.db.Prepare(
+		"insert ignore into publication_likes (publication_id, user_id) values (?, ?)")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(id, userId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// RegisterPublicationUnlike removes a user's like from publication
+func (repository PublicationRepository) RegisterPublicationUnlike(id uint64, userId uint64) error {
+	stmt, error := repository.db.Prepare(
+		"delete from publication_likes where publication_id = ? and user_id = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(id, userId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// GetPublicationLikes searches a page of users who liked a publication, by id, leaving out users
+// blocked or muted by viewerId and users blocking it
+func (repository PublicationRepository) GetPublicationLikes(id uint64, viewerId uint64, page models.PageRequest) ([]models.User, error) {
+
+	resultSet, error := repository.db.Query(
+		`select u.id, u.name, u.nick, u.email, u.created_at 
+				from users u join publication_likes l on (u.id = l.user_id) 
+				where l.publication_id = ? and not `+silencedBy("l.user_id")+`
+				and l.user_id > ? order by l.user_id limit ?`,
+		id, viewerId, viewerId, viewerId, afterId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var users []models.User
+
+	for resultSet.Next() {
+		var user models.User
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.CreatedAt); error != nil {
+			return nil, error
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+
+// RegisterRepost registers a user's repost of a publication, once per user
+func (repository PublicationRepository) RegisterRepost(id uint64, userId uint64) error {
+
 	stmt, error := repository.db.Prepare(
-		"update publications p set p.likes = p.likes + 1 where id = ? ")
+		"insert ignore into reposts (publication_id, user_id) values (?, ?)")
 	if error != nil {
 		return error
 	}
 	defer stmt.Close()
 
-	if _, error = stmt.Exec(id); error != nil  {
+	if _, error = stmt.Exec(id, userId); error != nil {
 		return error
 	}
 
 	return nil
 }
 
-// RegisterPublicationUnlike registers an unlike in publication
-func (repository PublicationRepository) RegisterPublicationUnlike(id uint64) error {
+// RemoveRepost removes a user's repost of a publication
+func (repository PublicationRepository) RemoveRepost(id uint64, userId uint64) error {
+
 	stmt, error := repository.db.Prepare(
-		"update publications p set p.likes = p.likes - 1 where p.likes > 0 and id = ? ")
+		"delete from reposts where publication_id = ? and user_id = ?")
 	if error != nil {
 		return error
 	}
 	defer stmt.Close()
 
-	if _, error = stmt.Exec(id); error != nil  {
+	if _, error = stmt.Exec(id, userId); error != nil {
 		return error
 	}
 
 	return nil
 }
 
+// savePublicationTags stores the tags of a publication, creating the missing ones
+func savePublicationTags(transaction *sql.Tx, publicationId uint64, tags []string) error {
+
+	for _, tag := range tags {
+		if _, error := transaction.Exec("insert ignore into tags (name) values (?)", tag); error != nil {
+			return error
+		}
+
+		if _, error := transaction.Exec(
+			`insert ignore into publication_tags (publication_id, tag_id) 
+					select ?, t.id from tags t where t.name = ?`, publicationId, tag); error != nil {
+			return error
+		}
+	}
+
+	return nil
+}
+
+// savePublicationMentions stores the mentions of a publication referring to existing users
+func savePublicationMentions(transaction *sql.Tx, publicationId uint64, mentions []models.Mention) error {
+
+	for _, mention := range mentions {
+		if _, error := transaction.Exec(
+			`insert ignore into publication_mentions (publication_id, mention_offset, mention_length, user_id) 
+					select ?, ?, ?, u.id from users u where u.nick = ?`,
+			publicationId, mention.Offset, mention.Length, mention.Nick); error != nil {
+			return error
+		}
+	}
+
+	return nil
+}
+
+// scanPublication reads a row of publicationColumns, followed by the extra columns given
+func scanPublication(resultSet *sql.Rows, extra ...interface{}) (models.Publication, error) {
+
+	var publication models.Publication
+	var quotedPublicationId, quotedId, quotedAuthorId sql.NullInt64
+	var tags, mentions, quotedTitle, quotedContent, quotedAuthorNick sql.NullString
+	var quotedCreatedAt sql.NullTime
+
+	columns := append([]interface{}{
+		&publication.ID, &publication.Title, &publication.Content,
+		&publication.AuthorId, &publication.AuthorNick, &publication.Visibility, &tags, &mentions,
+		&publication.Likes, &publication.LikedByMe,
+		&publication.CommentCount, &publication.Reposts,
+		&quotedPublicationId, &quotedId, &quotedTitle, &quotedContent,
+		&quotedAuthorId, &quotedAuthorNick, &quotedCreatedAt,
+		&publication.CreatedAt,
+	}, extra...)
+
+	if error := resultSet.Scan(columns...); error != nil {
+		return publication, error
+	}
+
+	if tags.Valid {
+		publication.Tags = strings.Fields(tags.String)
+	}
+	if mentions.Valid {
+		for _, field := range strings.Fields(mentions.String) {
+			var mention models.Mention
+			if _, error := fmt.Sscanf(field, "%d:%d:%d", &mention.Offset, &mention.Length, &mention.UserId); error != nil {
+				return publication, error
+			}
+			publication.Mentions = append(publication.Mentions, mention)
+		}
+	}
+	if quotedPublicationId.Valid {
+		publication.QuotedPublicationId = uint64(quotedPublicationId.Int64)
+	}
+	if quotedId.Valid {
+		publication.Quoted = &models.Publication{
+			ID:         uint64(quotedId.Int64),
+			Title:      quotedTitle.String,
+			Content:    quotedContent.String,
+			AuthorId:   uint64(quotedAuthorId.Int64),
+			AuthorNick: quotedAuthorNick.String,
+			CreatedAt:  quotedCreatedAt.Time,
+		}
+	}
+
+	return publication, nil
+}
 
 // NewPublicationRepository factory
 func NewPublicationRepository(db *sql.DB) *PublicationRepository {
@@ -0,0 +1,80 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+)
+
+// PublicationSearchRepository searches publications with the MySQL full-text index on their
+// title and content
+type PublicationSearchRepository struct {
+	db *sql.DB
+}
+
+// publicationRelevance scores publication p against the query bound to its single parameter
+const publicationRelevance = "match(p.title, p.content) against (? in natural language mode)"
+
+// SearchPublications gets a page of publications matching a search visible to viewerId, most
+// relevant first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+func (repository PublicationSearchRepository) SearchPublications(search models.PublicationSearch, viewerId uint64, page models.PageRequest) ([]models.Publication, error) {
+
+	conditions := publicationRelevance + " and " + visibleTo("p") + " and not " + silencedBy("p.author_id")
+	args := []interface{}{viewerId, search.Query, viewerId, viewerId,
+		search.Query, viewerId, viewerId, viewerId, viewerId, viewerId}
+
+	if search.AuthorId != 0 {
+		conditions += " and p.author_id = ?"
+		args = append(args, search.AuthorId)
+	}
+
+	if search.Tag != "" {
+		conditions += ` and p.id in (select pt.publication_id from publication_tags pt join tags t on (t.id = pt.tag_id) 
+				where t.name = ?)`
+		args = append(args, search.Tag)
+	}
+
+	if search.Since != nil {
+		conditions += " and p.created_at >= ?"
+		args = append(args, *search.Since)
+	}
+
+	if search.Until != nil {
+		conditions += " and p.created_at < ?"
+		args = append(args, *search.Until)
+	}
+
+	if page.After != nil {
+		conditions += " and (" + publicationRelevance + " < ? or (" + publicationRelevance + " = ? and p.id < ?))"
+		args = append(args, search.Query, page.After.Score, search.Query, page.After.Score, page.After.ID)
+	}
+
+	args = append(args, page.Limit+1)
+
+	resultSet, error := repository.db.Query(
+		"select "+publicationColumns+", "+publicationRelevance+" relevance from "+publicationTables+
+			" where "+conditions+" order by relevance desc, p.id desc limit ?",
+		args...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var publications []models.Publication
+
+	for resultSet.Next() {
+		var relevance float64
+		publication, error := scanPublication(resultSet, &relevance)
+		if error != nil {
+			return nil, error
+		}
+		publication.Relevance = relevance
+		publications = append(publications, publication)
+	}
+
+	return publications, nil
+}
+
+// NewPublicationSearchRepository factory
+func NewPublicationSearchRepository(db *sql.DB) *PublicationSearchRepository {
+	return &PublicationSearchRepository{db}
+}
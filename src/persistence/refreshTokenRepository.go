@@ -0,0 +1,123 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+)
+
+// RefreshTokenRepository persists refresh token data
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+// CreateRefreshToken stores a new refresh token
+func (repository RefreshTokenRepository) CreateRefreshToken(token models.RefreshToken) (uint64, error) {
+
+	stmt, error := repository.db.Prepare(
+		"insert into refresh_tokens (user_id, family_id, token_hash, expires_at) values (?, ?, ?, ?)")
+	if error != nil {
+		return 0, error
+	}
+	defer stmt.Close()
+
+	insert, error := stmt.Exec(token.UserId, token.FamilyId, token.TokenHash, token.ExpiresAt)
+	if error != nil {
+		return 0, error
+	}
+
+	id, error := insert.LastInsertId()
+	if error != nil {
+		return 0, error
+	}
+
+	return uint64(id), nil
+}
+
+// GetRefreshTokenByHash gets a refresh token by its hash
+func (repository RefreshTokenRepository) GetRefreshTokenByHash(tokenHash string) (models.RefreshToken, error) {
+
+	var token models.RefreshToken
+
+	resultSet, error := repository.db.Query(
+		`select t.id, t.user_id, t.family_id, t.token_hash, t.expires_at, t.revoked_at, t.created_at 
+				from refresh_tokens t where t.token_hash = ?`, tokenHash)
+	if error != nil {
+		return token, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		var revokedAt sql.NullTime
+		if error = resultSet.Scan(&token.ID, &token.UserId, &token.FamilyId, &token.TokenHash,
+			&token.ExpiresAt, &revokedAt, &token.CreatedAt); error != nil {
+			return token, error
+		}
+		if revokedAt.Valid {
+			token.RevokedAt = &revokedAt.Time
+		}
+	}
+
+	return token, nil
+}
+
+// RevokeRefreshToken revokes a token, telling whether it was still active
+func (repository RefreshTokenRepository) RevokeRefreshToken(id uint64) (bool, error) {
+
+	stmt, error := repository.db.Prepare(
+		"update refresh_tokens set revoked_at = current_timestamp() where id = ? and revoked_at is null")
+	if error != nil {
+		return false, error
+	}
+	defer stmt.Close()
+
+	update, error := stmt.Exec(id)
+	if error != nil {
+		return false, error
+	}
+
+	rows, error := update.RowsAffected()
+	if error != nil {
+		return false, error
+	}
+
+	return rows == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token of a family
+func (repository RefreshTokenRepository) RevokeRefreshTokenFamily(familyId string) error {
+
+	stmt, error := repository.db.Prepare(
+		"update refresh_tokens set revoked_at = current_timestamp() where family_id = ? and revoked_at is null")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(familyId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every token of a user
+func (repository RefreshTokenRepository) RevokeUserRefreshTokens(userId uint64) error {
+
+	stmt, error := repository.db.Prepare(
+		"update refresh_tokens set revoked_at = current_timestamp() where user_id = ? and revoked_at is null")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// NewRefreshTokenRepository factory
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db}
+}
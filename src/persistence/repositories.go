@@ -0,0 +1,53 @@
+package persistence
+
+import "database/sql"
+
+// Repositories groups the stores used by the API
+type Repositories struct {
+	Users         UserStore
+	Publications  PublicationStore
+	Search        SearchEngine
+	Comments      CommentStore
+	Notifications NotificationStore
+	Messages      MessageStore
+	Webhooks      WebhookStore
+	RefreshTokens RefreshTokenStore
+	OneTimeTokens OneTimeTokenStore
+	MFA           MFAStore
+	LoginAttempts LoginAttemptStore
+}
+
+// NewRepositories factory, sharing the given connection pool among MySQL repositories
+func NewRepositories(db *sql.DB) Repositories {
+	return Repositories{
+		Users:         NewUserRepository(db),
+		Publications:  NewPublicationRepository(db),
+		Search:        NewPublicationSearchRepository(db),
+		Comments:      NewCommentRepository(db),
+		Notifications: NewNotificationRepository(db),
+		Messages:      NewMessageRepository(db),
+		Webhooks:      NewWebhookRepository(db),
+		RefreshTokens: NewRefreshTokenRepository(db),
+		OneTimeTokens: NewOneTimeTokenRepository(db),
+		MFA:           NewMFARepository(db),
+		LoginAttempts: NewLoginAttemptRepository(db),
+	}
+}
+
+// NewMemoryRepositories factory, sharing a single in-memory database among repositories
+func NewMemoryRepositories() Repositories {
+	db := newMemoryDatabase()
+	return Repositories{
+		Users:         newMemoryUserRepository(db),
+		Publications:  newMemoryPublicationRepository(db),
+		Search:        newMemoryPublicationSearchRepository(db),
+		Comments:      newMemoryCommentRepository(db),
+		Notifications: newMemoryNotificationRepository(db),
+		Messages:      newMemoryMessageRepository(db),
+		Webhooks:      newMemoryWebhookRepository(db),
+		RefreshTokens: newMemoryRefreshTokenRepository(db),
+		OneTimeTokens: newMemoryOneTimeTokenRepository(db),
+		MFA:           newMemoryMFARepository(db),
+		LoginAttempts: newMemoryLoginAttemptRepository(db),
+	}
+}
@@ -0,0 +1,274 @@
+package persistence
+
+import (
+	"devbook/src/models"
+	"devbook/src/security"
+	"time"
+)
+
+// UserStore persists user data
+type UserStore interface {
+	// Create persists a new user
+	Create(user models.User) (uint64, error)
+	// ListUsers searches for a page of users with name or nick corresponding to description, by id,
+	// leaving out users blocked or muted by viewerId and users blocking it
+	ListUsers(description string, viewerId uint64, page models.PageRequest) ([]models.User, error)
+	// GetUserById gets a specific user by its id
+	GetUserById(id uint64) (models.User, error)
+	// GetUserByEmail gets a specific user by its email
+	GetUserByEmail(email string) (models.User, error)
+	// GetUserPasswordById gets a user's password by id
+	GetUserPasswordById(id uint64) (string, error)
+	// Update updates user information, unverifying the email when it changes
+	Update(id uint64, user models.User) error
+	// Delete deletes a user with the given id
+	Delete(id uint64) error
+	// FollowUser register a user following another user unless either blocks the other, telling if
+	// it follows it
+	FollowUser(followedId uint64, followerId uint64) (bool, error)
+	// UnfollowUser removes a register of a user following another user, or the request to follow it
+	UnfollowUser(followedId uint64, followerId uint64) error
+	// IsFollower tells if a user follows another user
+	IsFollower(followedId uint64, followerId uint64) (bool, error)
+	// GetFollowersAmong gets those of userIds following a user
+	GetFollowersAmong(followedId uint64, userIds []uint64) ([]uint64, error)
+	// CreateFollowRequest registers a user's request to follow a private account, once per user,
+	// unless either blocks the other
+	CreateFollowRequest(followedId uint64, followerId uint64) error
+	// GetFollowRequests gets a page of pending requests to follow a user, oldest first
+	GetFollowRequests(followedId uint64, page models.PageRequest) ([]models.FollowRequest, error)
+	// ApproveFollowRequest turns a request to follow a user into a follower, telling if it existed
+	// and neither user blocks the other
+	ApproveFollowRequest(followedId uint64, followerId uint64) (bool, error)
+	// DeleteFollowRequest rejects a request to follow a user, telling if it existed
+	DeleteFollowRequest(followedId uint64, followerId uint64) (bool, error)
+	// UpdateUserPrivacy makes a user account private or public, approving pending requests to
+	// follow it when it becomes public, but those of users it blocks or is blocked by
+	UpdateUserPrivacy(userId uint64, isPrivate bool) error
+	// BlockUser registers a user blocking another user, removing follows and follow requests
+	// between both users
+	BlockUser(userId uint64, blockedId uint64) error
+	// UnblockUser removes a register of a user blocking another user
+	UnblockUser(userId uint64, blockedId uint64) error
+	// IsBlocked tells if either user blocks the other
+	IsBlocked(userId uint64, otherId uint64) (bool, error)
+	// GetBlockedUsers searches a page of users blocked by a user, by id
+	GetBlockedUsers(userId uint64, page models.PageRequest) ([]models.User, error)
+	// MuteUser registers a user muting another user, once per user
+	MuteUser(userId uint64, mutedId uint64) error
+	// UnmuteUser removes a register of a user muting another user
+	UnmuteUser(userId uint64, mutedId uint64) error
+	// IsMuted tells if a user mutes another user
+	IsMuted(userId uint64, mutedId uint64) (bool, error)
+	// GetMutedUsers searches a page of users muted by a user, by id
+	GetMutedUsers(userId uint64, page models.PageRequest) ([]models.User, error)
+	// GetFollowersForUserId searches a page of followers of a user, by id
+	GetFollowersForUserId(followedId uint64, page models.PageRequest) ([]models.User, error)
+	// GetFollowedUsersForUserId searches a page of users followed by a user, by id
+	GetFollowedUsersForUserId(followerId uint64, page models.PageRequest) ([]models.User, error)
+	// UpdateUserPassword updates a user's password
+	UpdateUserPassword(userId uint64, password string) error
+	// VerifyUserEmail marks a user's email as verified, unless it is no longer email
+	VerifyUserEmail(userId uint64, email string) error
+	// UpdateUserRole assigns a role to a user
+	UpdateUserRole(userId uint64, role security.Role) error
+	// SuspendUser suspends a user, keeping the time of a previous suspension
+	SuspendUser(userId uint64) error
+	// UnsuspendUser lifts a user's suspension
+	UnsuspendUser(userId uint64) error
+}
+
+// PublicationStore persists publication data
+type PublicationStore interface {
+	// CreatePublication creates a new publication along with its tags and the mentions of existing users
+	CreatePublication(publication models.Publication) (uint64, error)
+	// GetPublicationById gets a specific publication by its id, if visible to viewerId
+	GetPublicationById(id uint64, viewerId uint64) (models.Publication, error)
+	// GetPublicationAuthorId gets the author of a publication whatever its visibility, zero when it
+	// does not exist
+	GetPublicationAuthorId(id uint64) (uint64, error)
+	// GetPublicationsForUserId gets a page of publications of a user and the users he/she follows,
+	// along with their reposts, visible to the user, most recent activity first, leaving out
+	// publications and reposts of users the user blocks or mutes and of users blocking the user
+	GetPublicationsForUserId(userId uint64, page models.PageRequest) ([]models.Publication, error)
+	// UpdatePublication updates a publication along with its tags and the mentions of existing users
+	UpdatePublication(id uint64, publication models.Publication) error
+	// DeletePublication deletes a publication
+	DeletePublication(id uint64) error
+	// GetUserPublicationById get a page of publications of a specific user visible to viewerId,
+	// most recent first
+	GetUserPublicationById(userId uint64, viewerId uint64, page models.PageRequest) ([]models.Publication, error)
+	// GetTagPublications gets a page of publications with a tag visible to viewerId, most recent
+	// first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+	GetTagPublications(tag string, viewerId uint64, page models.PageRequest) ([]models.Publication, error)
+	// GetMentionPublications gets a page of publications mentioning a user visible to viewerId, most
+	// recent first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+	GetMentionPublications(userId uint64, viewerId uint64, page models.PageRequest) ([]models.Publication, error)
+	// GetTrendingTags ranks tags of public publications by the growth of their uses in the last
+	// window over the window before
+	GetTrendingTags(window time.Duration, limit int) ([]models.TrendingTag, error)
+	// RegisterPublicationLike registers a user's like in publication, once per user
+	RegisterPublicationLike(id uint64, userId uint64) error
+	// RegisterPublicationUnlike removes a user's like from publication
+	RegisterPublicationUnlike(id uint64, userId uint64) error
+	// GetPublicationLikes searches a page of users who liked a publication, by id, leaving out users
+	// blocked or muted by viewerId and users blocking it
+	GetPublicationLikes(id uint64, viewerId uint64, page models.PageRequest) ([]models.User, error)
+	// RegisterRepost registers a user's repost of a publication, once per user
+	RegisterRepost(id uint64, userId uint64) error
+	// RemoveRepost removes a user's repost of a publication
+	RemoveRepost(id uint64, userId uint64) error
+}
+
+// SearchEngine searches publications by relevance to a text, with the database's full-text index
+// or an external engine
+type SearchEngine interface {
+	// SearchPublications gets a page of publications matching a search visible to viewerId, most
+	// relevant first, leaving out publications of users viewerId blocks or mutes and of users blocking it
+	SearchPublications(search models.PublicationSearch, viewerId uint64, page models.PageRequest) ([]models.Publication, error)
+}
+
+// CommentStore persists comments on publications
+type CommentStore interface {
+	// CreateComment creates a new comment, replying to the comment of its parent id if any
+	CreateComment(comment models.Comment) (uint64, error)
+	// GetCommentById gets a specific comment by its id
+	GetCommentById(id uint64) (models.Comment, error)
+	// GetPublicationComments gets a page of comments of a publication, oldest first, leaving out
+	// comments of users viewerId blocks or mutes and of users blocking it
+	GetPublicationComments(publicationId uint64, viewerId uint64, page models.PageRequest) ([]models.Comment, error)
+	// GetCommentReplies gets a page of replies of each of parentIds, by id, the cursor applying to
+	// the replies of every parent, leaving out replies of users viewerId blocks or mutes and of
+	// users blocking it
+	GetCommentReplies(parentIds []uint64, viewerId uint64, page models.PageRequest) ([]models.Comment, error)
+	// CountCommentReplies counts the replies of each of ids seen by viewerId, leaving out comments
+	// without replies
+	CountCommentReplies(ids []uint64, viewerId uint64) (map[uint64]int, error)
+	// UpdateComment updates the content of a comment
+	UpdateComment(id uint64, comment models.Comment) error
+	// DeleteComment deletes a comment along with its replies
+	DeleteComment(id uint64) error
+}
+
+// NotificationStore persists notifications of users
+type NotificationStore interface {
+	// CreateNotification registers a notification once per user, type, actor, publication and
+	// comment, marking it unread again when it was read, returning its id or zero when it was
+	// still unread
+	CreateNotification(notification models.Notification) (uint64, error)
+	// GetNotificationGroups gets a page of a user's notifications grouped by type and publication,
+	// with their most recent actors, most recently notified first
+	GetNotificationGroups(userId uint64, page models.PageRequest) ([]models.NotificationGroup, error)
+	// CountUnreadNotifications counts a user's unread notifications
+	CountUnreadNotifications(userId uint64) (uint64, error)
+	// MarkNotificationGroupRead marks as read the notifications of a user grouped with the
+	// notification of the given id, telling if it existed
+	MarkNotificationGroupRead(userId uint64, id uint64) (bool, error)
+	// MarkAllNotificationsRead marks every notification of a user as read
+	MarkAllNotificationsRead(userId uint64) error
+}
+
+// MessageStore persists direct messages between users
+type MessageStore interface {
+	// GetOrCreateConversation gets the conversation between two users, starting it when there is none
+	GetOrCreateConversation(userId uint64, otherId uint64) (uint64, error)
+	// GetConversation gets a specific conversation by its id as seen by one of its users
+	GetConversation(id uint64, userId uint64) (models.Conversation, error)
+	// GetConversations gets a page of the conversations of a user, most recently active first
+	GetConversations(userId uint64, page models.PageRequest) ([]models.Conversation, error)
+	// CreateMessage sends a message in a conversation, which its sender has read up to it
+	CreateMessage(message models.Message) (uint64, error)
+	// GetMessageById gets a specific message by its id
+	GetMessageById(id uint64) (models.Message, error)
+	// GetMessages gets a page of the messages of a conversation, most recent first
+	GetMessages(conversationId uint64, page models.PageRequest) ([]models.Message, error)
+	// MarkConversationRead marks a conversation read by a user up to a message, or its last message
+	// when messageId is zero, never moving back the messages read before
+	MarkConversationRead(conversationId uint64, userId uint64, messageId uint64) (models.ReadReceipt, error)
+}
+
+// WebhookStore persists webhooks of users and the queue of their deliveries
+type WebhookStore interface {
+	// CreateWebhook registers a webhook of a user
+	CreateWebhook(webhook models.Webhook) (uint64, error)
+	// GetWebhookById gets a specific webhook by its id, with its secret
+	GetWebhookById(id uint64) (models.Webhook, error)
+	// GetWebhooks gets a page of the webhooks of a user, by id
+	GetWebhooks(userId uint64, page models.PageRequest) ([]models.Webhook, error)
+	// GetSubscribedWebhooks gets the webhooks of a user subscribed to an event type, with their secrets
+	GetSubscribedWebhooks(userId uint64, eventType string) ([]models.Webhook, error)
+	// DeleteWebhook deletes a webhook along with its deliveries
+	DeleteWebhook(id uint64) error
+	// CreateDelivery queues a delivery to a webhook
+	CreateDelivery(delivery models.WebhookDelivery) (uint64, error)
+	// GetDeliveryById gets a specific delivery by its id
+	GetDeliveryById(id uint64) (models.WebhookDelivery, error)
+	// GetDeliveries gets a page of the deliveries to a webhook, most recent first
+	GetDeliveries(webhookId uint64, page models.PageRequest) ([]models.WebhookDelivery, error)
+	// GetPendingDeliveries gets pending deliveries unclaimed at now, except those to skipped webhooks,
+	// soonest next attempt first, up to limit
+	GetPendingDeliveries(now time.Time, skippedWebhookIds []uint64, limit int) ([]models.WebhookDelivery, error)
+	// ClaimDelivery claims a pending delivery until a time unless it is claimed at now, telling
+	// whether it was claimed
+	ClaimDelivery(id uint64, now time.Time, until time.Time) (bool, error)
+	// UpdateDelivery records the outcome of an attempt of a delivery, releasing its claim
+	UpdateDelivery(delivery models.WebhookDelivery) error
+}
+
+// RefreshTokenStore persists hashed refresh tokens
+type RefreshTokenStore interface {
+	// CreateRefreshToken stores a new refresh token
+	CreateRefreshToken(token models.RefreshToken) (uint64, error)
+	// GetRefreshTokenByHash gets a refresh token by its hash
+	GetRefreshTokenByHash(tokenHash string) (models.RefreshToken, error)
+	// RevokeRefreshToken revokes a token, telling whether it was still active
+	RevokeRefreshToken(id uint64) (bool, error)
+	// RevokeRefreshTokenFamily revokes every token of a family
+	RevokeRefreshTokenFamily(familyId string) error
+	// RevokeUserRefreshTokens revokes every token of a user
+	RevokeUserRefreshTokens(userId uint64) error
+}
+
+// OneTimeTokenStore persists hashed single use tokens
+type OneTimeTokenStore interface {
+	// CreateOneTimeToken stores a new single use token
+	CreateOneTimeToken(token models.OneTimeToken) (uint64, error)
+	// GetOneTimeToken gets a token of a purpose by its hash
+	GetOneTimeToken(purpose string, tokenHash string) (models.OneTimeToken, error)
+	// UseOneTimeToken marks a token as used, telling whether it was still unused
+	UseOneTimeToken(id uint64) (bool, error)
+	// DeleteOneTimeTokens deletes the tokens of a purpose issued to a user
+	DeleteOneTimeTokens(userId uint64, purpose string) error
+}
+
+// MFAStore persists TOTP second factors and recovery codes
+type MFAStore interface {
+	// GetMFA gets a user's second factor
+	GetMFA(userId uint64) (models.MFA, error)
+	// SaveMFASecret stores a new, not yet enabled, secret for a user replacing any previous one
+	SaveMFASecret(userId uint64, secret string) error
+	// EnableMFA enables a user's second factor
+	EnableMFA(userId uint64) error
+	// DisableMFA removes a user's second factor and recovery codes
+	DisableMFA(userId uint64) error
+	// UseMFAStep records a time step as used, telling whether it is newer than the last used one
+	UseMFAStep(userId uint64, step uint64) (bool, error)
+	// ReplaceRecoveryCodes replaces a user's recovery codes with the given hashes
+	ReplaceRecoveryCodes(userId uint64, codeHashes []string) error
+	// UseRecoveryCode marks a recovery code as used, telling whether it was valid and unused
+	UseRecoveryCode(userId uint64, codeHash string) (bool, error)
+}
+
+// LoginAttemptStore persists failed login counters, keyed by account or address
+type LoginAttemptStore interface {
+	// CountLoginAttempt atomically counts a login attempt under a key as failed until released,
+	// restarting the count when the previous failure is older than window, and returns the
+	// attempts counted, this one included
+	CountLoginAttempt(key string, window time.Duration) (models.LoginAttempts, error)
+	// RegisterLoginFailure records the time of a failed login counted under a key
+	RegisterLoginFailure(key string) error
+	// ReleaseLoginAttempt stops counting a login attempt under a key
+	ReleaseLoginAttempt(key string) error
+	// ResetLoginAttempts clears the login attempts counted under a key
+	ResetLoginAttempts(key string) error
+}
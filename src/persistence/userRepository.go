@@ -3,7 +3,9 @@ package persistence
 import (
 	"database/sql"
 	"devbook/src/models"
+	"devbook/src/security"
 	"fmt"
+	"strings"
 )
 
 // UserRepository persists user data
@@ -11,6 +13,14 @@ type UserRepository struct {
 	db *sql.DB
 }
 
+// silencedBy tells if the user at column is muted or blocked by the viewer bound to its first two
+// parameters, or blocks the viewer bound to its third one
+func silencedBy(column string) string {
+	return fmt.Sprintf(`(%[1]s in (select m.muted_id from user_mutes m where m.user_id = ?) or
+		%[1]s in (select b.blocked_id from user_blocks b where b.user_id = ?) or
+		%[1]s in (select b.user_id from user_blocks b where b.blocked_id = ?))`, column)
+}
+
 // Create persists a new user
 func (repository UserRepository) Create(user models.User) (uint64, error) {
 
@@ -34,14 +44,17 @@ func (repository UserRepository) Create(user models.User) (uint64, error) {
 	return uint64(id), nil;
 }
 
-// ListUsers searches for users with name or nick corresponding to description
-func (repository UserRepository) ListUsers(description string) ([]models.User, error) {
+// ListUsers searches for a page of users with name or nick corresponding to description, by id,
+// leaving out users blocked or muted by viewerId and users blocking it
+func (repository UserRepository) ListUsers(description string, viewerId uint64, page models.PageRequest) ([]models.User, error) {
 
 	description = fmt.Sprintf("%%%s%%", description)
 
 	resultSet, error := repository.db.Query(
-		"select u.id, u.name, u.nick, u.email, u.created_at from users u where u.name LIKE ? or u.nick LIKE ?",
-		description, description)
+		`select u.id, u.name, u.nick, u.email, u.is_private, u.created_at from users u
+				where (u.name LIKE ? or u.nick LIKE ?) and not `+silencedBy("u.id")+`
+				and u.id > ? order by u.id limit ?`,
+		description, description, viewerId, viewerId, viewerId, afterId(page), page.Limit+1)
 	if error != nil {
 		return nil, error
 	}
@@ -51,7 +64,7 @@ func (repository UserRepository) ListUsers(description string) ([]models.User, e
 
 	for resultSet.Next() {
 		var user models.User
-		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.CreatedAt, ); error != nil {
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.IsPrivate, &user.CreatedAt, ); error != nil {
 			return nil, error
 		}
 		users = append(users, user)
@@ -66,7 +79,7 @@ func (repository UserRepository) GetUserById(id uint64) (models.User, error) {
 	var user models.User
 
 	resultSet, error := repository.db.Query(
-		"select u.id, u.name, u.nick, u.email, u.password, u.created_at from users u where u.id = ?", id)
+		"select u.id, u.name, u.nick, u.email, u.password, u.email_verified_at, u.role, u.suspended_at, u.is_private, u.created_at from users u where u.id = ?", id)
 
 	if error != nil {
 		return user, error
@@ -74,9 +87,16 @@ func (repository UserRepository) GetUserById(id uint64) (models.User, error) {
 	defer resultSet.Close()
 
 	if resultSet.Next() {
-		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.Password, &user.CreatedAt, ); error != nil {
+		var emailVerifiedAt, suspendedAt sql.NullTime
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.Password, &emailVerifiedAt, &user.Role, &suspendedAt, &user.IsPrivate, &user.CreatedAt, ); error != nil {
 			return user, error
 		}
+		if emailVerifiedAt.Valid {
+			user.EmailVerifiedAt = &emailVerifiedAt.Time
+		}
+		if suspendedAt.Valid {
+			user.SuspendedAt = &suspendedAt.Time
+		}
 	}
 
 	return user, nil
@@ -88,7 +108,7 @@ func (repository UserRepository) GetUserByEmail(email string) (models.User, erro
 	var user models.User
 
 	resultSet, error := repository.db.Query(
-		"select u.id, u.name, u.nick, u.email, u.password from users u where u.email = ?", email)
+		"select u.id, u.name, u.nick, u.email, u.password, u.email_verified_at, u.role, u.suspended_at from users u where u.email = ?", email)
 
 	if error != nil {
 		return user, error
@@ -96,9 +116,16 @@ func (repository UserRepository) GetUserByEmail(email string) (models.User, erro
 	defer resultSet.Close()
 
 	if resultSet.Next() {
-		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.Password, ); error != nil {
+		var emailVerifiedAt, suspendedAt sql.NullTime
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.Password, &emailVerifiedAt, &user.Role, &suspendedAt, ); error != nil {
 			return user, error
 		}
+		if emailVerifiedAt.Valid {
+			user.EmailVerifiedAt = &emailVerifiedAt.Time
+		}
+		if suspendedAt.Valid {
+			user.SuspendedAt = &suspendedAt.Time
+		}
 	}
 
 	return user, nil
@@ -126,95 +153,299 @@ func (repository UserRepository) GetUserPasswordById(id uint64) (string, error)
 	return password, nil
 }
 
-// Update updates user information in database
+// Update updates user information in database, unverifying the email when it changes
 func (repository UserRepository) Update(id uint64, user models.User) error {
 
 	stmt, error := repository.db.Prepare(
-		"update users set name = ?, nick = ?, email = ? where id = ? ")
+		`update users set name = ?, nick = ?, 
+				email_verified_at = if(email = ?, email_verified_at, null), email = ? where id = ? `)
 	if error != nil {
 		return error
 	}
 	defer stmt.Close()
 
-	if _, error = stmt.Exec(user.Name, user.Nick, user.Email, id); error != nil {
+	if _, error = stmt.Exec(user.Name, user.Nick, user.Email, user.Email, id); error != nil {
 		return error
 	}
 
 	return nil
 }
 
-// Delete deletes a user with the given id
+// Delete deletes a user with the given id, along with replies to the user's comments
 func (repository UserRepository) Delete(id uint64) error {
 
-	stmt, error := repository.db.Prepare(
-		"delete from users where id = ? ")
+	transaction, error := repository.db.Begin()
 	if error != nil {
 		return error
 	}
-	defer stmt.Close()
+	defer transaction.Rollback()
 
-	if _, error = stmt.Exec(id); error != nil {
+	if _, error = transaction.Exec(
+		`delete from comments where id in (
+				select descendant_id from (
+					select t.descendant_id from comment_tree t join comments c on (t.ancestor_id = c.id)
+					where c.author_id = ?) as descendants)`, id); error != nil {
 		return error
 	}
 
-	return nil
+	if _, error = transaction.Exec("delete from users where id = ? ", id); error != nil {
+		return error
+	}
+
+	return transaction.Commit()
 }
 
-// FollowUser register a user following another user
-func (repository UserRepository) FollowUser(followedId uint64, followerId uint64) error {
+// FollowUser register a user following another user unless either blocks the other, telling if
+// it follows it, the block being checked by the insert itself
+func (repository UserRepository) FollowUser(followedId uint64, followerId uint64) (bool, error) {
 
 	stmt, error := repository.db.Prepare(
-		"insert ignore into followers (user_id, follower_id) values (?, ?)")
+		`insert ignore into followers (user_id, follower_id)
+				select ?, ? from dual where not exists (
+					select 1 from user_blocks b
+						where (b.user_id = ? and b.blocked_id = ?) or (b.user_id = ? and b.blocked_id = ?))`)
 
 	if error != nil {
-		return error
+		return false, error
 	}
 	defer stmt.Close()
 
-	insert, error := stmt.Exec(followedId, followerId)
+	insert, error := stmt.Exec(followedId, followerId, followedId, followerId, followerId, followedId)
+	if error != nil {
+		return false, error
+	}
+
+	inserted, error := insert.RowsAffected()
+	if error != nil || inserted == 1 {
+		return inserted == 1, error
+	}
+
+	return repository.IsFollower(followedId, followerId)
+}
+
+// UnfollowUser removes a register of a user following another user, or the request to follow it
+func (repository UserRepository) UnfollowUser(followedId uint64, followerId uint64) error {
+
+	transaction, error := repository.db.Begin()
 	if error != nil {
 		return error
 	}
+	defer transaction.Rollback()
 
-	_, error = insert.LastInsertId()
+	if _, error = transaction.Exec(
+		"delete from followers where user_id = ? and follower_id = ?", followedId, followerId); error != nil {
+		return error
+	}
+
+	if _, error = transaction.Exec(
+		"delete from follow_requests where user_id = ? and follower_id = ?", followedId, followerId); error != nil {
+		return error
+	}
+
+	return transaction.Commit()
+}
+
+// IsFollower tells if a user follows another user
+func (repository UserRepository) IsFollower(followedId uint64, followerId uint64) (bool, error) {
+
+	resultSet, error := repository.db.Query(
+		"select 1 from followers f where f.user_id = ? and f.follower_id = ?", followedId, followerId)
 	if error != nil {
+		return false, error
+	}
+	defer resultSet.Close()
+
+	return resultSet.Next(), resultSet.Err()
+}
+
+// GetFollowersAmong gets those of userIds following a user
+func (repository UserRepository) GetFollowersAmong(followedId uint64, userIds []uint64) ([]uint64, error) {
+
+	if len(userIds) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, 0, len(userIds)+1)
+	args = append(args, followedId)
+	for _, userId := range userIds {
+		args = append(args, userId)
+	}
+
+	resultSet, error := repository.db.Query(
+		"select f.follower_id from followers f where f.user_id = ? and f.follower_id in (?"+
+			strings.Repeat(", ?", len(userIds)-1)+")", args...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var followers []uint64
+	for resultSet.Next() {
+		var followerId uint64
+		if error = resultSet.Scan(&followerId); error != nil {
+			return nil, error
+		}
+		followers = append(followers, followerId)
+	}
+
+	return followers, resultSet.Err()
+}
+
+// CreateFollowRequest registers a user's request to follow a private account, once per user,
+// unless either blocks the other
+func (repository UserRepository) CreateFollowRequest(followedId uint64, followerId uint64) error {
+
+	stmt, error := repository.db.Prepare(
+		`insert ignore into follow_requests (user_id, follower_id)
+				select ?, ? from dual where not exists (
+					select 1 from user_blocks b
+						where (b.user_id = ? and b.blocked_id = ?) or (b.user_id = ? and b.blocked_id = ?))`)
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(followedId, followerId, followedId, followerId, followerId, followedId); error != nil {
 		return error
 	}
 
 	return nil
 }
 
-// UnfollowUser removes a register of a user following another user
-func (repository UserRepository) UnfollowUser(followedId uint64, followerId uint64) error {
+// GetFollowRequests gets a page of pending requests to follow a user, oldest first
+func (repository UserRepository) GetFollowRequests(followedId uint64, page models.PageRequest) ([]models.FollowRequest, error) {
 
-	stmt, error := repository.db.Prepare(
-		"delete from followers where user_id = ? and follower_id = ?")
+	after := "true"
+	args := []interface{}{followedId}
+	if page.After != nil {
+		after = "(r.created_at, r.follower_id) > (?, ?)"
+		args = append(args, page.After.Time, page.After.ID)
+	}
+	args = append(args, page.Limit+1)
 
+	resultSet, error := repository.db.Query(
+		`select r.user_id, u.id, u.name, u.nick, u.email, u.is_private, u.created_at, r.created_at
+				from follow_requests r join users u on (u.id = r.follower_id)
+				where r.user_id = ? and `+after+` order by r.created_at, r.follower_id limit ?`,
+		args...)
 	if error != nil {
-		return error
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var requests []models.FollowRequest
+
+	for resultSet.Next() {
+		var request models.FollowRequest
+		if error = resultSet.Scan(&request.UserId, &request.Follower.ID, &request.Follower.Name,
+			&request.Follower.Nick, &request.Follower.Email, &request.Follower.IsPrivate,
+			&request.Follower.CreatedAt, &request.CreatedAt); error != nil {
+			return nil, error
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// ApproveFollowRequest turns a request to follow a user into a follower, telling if it existed
+// and neither user blocks the other
+func (repository UserRepository) ApproveFollowRequest(followedId uint64, followerId uint64) (bool, error) {
+
+	transaction, error := repository.db.Begin()
+	if error != nil {
+		return false, error
+	}
+	defer transaction.Rollback()
+
+	deletion, error := transaction.Exec(
+		`delete from follow_requests where user_id = ? and follower_id = ? and not exists (
+					select 1 from user_blocks b
+						where (b.user_id = ? and b.blocked_id = ?) or (b.user_id = ? and b.blocked_id = ?))`,
+		followedId, followerId, followedId, followerId, followerId, followedId)
+	if error != nil {
+		return false, error
+	}
+
+	rows, error := deletion.RowsAffected()
+	if error != nil || rows == 0 {
+		return false, error
+	}
+
+	if _, error = transaction.Exec(
+		`insert ignore into followers (user_id, follower_id)
+				select ?, ? from dual where not exists (
+					select 1 from user_blocks b
+						where (b.user_id = ? and b.blocked_id = ?) or (b.user_id = ? and b.blocked_id = ?))`,
+		followedId, followerId, followedId, followerId, followerId, followedId); error != nil {
+		return false, error
+	}
+
+	return true, transaction.Commit()
+}
+
+// DeleteFollowRequest rejects a request to follow a user, telling if it existed
+func (repository UserRepository) DeleteFollowRequest(followedId uint64, followerId uint64) (bool, error) {
+
+	stmt, error := repository.db.Prepare(
+		"delete from follow_requests where user_id = ? and follower_id = ?")
+	if error != nil {
+		return false, error
 	}
 	defer stmt.Close()
 
-	insert, error := stmt.Exec(followedId, followerId)
+	deletion, error := stmt.Exec(followedId, followerId)
 	if error != nil {
-		return error
+		return false, error
 	}
-	_, error = insert.LastInsertId()
+
+	rows, error := deletion.RowsAffected()
+	if error != nil {
+		return false, error
+	}
+
+	return rows == 1, nil
+}
+
+// UpdateUserPrivacy makes a user account private or public, approving pending requests to
+// follow it when it becomes public, but those of users it blocks or is blocked by
+func (repository UserRepository) UpdateUserPrivacy(userId uint64, isPrivate bool) error {
+
+	transaction, error := repository.db.Begin()
 	if error != nil {
 		return error
 	}
+	defer transaction.Rollback()
 
-	return nil
+	if _, error = transaction.Exec("update users set is_private = ? where id = ?", isPrivate, userId); error != nil {
+		return error
+	}
+
+	if !isPrivate {
+		if _, error = transaction.Exec(
+			`insert ignore into followers (user_id, follower_id) 
+					select r.user_id, r.follower_id from follow_requests r where r.user_id = ? and not exists (
+						select 1 from user_blocks b where (b.user_id = r.user_id and b.blocked_id = r.follower_id)
+							or (b.user_id = r.follower_id and b.blocked_id = r.user_id))`, userId); error != nil {
+			return error
+		}
+
+		if _, error = transaction.Exec("delete from follow_requests where user_id = ?", userId); error != nil {
+			return error
+		}
+	}
+
+	return transaction.Commit()
 }
 
-// GetFollowersForUserId searches followers of a user
-func (repository UserRepository) GetFollowersForUserId(followedId uint64) ([]models.User, error) {
+// GetFollowersForUserId searches a page of followers of a user, by id
+func (repository UserRepository) GetFollowersForUserId(followedId uint64, page models.PageRequest) ([]models.User, error) {
 
 	resultSet, error := repository.db.Query(
-		`select u.id, u.name, u.nick, u.email, u.created_at 
+		`select u.id, u.name, u.nick, u.email, u.is_private, u.created_at 
 				from users u join followers f on (u.id = f.follower_id) 
-				where f.user_id = ?`,
-		followedId)
+				where f.user_id = ? and f.follower_id > ? order by f.follower_id limit ?`,
+		followedId, afterId(page), page.Limit+1)
 	if error != nil {
 		return nil, error
 	}
@@ -224,7 +455,7 @@ func (repository UserRepository) GetFollowersForUserId(followedId uint64) ([]mod
 
 	for resultSet.Next() {
 		var user models.User
-		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.CreatedAt, ); error != nil {
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.IsPrivate, &user.CreatedAt, ); error != nil {
 			return nil, error
 		}
 		users = append(users, user)
@@ -233,14 +464,14 @@ func (repository UserRepository) GetFollowersForUserId(followedId uint64) ([]mod
 	return users, nil
 }
 
-// GetFollowedUsersForUserId searches users followed by a user
-func (repository UserRepository) GetFollowedUsersForUserId(followerId uint64) ([]models.User, error) {
+// GetFollowedUsersForUserId searches a page of users followed by a user, by id
+func (repository UserRepository) GetFollowedUsersForUserId(followerId uint64, page models.PageRequest) ([]models.User, error) {
 
 	resultSet, error := repository.db.Query(
-		`select u.id, u.name, u.nick, u.email, u.created_at 
+		`select u.id, u.name, u.nick, u.email, u.is_private, u.created_at 
 				from users u join followers f on (u.id = f.user_id) 
-				where f.follower_id = ?`,
-		followerId)
+				where f.follower_id = ? and f.user_id > ? order by f.user_id limit ?`,
+		followerId, afterId(page), page.Limit+1)
 	if error != nil {
 		return nil, error
 	}
@@ -250,7 +481,7 @@ func (repository UserRepository) GetFollowedUsersForUserId(followerId uint64) ([
 
 	for resultSet.Next() {
 		var user models.User
-		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.CreatedAt, ); error != nil {
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.IsPrivate, &user.CreatedAt, ); error != nil {
 			return nil, error
 		}
 		users = append(users, user)
@@ -276,6 +507,226 @@ func (repository UserRepository) UpdateUserPassword(userId uint64, password stri
 	return nil
 }
 
+// VerifyUserEmail marks a user's email as verified, unless it is no longer email
+func (repository UserRepository) VerifyUserEmail(userId uint64, email string) error {
+
+	stmt, error := repository.db.Prepare(
+		"update users set email_verified_at = current_timestamp() where id = ? and email = ? and email_verified_at is null")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId, email); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// UpdateUserRole assigns a role to a user
+func (repository UserRepository) UpdateUserRole(userId uint64, role security.Role) error {
+
+	stmt, error := repository.db.Prepare("update users set role = ? where id = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(string(role), userId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// SuspendUser suspends a user, keeping the time of a previous suspension
+func (repository UserRepository) SuspendUser(userId uint64) error {
+
+	stmt, error := repository.db.Prepare(
+		"update users set suspended_at = current_timestamp() where id = ? and suspended_at is null")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// UnsuspendUser lifts a user's suspension
+func (repository UserRepository) UnsuspendUser(userId uint64) error {
+
+	stmt, error := repository.db.Prepare("update users set suspended_at = null where id = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// BlockUser registers a user blocking another user, removing follows and follow requests
+// between both users
+func (repository UserRepository) BlockUser(userId uint64, blockedId uint64) error {
+
+	transaction, error := repository.db.Begin()
+	if error != nil {
+		return error
+	}
+	defer transaction.Rollback()
+
+	if _, error = transaction.Exec(
+		"insert ignore into user_blocks (user_id, blocked_id) values (?, ?)", userId, blockedId); error != nil {
+		return error
+	}
+
+	for _, table := range []string{"followers", "follow_requests"} {
+		if _, error = transaction.Exec(
+			"delete from "+table+" where (user_id = ? and follower_id = ?) or (user_id = ? and follower_id = ?)",
+			userId, blockedId, blockedId, userId); error != nil {
+			return error
+		}
+	}
+
+	return transaction.Commit()
+}
+
+// UnblockUser removes a register of a user blocking another user
+func (repository UserRepository) UnblockUser(userId uint64, blockedId uint64) error {
+
+	stmt, error := repository.db.Prepare("delete from user_blocks where user_id = ? and blocked_id = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId, blockedId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// IsBlocked tells if either user blocks the other
+func (repository UserRepository) IsBlocked(userId uint64, otherId uint64) (bool, error) {
+
+	resultSet, error := repository.db.Query(
+		`select 1 from user_blocks b 
+				where (b.user_id = ? and b.blocked_id = ?) or (b.user_id = ? and b.blocked_id = ?)`,
+		userId, otherId, otherId, userId)
+	if error != nil {
+		return false, error
+	}
+	defer resultSet.Close()
+
+	return resultSet.Next(), resultSet.Err()
+}
+
+// GetBlockedUsers searches a page of users blocked by a user, by id
+func (repository UserRepository) GetBlockedUsers(userId uint64, page models.PageRequest) ([]models.User, error) {
+
+	resultSet, error := repository.db.Query(
+		`select u.id, u.name, u.nick, u.email, u.is_private, u.created_at 
+				from users u join user_blocks b on (u.id = b.blocked_id) 
+				where b.user_id = ? and b.blocked_id > ? order by b.blocked_id limit ?`,
+		userId, afterId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var users []models.User
+
+	for resultSet.Next() {
+		var user models.User
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.IsPrivate, &user.CreatedAt); error != nil {
+			return nil, error
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// MuteUser registers a user muting another user, once per user
+func (repository UserRepository) MuteUser(userId uint64, mutedId uint64) error {
+
+	stmt, error := repository.db.Prepare("insert ignore into user_mutes (user_id, muted_id) values (?, ?)")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId, mutedId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// UnmuteUser removes a register of a user muting another user
+func (repository UserRepository) UnmuteUser(userId uint64, mutedId uint64) error {
+
+	stmt, error := repository.db.Prepare("delete from user_mutes where user_id = ? and muted_id = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(userId, mutedId); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// IsMuted tells if a user mutes another user
+func (repository UserRepository) IsMuted(userId uint64, mutedId uint64) (bool, error) {
+
+	resultSet, error := repository.db.Query(
+		"select 1 from user_mutes m where m.user_id = ? and m.muted_id = ?", userId, mutedId)
+	if error != nil {
+		return false, error
+	}
+	defer resultSet.Close()
+
+	return resultSet.Next(), resultSet.Err()
+}
+
+// GetMutedUsers searches a page of users muted by a user, by id
+func (repository UserRepository) GetMutedUsers(userId uint64, page models.PageRequest) ([]models.User, error) {
+
+	resultSet, error := repository.db.Query(
+		`select u.id, u.name, u.nick, u.email, u.is_private, u.created_at 
+				from users u join user_mutes m on (u.id = m.muted_id) 
+				where m.user_id = ? and m.muted_id > ? order by m.muted_id limit ?`,
+		userId, afterId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	var users []models.User
+
+	for resultSet.Next() {
+		var user models.User
+		if error = resultSet.Scan(&user.ID, &user.Name, &user.Nick, &user.Email, &user.IsPrivate, &user.CreatedAt); error != nil {
+			return nil, error
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
 // NewUserRepository factory
 func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db}
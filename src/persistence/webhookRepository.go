@@ -0,0 +1,316 @@
+package persistence
+
+import (
+	"database/sql"
+	"devbook/src/models"
+	"strings"
+	"time"
+)
+
+// webhookColumns are the columns read by scanWebhook
+const webhookColumns = "id, user_id, url, event_types, secret, created_at"
+
+// deliveryColumns are the columns read by scanDelivery
+const deliveryColumns = `id, webhook_id, event_type, payload, status, attempts, response_status, last_error,
+		next_attempt_at, delivered_at, created_at`
+
+// WebhookRepository persists webhooks of users and the queue of their deliveries
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+// CreateWebhook registers a webhook of a user
+func (repository WebhookRepository) CreateWebhook(webhook models.Webhook) (uint64, error) {
+
+	stmt, error := repository.db.Prepare(
+		"insert into webhooks (user_id, url, event_types, secret) values (?, ?, ?, ?)")
+	if error != nil {
+		return 0, error
+	}
+	defer stmt.Close()
+
+	insert, error := stmt.Exec(webhook.UserId, webhook.URL, strings.Join(webhook.EventTypes, ","), webhook.Secret)
+	if error != nil {
+		return 0, error
+	}
+
+	id, error := insert.LastInsertId()
+	if error != nil {
+		return 0, error
+	}
+
+	return uint64(id), nil
+}
+
+// GetWebhookById gets a specific webhook by its id, with its secret
+func (repository WebhookRepository) GetWebhookById(id uint64) (models.Webhook, error) {
+
+	var webhook models.Webhook
+
+	resultSet, error := repository.db.Query("select "+webhookColumns+" from webhooks where id = ?", id)
+	if error != nil {
+		return webhook, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if webhook, error = scanWebhook(resultSet); error != nil {
+			return webhook, error
+		}
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks gets a page of the webhooks of a user, by id
+func (repository WebhookRepository) GetWebhooks(userId uint64, page models.PageRequest) ([]models.Webhook, error) {
+
+	resultSet, error := repository.db.Query(
+		"select "+webhookColumns+" from webhooks where user_id = ? and id > ? order by id limit ?",
+		userId, afterId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	return scanWebhooks(resultSet)
+}
+
+// GetSubscribedWebhooks gets the webhooks of a user subscribed to an event type, with their secrets
+func (repository WebhookRepository) GetSubscribedWebhooks(userId uint64, eventType string) ([]models.Webhook, error) {
+
+	resultSet, error := repository.db.Query(
+		"select "+webhookColumns+" from webhooks where user_id = ? and find_in_set(?, event_types) order by id",
+		userId, eventType)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	return scanWebhooks(resultSet)
+}
+
+// DeleteWebhook deletes a webhook along with its deliveries
+func (repository WebhookRepository) DeleteWebhook(id uint64) error {
+
+	stmt, error := repository.db.Prepare("delete from webhooks where id = ?")
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(id); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+// CreateDelivery queues a delivery to a webhook
+func (repository WebhookRepository) CreateDelivery(delivery models.WebhookDelivery) (uint64, error) {
+
+	stmt, error := repository.db.Prepare(
+		`insert into webhook_deliveries (webhook_id, event_type, payload, status, next_attempt_at)
+				values (?, ?, ?, ?, ?)`)
+	if error != nil {
+		return 0, error
+	}
+	defer stmt.Close()
+
+	insert, error := stmt.Exec(delivery.WebhookId, delivery.EventType, string(delivery.Payload),
+		delivery.Status, delivery.NextAttemptAt)
+	if error != nil {
+		return 0, error
+	}
+
+	id, error := insert.LastInsertId()
+	if error != nil {
+		return 0, error
+	}
+
+	return uint64(id), nil
+}
+
+// GetDeliveryById gets a specific delivery by its id
+func (repository WebhookRepository) GetDeliveryById(id uint64) (models.WebhookDelivery, error) {
+
+	var delivery models.WebhookDelivery
+
+	resultSet, error := repository.db.Query("select "+deliveryColumns+" from webhook_deliveries where id = ?", id)
+	if error != nil {
+		return delivery, error
+	}
+	defer resultSet.Close()
+
+	if resultSet.Next() {
+		if delivery, error = scanDelivery(resultSet); error != nil {
+			return delivery, error
+		}
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveries gets a page of the deliveries to a webhook, most recent first
+func (repository WebhookRepository) GetDeliveries(webhookId uint64, page models.PageRequest) ([]models.WebhookDelivery, error) {
+
+	resultSet, error := repository.db.Query(
+		"select "+deliveryColumns+" from webhook_deliveries where webhook_id = ? and id < ? order by id desc limit ?",
+		webhookId, beforeId(page), page.Limit+1)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	return scanDeliveries(resultSet)
+}
+
+// GetPendingDeliveries gets pending deliveries unclaimed at now, except those to skipped webhooks,
+// soonest next attempt first, up to limit
+func (repository WebhookRepository) GetPendingDeliveries(now time.Time, skippedWebhookIds []uint64,
+	limit int) ([]models.WebhookDelivery, error) {
+
+	args := []interface{}{models.DeliveryPending, now}
+	skipped := ""
+	if len(skippedWebhookIds) > 0 {
+		skipped = " and webhook_id not in (?" + strings.Repeat(", ?", len(skippedWebhookIds)-1) + ")"
+		for _, webhookId := range skippedWebhookIds {
+			args = append(args, webhookId)
+		}
+	}
+	args = append(args, limit)
+
+	resultSet, error := repository.db.Query(
+		"select "+deliveryColumns+" from webhook_deliveries where status = ? and (claimed_until is null or claimed_until <= ?)"+
+			skipped+" order by next_attempt_at, id limit ?", args...)
+	if error != nil {
+		return nil, error
+	}
+	defer resultSet.Close()
+
+	return scanDeliveries(resultSet)
+}
+
+// ClaimDelivery claims a pending delivery until a time unless it is claimed at now, telling whether
+// it was claimed
+func (repository WebhookRepository) ClaimDelivery(id uint64, now time.Time, until time.Time) (bool, error) {
+
+	stmt, error := repository.db.Prepare(
+		`update webhook_deliveries set claimed_until = ?
+				where id = ? and status = ? and (claimed_until is null or claimed_until <= ?)`)
+	if error != nil {
+		return false, error
+	}
+	defer stmt.Close()
+
+	result, error := stmt.Exec(until, id, models.DeliveryPending, now)
+	if error != nil {
+		return false, error
+	}
+
+	claimed, error := result.RowsAffected()
+	if error != nil {
+		return false, error
+	}
+
+	return claimed == 1, nil
+}
+
+// UpdateDelivery records the outcome of an attempt of a delivery, releasing its claim
+func (repository WebhookRepository) UpdateDelivery(delivery models.WebhookDelivery) error {
+
+	responseStatus := sql.NullInt64{Int64: int64(delivery.ResponseStatus), Valid: delivery.ResponseStatus != 0}
+	lastError := sql.NullString{String: delivery.LastError, Valid: delivery.LastError != ""}
+
+	stmt, error := repository.db.Prepare(
+		`update webhook_deliveries set status = ?, attempts = ?, response_status = ?, last_error = ?,
+				next_attempt_at = ?, delivered_at = ?, claimed_until = null where id = ?`)
+	if error != nil {
+		return error
+	}
+	defer stmt.Close()
+
+	if _, error = stmt.Exec(delivery.Status, delivery.Attempts, responseStatus,
+		lastError, delivery.NextAttemptAt, delivery.DeliveredAt, delivery.ID); error != nil {
+		return error
+	}
+
+	return nil
+}
+
+func scanWebhooks(resultSet *sql.Rows) ([]models.Webhook, error) {
+
+	var webhooks []models.Webhook
+
+	for resultSet.Next() {
+		webhook, error := scanWebhook(resultSet)
+		if error != nil {
+			return nil, error
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+func scanWebhook(resultSet *sql.Rows) (models.Webhook, error) {
+
+	var webhook models.Webhook
+	var eventTypes string
+
+	if error := resultSet.Scan(&webhook.ID, &webhook.UserId, &webhook.URL, &eventTypes,
+		&webhook.Secret, &webhook.CreatedAt); error != nil {
+		return webhook, error
+	}
+
+	webhook.EventTypes = strings.Split(eventTypes, ",")
+
+	return webhook, nil
+}
+
+func scanDeliveries(resultSet *sql.Rows) ([]models.WebhookDelivery, error) {
+
+	var deliveries []models.WebhookDelivery
+
+	for resultSet.Next() {
+		delivery, error := scanDelivery(resultSet)
+		if error != nil {
+			return nil, error
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func scanDelivery(resultSet *sql.Rows) (models.WebhookDelivery, error) {
+
+	var delivery models.WebhookDelivery
+	var payload string
+	var responseStatus sql.NullInt64
+	var lastError sql.NullString
+	var nextAttemptAt, deliveredAt sql.NullTime
+
+	if error := resultSet.Scan(&delivery.ID, &delivery.WebhookId, &delivery.EventType, &payload,
+		&delivery.Status, &delivery.Attempts, &responseStatus, &lastError,
+		&nextAttemptAt, &deliveredAt, &delivery.CreatedAt); error != nil {
+		return delivery, error
+	}
+
+	delivery.Payload = []byte(payload)
+	delivery.ResponseStatus = int(responseStatus.Int64)
+	delivery.LastError = lastError.String
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+
+	return delivery, nil
+}
+
+// NewWebhookRepository factory
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db}
+}
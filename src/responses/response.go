@@ -1,12 +1,13 @@
 package responses
 
 import (
+	"devbook/src/logging"
 	"encoding/json"
-	"log"
 	"net/http"
 )
 
-// JsonResponse returns a JSON response representation
+// JsonResponse returns a JSON response representation, logging encoding failures since the status
+// is already sent
 func JsonResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -16,7 +17,7 @@ func JsonResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 
 	if error := json.NewEncoder(w).Encode(data); error != nil {
-		log.Fatal(error)
+		logging.Error("failed to encode response", logging.Fields{"status": statusCode, "error": error})
 	}
 }
 
@@ -1,12 +1,15 @@
 package router
 
 import (
+	"devbook/src/mail"
+	"devbook/src/persistence"
 	"devbook/src/router/routes"
+	"devbook/src/webhooks"
 	"github.com/gorilla/mux"
 )
 
 // GetRouter return a router with configured routes
-func GetRouter() *mux.Router {
+func GetRouter(repositories persistence.Repositories, mailer mail.Mailer, dispatcher *webhooks.Dispatcher) *mux.Router {
 	router := mux.NewRouter()
-	return routes.ConfigureRoutes(router)
-}
\ No newline at end of file
+	return routes.ConfigureRoutes(router, repositories, mailer, dispatcher)
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// commentRoutes returns routes handled by the comment controller
+func commentRoutes(controller *controllers.CommentController) []Route {
+	return []Route{
+		{
+			URI:                    "/publications/{id}/comments",
+			Method:                 http.MethodPost,
+			Function:               controller.CreateComment,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/comments",
+			Method:                 http.MethodGet,
+			Function:               controller.GetComments,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/comments/{commentId}/thread",
+			Method:                 http.MethodGet,
+			Function:               controller.GetCommentThread,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/comments/{commentId}",
+			Method:                 http.MethodPut,
+			Function:               controller.UpdateComment,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/comments/{commentId}",
+			Method:                 http.MethodDelete,
+			Function:               controller.DeleteComment,
+			RequiresAuthentication: true,
+		},
+	}
+}
@@ -0,0 +1,291 @@
+package routes
+
+import (
+	"devbook/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// comment comments on publication as user
+func (server *testServer) comment(user testUser, publication models.Publication, content string) models.Comment {
+	server.t.Helper()
+
+	var comment models.Comment
+	server.decode(server.expect(http.StatusCreated, http.MethodPost,
+		fmt.Sprintf("/publications/%d/comments", publication.ID), user.token,
+		map[string]string{"content": content}), &comment)
+
+	return comment
+}
+
+// reply replies to parent as user
+func (server *testServer) reply(user testUser, parent models.Comment, content string) models.Comment {
+	server.t.Helper()
+
+	var comment models.Comment
+	server.decode(server.expect(http.StatusCreated, http.MethodPost,
+		fmt.Sprintf("/publications/%d/comments", parent.PublicationId), user.token,
+		map[string]interface{}{"content": content, "parentId": parent.ID}), &comment)
+
+	return comment
+}
+
+func TestCreateAndListComments(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	publication := server.publish(ada, "Notes")
+	uri := fmt.Sprintf("/publications/%d/comments", publication.ID)
+
+	server.expect(http.StatusBadRequest, http.MethodPost, uri, grace.token, map[string]string{"content": "  "})
+	server.expect(http.StatusNotFound, http.MethodPost, "/publications/999/comments", grace.token,
+		map[string]string{"content": "Lost"})
+
+	first := server.comment(grace, publication, " Great notes ")
+	if first.ID == 0 || first.Content != "Great notes" || first.AuthorNick != "grace" {
+		t.Fatalf("unexpected comment: %+v", first)
+	}
+	second := server.comment(ada, publication, "Thanks")
+
+	var comments []models.Comment
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, uri, grace.token, nil), &comments)
+	if len(comments) != 2 || comments[0].ID != first.ID || comments[1].ID != second.ID {
+		t.Fatalf("unexpected comments: %+v", comments)
+	}
+
+	var stored models.Publication
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d", publication.ID), grace.token, nil), &stored)
+	if stored.CommentCount != 2 {
+		t.Fatalf("expected 2 comments, got %d", stored.CommentCount)
+	}
+
+	server.expect(http.StatusNotFound, http.MethodGet, "/publications/999/comments", grace.token, nil)
+}
+
+func TestUpdateAndDeleteComments(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+	publication := server.publish(ada, "Notes")
+	other := server.publish(linus, "Other")
+
+	comment := server.comment(grace, publication, "First draft")
+	uri := fmt.Sprintf("/publications/%d/comments/%d", publication.ID, comment.ID)
+	update := map[string]string{"content": "Edited"}
+
+	server.expect(http.StatusForbidden, http.MethodPut, uri, linus.token, update)
+	server.expect(http.StatusNotFound, http.MethodPut,
+		fmt.Sprintf("/publications/%d/comments/%d", other.ID, comment.ID), linus.token, update)
+	server.expect(http.StatusNoContent, http.MethodPut, uri, grace.token, update)
+
+	var comments []models.Comment
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d/comments", publication.ID), ada.token, nil), &comments)
+	if len(comments) != 1 || comments[0].Content != "Edited" || comments[0].UpdatedAt == nil {
+		t.Fatalf("unexpected comments: %+v", comments)
+	}
+
+	server.expect(http.StatusForbidden, http.MethodDelete, uri, linus.token, nil)
+	server.expect(http.StatusNoContent, http.MethodDelete, uri, ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodDelete, uri, ada.token, nil)
+}
+
+func TestCommentsCascade(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	publication := server.publish(ada, "Notes")
+	kept := server.publish(ada, "Kept")
+
+	removed := server.comment(grace, publication, "On a removed publication")
+	server.comment(grace, kept, "By a removed user")
+
+	server.expect(http.StatusNoContent, http.MethodDelete,
+		fmt.Sprintf("/publications/%d", publication.ID), ada.token, nil)
+	if comment, _ := server.repositories.Comments.GetCommentById(removed.ID); comment.ID != 0 {
+		t.Fatalf("expected comment removed along with its publication: %+v", comment)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodDelete, fmt.Sprintf("/users/%d", grace.id), grace.token, nil)
+
+	var stored models.Publication
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d", kept.ID), ada.token, nil), &stored)
+	if stored.CommentCount != 0 {
+		t.Fatalf("expected comments removed along with their author, got %d", stored.CommentCount)
+	}
+}
+
+func TestCommentThread(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	publication := server.publish(ada, "Notes")
+	other := server.publish(ada, "Other")
+
+	root := server.comment(ada, publication, "Root")
+	first := server.reply(grace, root, "First")
+	server.reply(ada, root, "Second")
+	third := server.reply(ada, root, "Third")
+	nested := server.reply(ada, first, "Nested")
+	deeper := server.reply(grace, nested, "Deeper")
+	server.reply(ada, deeper, "Deepest")
+
+	server.expect(http.StatusBadRequest, http.MethodPost,
+		fmt.Sprintf("/publications/%d/comments", other.ID), ada.token,
+		map[string]interface{}{"content": "Misplaced", "parentId": root.ID})
+
+	uri := fmt.Sprintf("/publications/%d/comments/%d/thread", publication.ID, root.ID)
+
+	var thread models.CommentThread
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, ada.token, nil), &thread)
+	if thread.ID != root.ID || thread.ReplyCount != 3 || len(thread.Replies) != 2 ||
+		thread.Replies[0].ID != first.ID || thread.Replies[0].ParentId != root.ID || thread.NextCursor == "" {
+		t.Fatalf("unexpected thread: %+v", thread)
+	}
+
+	level := thread.Replies[0]
+	for _, expected := range []uint64{nested.ID, deeper.ID} {
+		if len(level.Replies) != 1 || level.Replies[0].ID != expected {
+			t.Fatalf("expected reply %d, got %+v", expected, level.Replies)
+		}
+		level = level.Replies[0]
+	}
+	if level.ReplyCount != 1 || len(level.Replies) != 0 {
+		t.Fatalf("expected replies beyond the maximum depth counted but omitted: %+v", level)
+	}
+
+	next := thread.NextCursor
+	thread = models.CommentThread{}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri+"?depth=0", ada.token, nil), &thread)
+	if len(thread.Replies) != 0 || thread.ReplyCount != 3 {
+		t.Fatalf("expected replies counted but omitted at depth 0: %+v", thread)
+	}
+
+	thread = models.CommentThread{}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri+"?cursor="+next, ada.token, nil), &thread)
+	if len(thread.Replies) != 1 || thread.Replies[0].ID != third.ID || thread.NextCursor != "" {
+		t.Fatalf("unexpected second page of replies: %+v", thread.Replies)
+	}
+
+	for index := 0; index < 2; index++ {
+		server.reply(grace, first, "More")
+	}
+	thread = models.CommentThread{}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, ada.token, nil), &thread)
+	if level := thread.Replies[0]; level.ReplyCount != 3 || len(level.Replies) != 2 || level.NextCursor == "" {
+		t.Fatalf("expected a page of nested replies with a cursor: %+v", level)
+	}
+
+	var nestedPage models.CommentThread
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d/comments/%d/thread?cursor=%s", publication.ID, first.ID, thread.Replies[0].NextCursor),
+		ada.token, nil), &nestedPage)
+	if len(nestedPage.Replies) != 1 || nestedPage.Replies[0].Content != "More" {
+		t.Fatalf("expected the next page of nested replies: %+v", nestedPage)
+	}
+
+	server.expect(http.StatusBadRequest, http.MethodGet, uri+"?limit=-1", ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet,
+		fmt.Sprintf("/publications/%d/comments/%d/thread", other.ID, root.ID), ada.token, nil)
+
+	server.expect(http.StatusNoContent, http.MethodDelete, fmt.Sprintf("/users/%d", grace.id), grace.token, nil)
+
+	var comments []models.Comment
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d/comments", publication.ID), ada.token, nil), &comments)
+	if len(comments) != 3 {
+		t.Fatalf("expected replies to removed comments removed too: %+v", comments)
+	}
+}
+
+// countReplies counts the replies in a comment thread
+func countReplies(thread models.CommentThread) int {
+	count := len(thread.Replies)
+	for _, reply := range thread.Replies {
+		count += countReplies(reply)
+	}
+	return count
+}
+
+func TestCommentThreadMaxReplies(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	publication := server.publish(ada, "Notes")
+
+	root := server.comment(ada, publication, "Root")
+	level := []models.Comment{root}
+	for depth := 0; depth < 3; depth++ {
+		var next []models.Comment
+		for _, parent := range level {
+			for index := 0; index < 3; index++ {
+				next = append(next, server.reply(ada, parent, "Reply"))
+			}
+		}
+		level = next
+	}
+
+	var thread models.CommentThread
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d/comments/%d/thread", publication.ID, root.ID), ada.token, nil), &thread)
+	if count := countReplies(thread); count != 10 || thread.ReplyCount != 3 || thread.NextCursor == "" {
+		t.Fatalf("expected 10 replies at most, got %d: %+v", count, thread)
+	}
+
+	var expanded, unexpanded int
+	for _, reply := range thread.Replies {
+		for _, nested := range reply.Replies {
+			if len(nested.Replies) > 0 {
+				expanded++
+			} else if nested.ReplyCount == 3 {
+				unexpanded++
+			}
+		}
+	}
+	if expanded != 2 || unexpanded != 2 {
+		t.Fatalf("expected replies beyond the maximum counted but omitted, got %d and %d", expanded, unexpanded)
+	}
+}
+
+func TestCommentThreadVisibility(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+	alan := server.signUp("alan")
+
+	var followersOnly models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Followers", "content": "For followers", "visibility": "followers",
+	}), &followersOnly)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), linus.token, nil)
+
+	root := server.comment(ada, followersOnly, "Root")
+	server.reply(grace, root, "Kept")
+	muted := server.reply(linus, root, "Muted")
+	server.reply(ada, muted, "Below a muted reply")
+
+	uri := fmt.Sprintf("/publications/%d/comments/%d/thread", followersOnly.ID, root.ID)
+	server.expect(http.StatusNotFound, http.MethodGet, uri, alan.token, nil)
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/mute", linus.id), grace.token, nil)
+
+	var thread models.CommentThread
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, grace.token, nil), &thread)
+	if thread.ReplyCount != 1 || len(thread.Replies) != 1 || thread.Replies[0].Content != "Kept" {
+		t.Fatalf("expected replies of muted users left out: %+v", thread)
+	}
+
+	var comments []models.Comment
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d/comments", followersOnly.ID), grace.token, nil), &comments)
+	for _, comment := range comments {
+		if comment.AuthorId == linus.id {
+			t.Fatalf("expected comments of muted users left out: %+v", comments)
+		}
+	}
+}
@@ -5,13 +5,32 @@ import (
 	"net/http"
 )
 
-var loginRoutes = []Route {
-	{
-		URI: "/login",
-		Method: http.MethodPost,
-		Function: controllers.Login,
-		RequiresAuthentication: false,
-	},
+// loginRoutes returns routes handled by the login controller
+func loginRoutes(controller *controllers.LoginController) []Route {
+	return []Route{
+		{
+			URI:                    "/login",
+			Method:                 http.MethodPost,
+			Function:               controller.Login,
+			RequiresAuthentication: false,
+		},
+		{
+			URI:                    "/login/mfa",
+			Method:                 http.MethodPost,
+			Function:               controller.LoginMFA,
+			RequiresAuthentication: false,
+		},
+		{
+			URI:                    "/token/refresh",
+			Method:                 http.MethodPost,
+			Function:               controller.RefreshToken,
+			RequiresAuthentication: false,
+		},
+		{
+			URI:                    "/logout",
+			Method:                 http.MethodPost,
+			Function:               controller.Logout,
+			RequiresAuthentication: false,
+		},
+	}
 }
-
-
@@ -0,0 +1,191 @@
+package routes
+
+import (
+	"devbook/src/config"
+	"devbook/src/models"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLogin(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	if user.token == "" {
+		t.Fatal("expected a token on successful login")
+	}
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]string{
+		"email":    user.email,
+		"password": "wrong-password",
+	})
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]string{
+		"email":    "nobody@devbook.com",
+		"password": user.password,
+	})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/login", "", map[string]string{
+		"email": user.email,
+	})
+}
+
+func TestLoginErrorsAreUniform(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	wrongPassword := server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]string{
+		"email":    user.email,
+		"password": "wrong-password",
+	})
+	unknownEmail := server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]string{
+		"email":    "nobody@devbook.com",
+		"password": user.password,
+	})
+
+	if string(wrongPassword) != string(unknownEmail) {
+		t.Fatalf("expected the same error for unknown emails and wrong passwords, got %s and %s",
+			wrongPassword, unknownEmail)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+	wrongCredential := map[string]string{"email": user.email, "password": "wrong-password"}
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", wrongCredential)
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", wrongCredential)
+	server.login(user.email, user.password)
+
+	for attempt := 0; attempt < config.LoginMaxFailures; attempt++ {
+		server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", wrongCredential)
+	}
+
+	response, error := http.Post(server.URL+"/login", "application/json",
+		strings.NewReader(fmt.Sprintf(`{"email": %q, "password": %q}`, user.email, user.password)))
+	if error != nil {
+		t.Fatal(error)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusTooManyRequests || response.Header.Get("Retry-After") != "60" {
+		t.Fatalf("expected a locked out account, got %d with Retry-After %q",
+			response.StatusCode, response.Header.Get("Retry-After"))
+	}
+
+	other := server.signUp("grace")
+	server.login(other.email, other.password)
+}
+
+func TestLoginLockoutConcurrentAttempts(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+	body := fmt.Sprintf(`{"email": %q, "password": "wrong-password"}`, user.email)
+
+	statuses := make(chan int, 3*config.LoginMaxFailures)
+	var attempts sync.WaitGroup
+	for attempt := 0; attempt < cap(statuses); attempt++ {
+		attempts.Add(1)
+		go func() {
+			defer attempts.Done()
+			response, error := http.Post(server.URL+"/login", "application/json", strings.NewReader(body))
+			if error != nil {
+				statuses <- 0
+				return
+			}
+			response.Body.Close()
+			statuses <- response.StatusCode
+		}()
+	}
+	attempts.Wait()
+	close(statuses)
+
+	counted := make(map[int]int)
+	for status := range statuses {
+		counted[status]++
+	}
+	if counted[http.StatusUnauthorized] != config.LoginMaxFailures ||
+		counted[http.StatusTooManyRequests] != 2*config.LoginMaxFailures {
+		t.Fatalf("expected %d concurrent attempts checked, got %+v", config.LoginMaxFailures, counted)
+	}
+}
+
+func TestLoginLockoutPerAddress(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	for attempt := 0; attempt < config.LoginMaxFailuresPerAddress; attempt++ {
+		server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "", map[string]string{
+			"email":    fmt.Sprintf("user%d@devbook.com", attempt),
+			"password": "guess",
+		})
+	}
+
+	server.expect(http.StatusTooManyRequests, http.MethodPost, "/login", "", map[string]string{
+		"email":    user.email,
+		"password": user.password,
+	})
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	first := server.authenticate(user.email, user.password)
+	if first.RefreshToken == "" {
+		t.Fatal("expected a refresh token on login")
+	}
+
+	var second models.Token
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: first.RefreshToken}), &second)
+	if second.Token == "" || second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected a rotated token pair: %+v", second)
+	}
+	server.expect(http.StatusOK, http.MethodGet, "/users", second.Token, nil)
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: "unknown"})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{})
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	first := server.authenticate(user.email, user.password)
+	other := server.authenticate(user.email, user.password)
+
+	var second models.Token
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: first.RefreshToken}), &second)
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: second.RefreshToken})
+
+	server.expect(http.StatusOK, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: other.RefreshToken})
+}
+
+func TestLogout(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	first := server.authenticate(user.email, user.password)
+
+	var second models.Token
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: first.RefreshToken}), &second)
+
+	server.expect(http.StatusNoContent, http.MethodPost, "/logout", "",
+		models.RefreshTokenRequest{RefreshToken: second.RefreshToken})
+	server.expect(http.StatusNoContent, http.MethodPost, "/logout", "",
+		models.RefreshTokenRequest{RefreshToken: second.RefreshToken})
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: second.RefreshToken})
+}
@@ -0,0 +1,54 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// messageRoutes returns routes handled by the message controller
+func messageRoutes(controller *controllers.MessageController) []Route {
+	return []Route{
+		{
+			URI:                    "/conversations",
+			Method:                 http.MethodGet,
+			Function:               controller.GetConversations,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/conversations",
+			Method:                 http.MethodPost,
+			Function:               controller.StartConversation,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/conversations/{id}",
+			Method:                 http.MethodGet,
+			Function:               controller.GetConversation,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/conversations/{id}/messages",
+			Method:                 http.MethodGet,
+			Function:               controller.GetMessages,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/conversations/{id}/messages",
+			Method:                 http.MethodPost,
+			Function:               controller.SendMessage,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/conversations/{id}/read",
+			Method:                 http.MethodPost,
+			Function:               controller.MarkConversationRead,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/messages/ws",
+			Method:                 http.MethodGet,
+			Function:               controller.Connect,
+			RequiresAuthentication: true,
+		},
+	}
+}
@@ -0,0 +1,272 @@
+package routes
+
+import (
+	"devbook/src/config"
+	"devbook/src/messaging"
+	"devbook/src/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testSocket is an open direct message WebSocket connection of a user
+type testSocket struct {
+	t          *testing.T
+	connection *websocket.Conn
+}
+
+// testFrame is a frame read from a WebSocket connection
+type testFrame struct {
+	Type           string          `json:"type"`
+	ConversationId uint64          `json:"conversationId"`
+	MessageId      uint64          `json:"messageId"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// socket opens the direct message WebSocket connection of a user
+func (server *testServer) socket(user testUser) *testSocket {
+	server.t.Helper()
+
+	uri := "ws" + strings.TrimPrefix(server.URL, "http") + "/messages/ws?access_token=" + user.token
+	connection, response, error := websocket.DefaultDialer.Dial(uri, nil)
+	if error != nil {
+		server.t.Fatalf("cannot connect to %s: %v", uri, error)
+	}
+	response.Body.Close()
+	server.t.Cleanup(func() { connection.Close() })
+
+	return &testSocket{server.t, connection}
+}
+
+// next reads the next frame of the connection
+func (socket *testSocket) next() testFrame {
+	socket.t.Helper()
+
+	var frame testFrame
+	socket.connection.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if error := socket.connection.ReadJSON(&frame); error != nil {
+		socket.t.Fatalf("cannot read frame: %v", error)
+	}
+	return frame
+}
+
+// send writes a frame to the connection
+func (socket *testSocket) send(frame messaging.Frame) {
+	socket.t.Helper()
+
+	if error := socket.connection.WriteJSON(frame); error != nil {
+		socket.t.Fatalf("cannot write frame: %v", error)
+	}
+}
+
+// befriend makes two users follow each other
+func (server *testServer) befriend(user testUser, other testUser) {
+	server.t.Helper()
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", other.id), user.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", user.id), other.token, nil)
+}
+
+// startConversation starts the conversation of a user with another user
+func (server *testServer) startConversation(user testUser, other testUser) models.Conversation {
+	server.t.Helper()
+
+	var conversation models.Conversation
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/conversations", user.token,
+		models.ConversationRequest{UserId: other.id}), &conversation)
+	return conversation
+}
+
+// conversation gets a conversation as seen by a user
+func (server *testServer) conversation(user testUser, id uint64) models.Conversation {
+	server.t.Helper()
+
+	var conversation models.Conversation
+	server.decode(server.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/conversations/%d", id), user.token, nil),
+		&conversation)
+	return conversation
+}
+
+func TestConversationsRequireMutualFollow(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	request := models.ConversationRequest{UserId: grace.id}
+	server.expect(http.StatusBadRequest, http.MethodPost, "/conversations", ada.token,
+		models.ConversationRequest{UserId: ada.id})
+	server.expect(http.StatusNotFound, http.MethodPost, "/conversations", ada.token,
+		models.ConversationRequest{UserId: 999})
+	server.expect(http.StatusForbidden, http.MethodPost, "/conversations", ada.token, request)
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", grace.id), ada.token, nil)
+	server.expect(http.StatusForbidden, http.MethodPost, "/conversations", ada.token, request)
+
+	config.MessagingRequiresMutualFollow = false
+	defer func() { config.MessagingRequiresMutualFollow = true }()
+
+	conversation := server.startConversation(ada, grace)
+	if conversation.Participant.ID != grace.id || conversation.LastMessage != nil {
+		t.Fatalf("unexpected conversation: %+v", conversation)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/block", ada.id), grace.token, nil)
+	server.expect(http.StatusForbidden, http.MethodPost, "/conversations", ada.token, request)
+	server.expect(http.StatusForbidden, http.MethodPost, fmt.Sprintf("/conversations/%d/messages", conversation.ID),
+		ada.token, models.Message{Content: "Hello?"})
+}
+
+func TestMessages(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+	server.befriend(ada, grace)
+
+	conversation := server.startConversation(ada, grace)
+	if again := server.startConversation(grace, ada); again.ID != conversation.ID || again.Participant.ID != ada.id {
+		t.Fatalf("expected the same conversation, got %+v and %+v", conversation, again)
+	}
+
+	uri := fmt.Sprintf("/conversations/%d", conversation.ID)
+	server.expect(http.StatusNotFound, http.MethodGet, uri, linus.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, uri+"/messages", linus.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, uri+"/messages", linus.token, models.Message{Content: "Hi"})
+	server.expect(http.StatusBadRequest, http.MethodPost, uri+"/messages", ada.token, models.Message{Content: " "})
+	server.expect(http.StatusBadRequest, http.MethodPost, uri+"/messages", ada.token,
+		models.Message{Content: strings.Repeat("a", models.MaxMessageLength+1)})
+
+	var sent []models.Message
+	for index := 1; index <= 3; index++ {
+		var message models.Message
+		server.decode(server.expect(http.StatusCreated, http.MethodPost, uri+"/messages", ada.token,
+			models.Message{Content: fmt.Sprintf("Message %d", index)}), &message)
+		sent = append(sent, message)
+	}
+
+	if seen := server.conversation(grace, conversation.ID); seen.UnreadCount != 3 ||
+		seen.LastMessage == nil || seen.LastMessage.ID != sent[2].ID {
+		t.Fatalf("expected 3 unread messages of grace: %+v", seen)
+	}
+	if seen := server.conversation(ada, conversation.ID); seen.UnreadCount != 0 || seen.LastReadMessageId != sent[2].ID {
+		t.Fatalf("expected no unread message of ada: %+v", seen)
+	}
+
+	var page []models.Message
+	next := server.decodePage(server.expect(http.StatusOK, http.MethodGet, uri+"/messages?limit=2", grace.token, nil), &page)
+	if len(page) != 2 || page[0].ID != sent[2].ID || page[1].ID != sent[1].ID || next == "" {
+		t.Fatalf("unexpected first page of messages: %+v", page)
+	}
+	page = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, uri+"/messages?cursor="+next, grace.token, nil), &page)
+	if len(page) != 1 || page[0].ID != sent[0].ID {
+		t.Fatalf("unexpected second page of messages: %+v", page)
+	}
+
+	var receipt models.ReadReceipt
+	server.decode(server.expect(http.StatusOK, http.MethodPost, uri+"/read", grace.token,
+		models.ReadRequest{MessageId: sent[1].ID}), &receipt)
+	if receipt.MessageId != sent[1].ID || receipt.UserId != grace.id {
+		t.Fatalf("unexpected read receipt: %+v", receipt)
+	}
+	server.decode(server.expect(http.StatusOK, http.MethodPost, uri+"/read", grace.token,
+		models.ReadRequest{MessageId: sent[0].ID}), &receipt)
+	if receipt.MessageId != sent[1].ID {
+		t.Fatalf("expected the read messages not to move back: %+v", receipt)
+	}
+	if seen := server.conversation(grace, conversation.ID); seen.UnreadCount != 1 {
+		t.Fatalf("expected 1 unread message of grace: %+v", seen)
+	}
+
+	server.expect(http.StatusOK, http.MethodPost, uri+"/read", grace.token, nil)
+	if seen := server.conversation(ada, conversation.ID); seen.ParticipantLastReadMessageId != sent[2].ID {
+		t.Fatalf("expected grace to have read every message: %+v", seen)
+	}
+
+	server.befriend(ada, linus)
+	other := server.startConversation(linus, ada)
+
+	var conversations []models.Conversation
+	next = server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/conversations?limit=1", ada.token, nil),
+		&conversations)
+	if len(conversations) != 1 || conversations[0].ID != other.ID {
+		t.Fatalf("expected the latest conversation first: %+v", conversations)
+	}
+	conversations = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/conversations?cursor="+next, ada.token, nil),
+		&conversations)
+	if len(conversations) != 1 || conversations[0].ID != conversation.ID {
+		t.Fatalf("unexpected second page of conversations: %+v", conversations)
+	}
+}
+
+func TestMessagesWebSocket(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+	server.befriend(ada, grace)
+
+	conversation := server.startConversation(ada, grace)
+	adaSocket := server.socket(ada)
+	graceSocket := server.socket(grace)
+
+	var message models.Message
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, fmt.Sprintf("/conversations/%d/messages", conversation.ID),
+		ada.token, models.Message{Content: "Hello"}), &message)
+
+	for _, socket := range []*testSocket{adaSocket, graceSocket} {
+		frame := socket.next()
+		var delivered models.Message
+		if error := json.Unmarshal(frame.Data, &delivered); error != nil || frame.Type != messaging.FrameMessage ||
+			frame.MessageId != message.ID || delivered.Content != "Hello" || delivered.SenderId != ada.id {
+			t.Fatalf("unexpected message frame: %+v", frame)
+		}
+	}
+
+	graceSocket.send(messaging.Frame{Type: messaging.FrameTyping, ConversationId: conversation.ID})
+	frame := adaSocket.next()
+	var typing models.TypingIndicator
+	if error := json.Unmarshal(frame.Data, &typing); error != nil || frame.Type != messaging.FrameTyping ||
+		typing.ConversationId != conversation.ID || typing.UserId != grace.id {
+		t.Fatalf("unexpected typing frame: %+v", frame)
+	}
+
+	graceSocket.send(messaging.Frame{Type: messaging.FrameRead, ConversationId: conversation.ID})
+	frame = adaSocket.next()
+	var receipt models.ReadReceipt
+	if error := json.Unmarshal(frame.Data, &receipt); error != nil || frame.Type != messaging.FrameRead ||
+		receipt.UserId != grace.id || receipt.MessageId != message.ID {
+		t.Fatalf("unexpected read frame: %+v", frame)
+	}
+	if seen := server.conversation(grace, conversation.ID); seen.UnreadCount != 0 {
+		t.Fatalf("expected no unread message of grace: %+v", seen)
+	}
+
+	linusSocket := server.socket(linus)
+	linusSocket.send(messaging.Frame{Type: messaging.FrameTyping, ConversationId: conversation.ID})
+	if frame := linusSocket.next(); frame.Type != messaging.FrameError || frame.ConversationId != conversation.ID {
+		t.Fatalf("expected an error frame for a conversation of others: %+v", frame)
+	}
+	graceSocket.send(messaging.Frame{Type: "shout", ConversationId: conversation.ID})
+	if frame := graceSocket.next(); frame.Type != messaging.FrameError {
+		t.Fatalf("expected an error frame for an unknown frame type: %+v", frame)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/block", ada.id), grace.token, nil)
+	graceSocket.send(messaging.Frame{Type: messaging.FrameTyping, ConversationId: conversation.ID})
+	if frame := graceSocket.next(); frame.Type != messaging.FrameError {
+		t.Fatalf("expected an error frame typing to a blocked user: %+v", frame)
+	}
+
+	linusSocket.send(messaging.Frame{Type: messaging.FrameTyping, Data: strings.Repeat("x", 5000)})
+	linusSocket.connection.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, error := linusSocket.connection.ReadMessage(); !websocket.IsCloseError(error, websocket.CloseMessageTooBig) {
+		t.Fatalf("expected an oversized frame to close the connection, got %v", error)
+	}
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// mfaRoutes returns routes handled by the MFA controller
+func mfaRoutes(controller *controllers.MFAController) []Route {
+	return []Route{
+		{
+			URI:                    "/mfa/enroll",
+			Method:                 http.MethodPost,
+			Function:               controller.EnrollMFA,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/mfa/confirm",
+			Method:                 http.MethodPost,
+			Function:               controller.ConfirmMFA,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/mfa/disable",
+			Method:                 http.MethodPost,
+			Function:               controller.DisableMFA,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/mfa/recovery-codes",
+			Method:                 http.MethodPost,
+			Function:               controller.RegenerateRecoveryCodes,
+			RequiresAuthentication: true,
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"devbook/src/config"
+	"devbook/src/models"
+	"devbook/src/security"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// totpCode generates the TOTP code of secret at an offset of time steps from now
+func totpCode(t *testing.T, secret string, steps int) string {
+	t.Helper()
+
+	code, error := security.TOTPCode(secret, time.Now().Add(time.Duration(steps)*30*time.Second))
+	if error != nil {
+		t.Fatal(error)
+	}
+	return code
+}
+
+// enableMFA enrolls and confirms a second factor for user, returning its secret, the code
+// used to confirm it and its recovery codes
+func (server *testServer) enableMFA(user testUser) (string, string, []string) {
+	server.t.Helper()
+
+	var enrollment models.MFAEnrollment
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/mfa/enroll", user.token, nil), &enrollment)
+
+	code := totpCode(server.t, enrollment.Secret, 0)
+
+	var recoveryCodes models.MFARecoveryCodes
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/mfa/confirm", user.token,
+		models.MFACode{Code: code}), &recoveryCodes)
+
+	return enrollment.Secret, code, recoveryCodes.RecoveryCodes
+}
+
+func TestMFAEnrollment(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/mfa/confirm", user.token,
+		models.MFACode{Code: "123456"})
+
+	var enrollment models.MFAEnrollment
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/mfa/enroll", user.token, nil), &enrollment)
+	if enrollment.Secret == "" || enrollment.URI == "" {
+		t.Fatalf("unexpected enrollment: %+v", enrollment)
+	}
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/mfa/confirm", user.token,
+		models.MFACode{Code: "000000"})
+
+	var recoveryCodes models.MFARecoveryCodes
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/mfa/confirm", user.token,
+		models.MFACode{Code: totpCode(t, enrollment.Secret, 0)}), &recoveryCodes)
+	if len(recoveryCodes.RecoveryCodes) != 10 {
+		t.Fatalf("expected 10 recovery codes: %+v", recoveryCodes)
+	}
+
+	server.expect(http.StatusConflict, http.MethodPost, "/mfa/enroll", user.token, nil)
+}
+
+func TestMFALogin(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+	secret, usedCode, recoveryCodes := server.enableMFA(user)
+
+	var challenge models.MFAChallenge
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/login", "",
+		map[string]string{"email": user.email, "password": user.password}), &challenge)
+	if !challenge.MFARequired || challenge.MFAToken == "" {
+		t.Fatalf("expected an MFA challenge: %+v", challenge)
+	}
+
+	server.expect(http.StatusUnauthorized, http.MethodGet, "/users", challenge.MFAToken, nil)
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login/mfa", "",
+		models.MFALogin{MFAToken: user.token, Code: totpCode(t, secret, 1)})
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login/mfa", "",
+		models.MFALogin{MFAToken: challenge.MFAToken, Code: "000000"})
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login/mfa", "",
+		models.MFALogin{MFAToken: challenge.MFAToken, Code: usedCode})
+
+	var token models.Token
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/login/mfa", "",
+		models.MFALogin{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, 1)}), &token)
+	server.expect(http.StatusOK, http.MethodGet, "/users", token.Token, nil)
+
+	server.expect(http.StatusOK, http.MethodPost, "/login/mfa", "",
+		models.MFALogin{MFAToken: challenge.MFAToken, Code: recoveryCodes[0]})
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login/mfa", "",
+		models.MFALogin{MFAToken: challenge.MFAToken, Code: recoveryCodes[0]})
+}
+
+func TestDisableMFA(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+	_, _, recoveryCodes := server.enableMFA(user)
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/mfa/disable", user.token,
+		models.MFACode{Code: "wrong-code"})
+
+	var regenerated models.MFARecoveryCodes
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/mfa/recovery-codes", user.token,
+		models.MFACode{Code: recoveryCodes[0]}), &regenerated)
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/mfa/disable", user.token,
+		models.MFACode{Code: recoveryCodes[1]})
+	server.expect(http.StatusNoContent, http.MethodPost, "/mfa/disable", user.token,
+		models.MFACode{Code: regenerated.RecoveryCodes[0]})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/mfa/disable", user.token,
+		models.MFACode{Code: regenerated.RecoveryCodes[1]})
+
+	server.login(user.email, user.password)
+}
+
+func TestMFACodeLockout(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+	secret, _, recoveryCodes := server.enableMFA(user)
+
+	for attempt := 0; attempt < config.LoginMaxFailures; attempt++ {
+		server.expect(http.StatusUnauthorized, http.MethodPost, "/mfa/disable", user.token,
+			models.MFACode{Code: "wrong-code"})
+	}
+
+	server.expect(http.StatusTooManyRequests, http.MethodPost, "/mfa/recovery-codes", user.token,
+		models.MFACode{Code: recoveryCodes[0]})
+	server.expect(http.StatusTooManyRequests, http.MethodPost, "/mfa/disable", user.token,
+		models.MFACode{Code: totpCode(t, secret, 1)})
+
+	var challenge models.MFAChallenge
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/login", "",
+		map[string]string{"email": user.email, "password": user.password}), &challenge)
+	server.expect(http.StatusTooManyRequests, http.MethodPost, "/login/mfa", "",
+		models.MFALogin{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, 1)})
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"devbook/src/security"
+	"net/http"
+)
+
+// moderationRoutes returns routes handled by the moderation controller
+func moderationRoutes(controller *controllers.ModerationController) []Route {
+	return []Route{
+		{
+			URI:                    "/users/{id}/suspend",
+			Method:                 http.MethodPost,
+			Function:               controller.SuspendUser,
+			RequiresAuthentication: true,
+			RequiredRole:           security.RoleModerator,
+		},
+		{
+			URI:                    "/users/{id}/unsuspend",
+			Method:                 http.MethodPost,
+			Function:               controller.UnsuspendUser,
+			RequiresAuthentication: true,
+			RequiredRole:           security.RoleModerator,
+		},
+		{
+			URI:                    "/users/{id}/role",
+			Method:                 http.MethodPut,
+			Function:               controller.UpdateUserRole,
+			RequiresAuthentication: true,
+			RequiredRole:           security.RoleAdmin,
+		},
+	}
+}
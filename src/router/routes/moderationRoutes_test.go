@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"devbook/src/models"
+	"devbook/src/security"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// promote assigns role to user and logs it in again, so its token carries the role
+func (server *testServer) promote(user testUser, role security.Role) testUser {
+	server.t.Helper()
+
+	if error := server.repositories.Users.UpdateUserRole(user.id, role); error != nil {
+		server.t.Fatalf("cannot promote %s: %v", user.nick, error)
+	}
+	user.token = server.login(user.email, user.password)
+
+	return user
+}
+
+func TestModerationRoutesRequireRole(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	for _, route := range allRoutes(server.repositories, server.mailer, server.dispatcher) {
+		if route.RequiredRole == "" {
+			continue
+		}
+		uri := fmt.Sprintf("/users/%d/%s", grace.id, route.URI[len("/users/{id}/"):])
+		server.expect(http.StatusForbidden, route.Method, uri, ada.token,
+			map[string]string{"role": "admin"})
+	}
+
+	moderator := server.promote(ada, security.RoleModerator)
+	server.expect(http.StatusForbidden, http.MethodPut, fmt.Sprintf("/users/%d/role", grace.id),
+		moderator.token, map[string]string{"role": "moderator"})
+}
+
+func TestModeratorsManageAnyPublication(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	publication := server.publish(ada, "Spam")
+	uri := fmt.Sprintf("/publications/%d", publication.ID)
+	update := map[string]string{"title": "Moderated", "content": "Removed by moderation"}
+
+	server.expect(http.StatusUnauthorized, http.MethodPut, uri, grace.token, update)
+
+	grace = server.promote(grace, security.RoleModerator)
+	server.expect(http.StatusNoContent, http.MethodPut, uri, grace.token, update)
+
+	stored, _ := server.repositories.Publications.GetPublicationById(publication.ID, ada.id)
+	if stored.Title != "Moderated" || stored.AuthorId != ada.id {
+		t.Fatalf("unexpected moderated publication: %+v", stored)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodDelete, uri, grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodDelete, uri, grace.token, nil)
+
+	var private models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Hidden", "content": "Private spam", "visibility": "private",
+	}), &private)
+	uri = fmt.Sprintf("/publications/%d", private.ID)
+
+	server.expect(http.StatusNotFound, http.MethodGet, uri, grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPut, uri, grace.token, update)
+	stored, _ = server.repositories.Publications.GetPublicationById(private.ID, ada.id)
+	if stored.Title != "Moderated" || stored.Visibility != models.VisibilityPrivate {
+		t.Fatalf("expected the private publication moderated: %+v", stored)
+	}
+	server.expect(http.StatusNoContent, http.MethodDelete, uri, grace.token, nil)
+}
+
+func TestSuspendUser(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.promote(server.signUp("ada"), security.RoleModerator)
+	grace := server.signUp("grace")
+	linus := server.promote(server.signUp("linus"), security.RoleModerator)
+	alan := server.signUp("alan")
+	tokens := server.authenticate(grace.email, grace.password)
+
+	server.expect(http.StatusForbidden, http.MethodPost, fmt.Sprintf("/users/%d/suspend", linus.id), ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, "/users/999/suspend", ada.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/suspend", grace.id), ada.token, nil)
+
+	server.expect(http.StatusForbidden, http.MethodPost, "/login", "", map[string]string{
+		"email":    grace.email,
+		"password": grace.password,
+	})
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/token/refresh", "",
+		map[string]string{"refreshToken": tokens.RefreshToken})
+	server.expect(http.StatusForbidden, http.MethodGet, fmt.Sprintf("/users/%d", alan.id), grace.token, nil)
+
+	var user models.User
+	server.decode(server.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d", grace.id), ada.token, nil), &user)
+	if user.SuspendedAt == nil || user.Role != security.RoleUser {
+		t.Fatalf("expected moderators to see the suspension: %+v", user)
+	}
+	user = models.User{}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d", grace.id), alan.token, nil), &user)
+	if user.ID != grace.id || user.SuspendedAt != nil || user.Role != "" {
+		t.Fatalf("expected the suspension hidden from other users: %+v", user)
+	}
+	user = models.User{}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d", alan.id), alan.token, nil), &user)
+	if user.Role != security.RoleUser || user.Password != "" {
+		t.Fatalf("expected users to see their own role without their password: %+v", user)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/unsuspend", grace.id), ada.token, nil)
+	server.login(grace.email, grace.password)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.promote(server.signUp("ada"), security.RoleAdmin)
+	grace := server.signUp("grace")
+	uri := fmt.Sprintf("/users/%d/role", grace.id)
+
+	server.expect(http.StatusBadRequest, http.MethodPut, uri, ada.token, map[string]string{"role": "root"})
+	server.expect(http.StatusForbidden, http.MethodPut, fmt.Sprintf("/users/%d/role", ada.id), ada.token,
+		map[string]string{"role": "user"})
+	server.expect(http.StatusNoContent, http.MethodPut, uri, ada.token, map[string]string{"role": "moderator"})
+
+	grace.token = server.login(grace.email, grace.password)
+	linus := server.signUp("linus")
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/suspend", linus.id), grace.token, nil)
+	server.expect(http.StatusForbidden, http.MethodPost, fmt.Sprintf("/users/%d/suspend", ada.id), grace.token, nil)
+
+	server.expect(http.StatusNoContent, http.MethodPut, uri, ada.token, map[string]string{"role": "user"})
+	server.expect(http.StatusForbidden, http.MethodPost, fmt.Sprintf("/users/%d/unsuspend", linus.id), grace.token, nil)
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// notificationRoutes returns routes handled by the notification controller
+func notificationRoutes(controller *controllers.NotificationController) []Route {
+	return []Route{
+		{
+			URI:                    "/notifications",
+			Method:                 http.MethodGet,
+			Function:               controller.GetNotifications,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/notifications/read-all",
+			Method:                 http.MethodPost,
+			Function:               controller.MarkAllNotificationsRead,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/notifications/{id}/read",
+			Method:                 http.MethodPost,
+			Function:               controller.MarkNotificationRead,
+			RequiresAuthentication: true,
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"devbook/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// notifications gets the first page of a user's notification groups and unread count
+func (server *testServer) notifications(user testUser) ([]models.NotificationGroup, uint64) {
+	server.t.Helper()
+
+	var page struct {
+		Items       []models.NotificationGroup `json:"items"`
+		UnreadCount uint64                     `json:"unreadCount"`
+	}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, "/notifications", user.token, nil), &page)
+
+	return page.Items, page.UnreadCount
+}
+
+func TestNotifications(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+	alan := server.signUp("alan")
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), linus.token, nil)
+
+	var publication models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Notes", "content": "Thanks @grace",
+	}), &publication)
+	uri := fmt.Sprintf("/publications/%d", publication.ID)
+
+	for _, user := range []testUser{grace, linus, alan, grace, ada} {
+		server.expect(http.StatusOK, http.MethodPost, uri+"/like", user.token, nil)
+	}
+	server.expect(http.StatusOK, http.MethodPost, uri+"/unlike", grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/like", grace.token, nil)
+
+	comment := server.comment(grace, publication, "Nice")
+	server.reply(linus, comment, "Indeed")
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/mute", alan.id), ada.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/repost", alan.token, nil)
+
+	groups, unread := server.notifications(ada)
+	if unread != 7 || len(groups) != 3 {
+		t.Fatalf("expected 7 unread notifications in 3 groups, got %d in %+v", unread, groups)
+	}
+	if groups[0].Type != models.NotificationComment || groups[0].Summary != "linus and grace commented on your publication" ||
+		groups[1].Type != models.NotificationLike || groups[1].Summary != "alan and 2 others liked your publication" ||
+		groups[1].PublicationId != publication.ID || groups[1].UnreadCount != 3 ||
+		groups[2].Type != models.NotificationFollow || groups[2].Summary != "linus and grace followed you" {
+		t.Fatalf("unexpected notification groups: %+v", groups)
+	}
+
+	graceGroups, graceUnread := server.notifications(grace)
+	if graceUnread != 2 || len(graceGroups) != 2 || graceGroups[0].Type != models.NotificationReply ||
+		graceGroups[1].Type != models.NotificationMention || graceGroups[1].Summary != "ada mentioned you in a publication" {
+		t.Fatalf("unexpected notifications of grace: %d %+v", graceUnread, graceGroups)
+	}
+
+	var page []models.NotificationGroup
+	next := server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/notifications?limit=2", ada.token, nil), &page)
+	page = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/notifications?cursor="+next, ada.token, nil), &page)
+	if len(page) != 1 || page[0].Type != models.NotificationFollow {
+		t.Fatalf("unexpected second page of notifications: %+v", page)
+	}
+
+	server.expect(http.StatusNotFound, http.MethodPost,
+		fmt.Sprintf("/notifications/%d/read", graceGroups[1].ID), ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, "/notifications/999/read", ada.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/notifications/%d/read", groups[1].ID), ada.token, nil)
+
+	groups, unread = server.notifications(ada)
+	if unread != 4 || groups[1].UnreadCount != 0 || groups[0].UnreadCount != 2 {
+		t.Fatalf("expected the likes group read: %d %+v", unread, groups)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPost, "/notifications/read-all", ada.token, nil)
+	if _, unread = server.notifications(ada); unread != 0 {
+		t.Fatalf("expected every notification read, got %d unread", unread)
+	}
+
+	server.expect(http.StatusOK, http.MethodPost, uri+"/unlike", linus.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/like", linus.token, nil)
+	groups, unread = server.notifications(ada)
+	if unread != 1 || len(groups) != 3 || groups[1].UnreadCount != 1 || groups[1].ActorCount != 3 {
+		t.Fatalf("expected a repeated like unread again: %d %+v", unread, groups)
+	}
+}
+
+func TestNotificationsRespectVisibility(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Draft", "content": "For @grace, later", "visibility": "private",
+	})
+
+	if groups, unread := server.notifications(grace); unread != 0 || len(groups) != 0 {
+		t.Fatalf("expected no notification of a hidden mention: %d %+v", unread, groups)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/users/%d/privacy", ada.id), ada.token,
+		models.PrivacyUpdate{IsPrivate: true})
+	server.expect(http.StatusAccepted, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPost,
+		fmt.Sprintf("/users/%d/follow-requests/%d/approve", ada.id, grace.id), ada.token, nil)
+
+	groups, _ := server.notifications(ada)
+	if len(groups) != 1 || groups[0].Summary != "grace asked to follow you" {
+		t.Fatalf("unexpected notifications of ada: %+v", groups)
+	}
+
+	groups, _ = server.notifications(grace)
+	if len(groups) != 1 || groups[0].Summary != "ada accepted your follow request" {
+		t.Fatalf("unexpected notifications of grace: %+v", groups)
+	}
+}
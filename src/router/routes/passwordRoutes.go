@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// passwordRoutes returns routes handled by the password controller
+func passwordRoutes(controller *controllers.PasswordController) []Route {
+	return []Route{
+		{
+			URI:                    "/password/forgot",
+			Method:                 http.MethodPost,
+			Function:               controller.ForgotPassword,
+			RequiresAuthentication: false,
+		},
+		{
+			URI:                    "/password/reset",
+			Method:                 http.MethodPost,
+			Function:               controller.ResetPassword,
+			RequiresAuthentication: false,
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"devbook/src/models"
+	"net/http"
+	"testing"
+)
+
+const resetSubject = "Reset your devbook password"
+
+func TestForgotPasswordDoesNotRevealEmails(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+
+	knownStatus, knownBody := server.request(http.MethodPost, "/password/forgot", "",
+		models.PasswordForgot{Email: user.email})
+	unknownStatus, unknownBody := server.request(http.MethodPost, "/password/forgot", "",
+		models.PasswordForgot{Email: "nobody@devbook.com"})
+
+	if knownStatus != http.StatusAccepted || unknownStatus != knownStatus ||
+		string(knownBody) != string(unknownBody) {
+		t.Fatalf("expected identical responses, got %d %q and %d %q",
+			knownStatus, knownBody, unknownStatus, unknownBody)
+	}
+
+	server.mailer.awaitSent(t, user.email, resetSubject, 1)
+	if server.mailer.sentTo("nobody@devbook.com", resetSubject) != 0 {
+		t.Fatalf("unexpected messages: %+v", server.mailer.messages)
+	}
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/password/forgot", "",
+		models.PasswordForgot{Email: "not-an-email"})
+}
+
+func TestResetPassword(t *testing.T) {
+	server := newTestServer(t)
+	user := server.signUp("ada")
+	session := server.authenticate(user.email, user.password)
+
+	server.expect(http.StatusAccepted, http.MethodPost, "/password/forgot", "",
+		models.PasswordForgot{Email: user.email})
+	token := server.mailer.lastToken(t, user.email, resetSubject)
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/password/reset", "",
+		models.PasswordReset{Token: "invalid", NewPassword: "new-secret"})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/password/reset", "",
+		models.PasswordReset{Token: token})
+
+	server.expect(http.StatusOK, http.MethodPost, "/password/reset", "",
+		models.PasswordReset{Token: token, NewPassword: "new-secret"})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/password/reset", "",
+		models.PasswordReset{Token: token, NewPassword: "another-secret"})
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "",
+		map[string]string{"email": user.email, "password": user.password})
+	server.login(user.email, "new-secret")
+}
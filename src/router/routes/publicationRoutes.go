@@ -5,54 +5,80 @@ import (
 	"net/http"
 )
 
-var publicationRoutes = []Route{
-	{
-		URI:                    "/publications",
-		Method:                 http.MethodPost,
-		Function:               controllers.CreatePublication,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/publications/{id}",
-		Method:                 http.MethodPut,
-		Function:               controllers.UpdatePublication,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/publications",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetPublications,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/publications/{id}",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetPublication,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/publications/{id}",
-		Method:                 http.MethodDelete,
-		Function:               controllers.DeletePublication,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}/publications",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetUserPublications,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/publications/{id}/like",
-		Method:                 http.MethodPost,
-		Function:               controllers.LikePublication,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/publications/{id}/unlike",
-		Method:                 http.MethodPost,
-		Function:               controllers.UnlikePublication,
-		RequiresAuthentication: true,
-	},
-
+// publicationRoutes returns routes handled by the publication controller
+func publicationRoutes(controller *controllers.PublicationController) []Route {
+	return []Route{
+		{
+			URI:                    "/publications",
+			Method:                 http.MethodPost,
+			Function:               controller.CreatePublication,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}",
+			Method:                 http.MethodPut,
+			Function:               controller.UpdatePublication,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications",
+			Method:                 http.MethodGet,
+			Function:               controller.GetPublications,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}",
+			Method:                 http.MethodGet,
+			Function:               controller.GetPublication,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}",
+			Method:                 http.MethodDelete,
+			Function:               controller.DeletePublication,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/publications",
+			Method:                 http.MethodGet,
+			Function:               controller.GetUserPublications,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/mentions",
+			Method:                 http.MethodGet,
+			Function:               controller.GetUserMentions,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/like",
+			Method:                 http.MethodPost,
+			Function:               controller.LikePublication,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/unlike",
+			Method:                 http.MethodPost,
+			Function:               controller.UnlikePublication,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/likes",
+			Method:                 http.MethodGet,
+			Function:               controller.GetPublicationLikes,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/repost",
+			Method:                 http.MethodPost,
+			Function:               controller.RepostPublication,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/publications/{id}/unrepost",
+			Method:                 http.MethodPost,
+			Function:               controller.UnrepostPublication,
+			RequiresAuthentication: true,
+		},
+	}
 }
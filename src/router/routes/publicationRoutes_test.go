@@ -0,0 +1,383 @@
+package routes
+
+import (
+	"devbook/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// publish creates a publication authored by user
+func (server *testServer) publish(user testUser, title string) models.Publication {
+	server.t.Helper()
+
+	var publication models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", user.token,
+		map[string]string{"title": title, "content": "Content of " + title}), &publication)
+
+	return publication
+}
+
+func TestCreateAndGetPublication(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/publications", ada.token,
+		map[string]string{"title": "No content"})
+
+	created := server.publish(ada, "Notes")
+	if created.ID == 0 || created.AuthorId != ada.id {
+		t.Fatalf("unexpected created publication: %+v", created)
+	}
+
+	var publication models.Publication
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d", created.ID), ada.token, nil), &publication)
+	if publication.Title != "Notes" || publication.AuthorNick != "ada" {
+		t.Fatalf("unexpected publication: %+v", publication)
+	}
+
+	server.expect(http.StatusNotFound, http.MethodGet, "/publications/999", ada.token, nil)
+}
+
+func TestPublicationFeeds(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	first := server.publish(ada, "First")
+	second := server.publish(grace, "Second")
+	server.publish(linus, "Unrelated")
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", grace.id), ada.token, nil)
+
+	var feed []models.Publication
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/publications", ada.token, nil), &feed)
+	if len(feed) != 2 || feed[0].ID != second.ID || feed[1].ID != first.ID {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+
+	var publications []models.Publication
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/publications", grace.id), ada.token, nil), &publications)
+	if len(publications) != 1 || publications[0].ID != second.ID {
+		t.Fatalf("unexpected user publications: %+v", publications)
+	}
+}
+
+func TestUpdateAndDeletePublication(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	publication := server.publish(ada, "Draft")
+
+	uri := fmt.Sprintf("/publications/%d", publication.ID)
+	update := map[string]string{"title": "Final", "content": "Reviewed content"}
+
+	server.expect(http.StatusUnauthorized, http.MethodPut, uri, grace.token, update)
+	server.expect(http.StatusNoContent, http.MethodPut, uri, ada.token, update)
+
+	var updated models.Publication
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, ada.token, nil), &updated)
+	if updated.Title != "Final" || updated.Content != "Reviewed content" {
+		t.Fatalf("publication was not updated: %+v", updated)
+	}
+
+	server.expect(http.StatusUnauthorized, http.MethodDelete, uri, grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodDelete, uri, ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, uri, ada.token, nil)
+}
+
+func TestLikeAndUnlikePublication(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	publication := server.publish(ada, "Likeable")
+
+	uri := fmt.Sprintf("/publications/%d", publication.ID)
+
+	server.expect(http.StatusOK, http.MethodPost, uri+"/like", grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/like", grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/unlike", ada.token, nil)
+
+	var liked models.Publication
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, grace.token, nil), &liked)
+	if liked.Likes != 1 || !liked.LikedByMe {
+		t.Fatalf("expected a single like by the viewer: %+v", liked)
+	}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, ada.token, nil), &liked)
+	if liked.Likes != 1 || liked.LikedByMe {
+		t.Fatalf("expected a single like by someone else: %+v", liked)
+	}
+
+	var users []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, uri+"/likes", ada.token, nil), &users)
+	if len(users) != 1 || users[0].ID != grace.id {
+		t.Fatalf("unexpected likes: %+v", users)
+	}
+
+	alan := server.signUp("alan")
+	linus := server.signUp("linus")
+	server.expect(http.StatusOK, http.MethodPost, uri+"/like", alan.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/like", linus.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/mute", linus.id), ada.token, nil)
+
+	var first, second []models.User
+	next := server.decodePage(server.expect(http.StatusOK, http.MethodGet, uri+"/likes?limit=1", ada.token, nil), &first)
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, uri+"/likes?cursor="+next, ada.token, nil), &second)
+	if len(first) != 1 || first[0].ID != grace.id || len(second) != 1 || second[0].ID != alan.id {
+		t.Fatalf("expected the likes paged without muted users: %+v %+v", first, second)
+	}
+	server.expect(http.StatusOK, http.MethodPost, uri+"/unlike", alan.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/unlike", linus.token, nil)
+
+	server.expect(http.StatusOK, http.MethodPost, uri+"/unlike", grace.token, nil)
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, grace.token, nil), &liked)
+	if liked.Likes != 0 || liked.LikedByMe {
+		t.Fatalf("expected no likes: %+v", liked)
+	}
+
+	server.expect(http.StatusNotFound, http.MethodPost, "/publications/999/like", grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, "/publications/999/likes", grace.token, nil)
+}
+
+func TestRepostPublication(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	original := server.publish(linus, "Original")
+	own := server.publish(ada, "Own")
+	uri := fmt.Sprintf("/publications/%d", original.ID)
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", grace.id), ada.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/repost", grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/repost", grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, "/publications/999/repost", grace.token, nil)
+
+	var feed []models.Publication
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/publications", ada.token, nil), &feed)
+	if len(feed) != 2 || feed[0].ID != original.ID || feed[0].RepostedBy != "grace" ||
+		feed[0].Reposts != 1 || feed[1].ID != own.ID || feed[1].RepostedBy != "" {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+
+	server.expect(http.StatusOK, http.MethodPost, uri+"/unrepost", grace.token, nil)
+	feed = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/publications", ada.token, nil), &feed)
+	if len(feed) != 1 || feed[0].ID != own.ID {
+		t.Fatalf("unexpected feed after unrepost: %+v", feed)
+	}
+
+	server.expect(http.StatusOK, http.MethodPost, uri+"/repost", grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodDelete, uri, linus.token, nil)
+	feed = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/publications", ada.token, nil), &feed)
+	if len(feed) != 1 || feed[0].ID != own.ID {
+		t.Fatalf("expected reposts of a deleted publication removed from feeds: %+v", feed)
+	}
+}
+
+func TestQuotePublication(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	original := server.publish(grace, "Original")
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/publications", ada.token, map[string]interface{}{
+		"title": "Quote", "content": "Quoting nothing", "quotedPublicationId": 999,
+	})
+
+	var quote models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]interface{}{
+		"title": "Quote", "content": "Well said", "quotedPublicationId": original.ID,
+	}), &quote)
+
+	uri := fmt.Sprintf("/publications/%d", quote.ID)
+
+	var stored models.Publication
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, ada.token, nil), &stored)
+	if stored.QuotedPublicationId != original.ID || stored.Quoted == nil ||
+		stored.Quoted.Title != "Original" || stored.Quoted.AuthorNick != "grace" {
+		t.Fatalf("unexpected quote: %+v", stored)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodDelete, fmt.Sprintf("/publications/%d", original.ID), grace.token, nil)
+
+	stored = models.Publication{}
+	server.decode(server.expect(http.StatusOK, http.MethodGet, uri, ada.token, nil), &stored)
+	if stored.QuotedPublicationId != original.ID || stored.Quoted != nil {
+		t.Fatalf("expected a quote of a deleted publication to keep its reference only: %+v", stored)
+	}
+}
+
+func TestPaginatedFeed(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", grace.id), ada.token, nil)
+
+	var expected []uint64
+	for index := 0; index < 4; index++ {
+		expected = append([]uint64{server.publish(grace, fmt.Sprintf("Post %d", index)).ID}, expected...)
+	}
+	reposted := server.publish(linus, "Reposted")
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/publications/%d/repost", reposted.ID), grace.token, nil)
+	expected = append([]uint64{reposted.ID}, expected...)
+
+	var ids []uint64
+	cursor := ""
+	for pages := 0; pages == 0 || cursor != ""; pages++ {
+		if pages > len(expected) {
+			t.Fatalf("expected the feed to end, read %v", ids)
+		}
+
+		var feed []models.Publication
+		cursor = server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+			"/publications?limit=2&cursor="+cursor, ada.token, nil), &feed)
+		if len(feed) > 2 {
+			t.Fatalf("expected at most 2 publications per page: %+v", feed)
+		}
+		for _, publication := range feed {
+			ids = append(ids, publication.ID)
+		}
+	}
+
+	if fmt.Sprint(ids) != fmt.Sprint(expected) {
+		t.Fatalf("expected feed %v, got %v", expected, ids)
+	}
+
+	server.expect(http.StatusBadRequest, http.MethodGet, "/publications?limit=0", ada.token, nil)
+	server.expect(http.StatusBadRequest, http.MethodGet, "/publications?cursor=not-a-cursor", ada.token, nil)
+}
+
+func TestPublicationVisibility(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", grace.id), linus.token, nil)
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Hidden", "content": "Nobody sees this", "visibility": "friends",
+	})
+
+	public := server.publish(ada, "Public")
+	if public.Visibility != models.VisibilityPublic {
+		t.Fatalf("expected publications public by default: %+v", public)
+	}
+
+	var followersOnly, private models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Followers", "content": "For followers", "visibility": "followers",
+	}), &followersOnly)
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Private", "content": "For me", "visibility": "private",
+	}), &private)
+
+	followersUri := fmt.Sprintf("/publications/%d", followersOnly.ID)
+	privateUri := fmt.Sprintf("/publications/%d", private.ID)
+
+	server.expect(http.StatusOK, http.MethodGet, privateUri, ada.token, nil)
+	server.expect(http.StatusOK, http.MethodGet, followersUri, grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, privateUri, grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, followersUri, linus.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, followersUri+"/like", linus.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, followersUri+"/comments", linus.token, nil)
+
+	userPublications := func(viewer testUser) []uint64 {
+		var publications []models.Publication
+		server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+			fmt.Sprintf("/users/%d/publications", ada.id), viewer.token, nil), &publications)
+
+		var ids []uint64
+		for _, publication := range publications {
+			ids = append(ids, publication.ID)
+		}
+		return ids
+	}
+
+	if ids := fmt.Sprint(userPublications(ada)); ids != fmt.Sprint([]uint64{private.ID, followersOnly.ID, public.ID}) {
+		t.Fatalf("expected the author to see all publications, got %s", ids)
+	}
+	if ids := fmt.Sprint(userPublications(grace)); ids != fmt.Sprint([]uint64{followersOnly.ID, public.ID}) {
+		t.Fatalf("expected a follower to see public and followers only publications, got %s", ids)
+	}
+	if ids := fmt.Sprint(userPublications(linus)); ids != fmt.Sprint([]uint64{public.ID}) {
+		t.Fatalf("expected others to see public publications, got %s", ids)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPut, privateUri, ada.token,
+		map[string]string{"title": "Still private", "content": "Edited"})
+	server.expect(http.StatusNotFound, http.MethodGet, privateUri, grace.token, nil)
+
+	var quote models.Publication
+	server.expect(http.StatusOK, http.MethodPost, followersUri+"/repost", grace.token, nil)
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", grace.token, map[string]interface{}{
+		"title": "Quote", "content": "Quoting followers", "quotedPublicationId": followersOnly.ID,
+	}), &quote)
+
+	var feed []models.Publication
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/publications", linus.token, nil), &feed)
+	if len(feed) != 1 || feed[0].ID != quote.ID || feed[0].Quoted != nil ||
+		feed[0].QuotedPublicationId != followersOnly.ID {
+		t.Fatalf("expected hidden reposts and quotes left out of feeds: %+v", feed)
+	}
+
+	feed = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/publications", grace.token, nil), &feed)
+	if len(feed) != 4 || feed[0].ID != quote.ID || feed[0].Quoted == nil {
+		t.Fatalf("unexpected follower feed: %+v", feed)
+	}
+}
+
+func TestPublicationMentions(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	var mentioning models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Thanks", "content": "Olá @Grace, @nobody and @linus.",
+	}), &mentioning)
+	if fmt.Sprint(mentioning.Mentions) != fmt.Sprint([]models.Mention{{Offset: 4, Length: 6, UserId: grace.id}, {Offset: 24, Length: 6, UserId: linus.id}}) {
+		t.Fatalf("unexpected mentions: %+v", mentioning.Mentions)
+	}
+
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Private", "content": "Hidden @grace", "visibility": "private",
+	})
+	server.publish(linus, "Unrelated")
+
+	var publications []models.Publication
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/mentions", grace.id), grace.token, nil), &publications)
+	if len(publications) != 1 || publications[0].ID != mentioning.ID {
+		t.Fatalf("unexpected mentions of grace: %+v", publications)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/publications/%d", mentioning.ID), ada.token,
+		map[string]string{"title": "Thanks", "content": "Only @linus"})
+
+	publications = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/mentions", grace.id), grace.token, nil), &publications)
+	if len(publications) != 0 {
+		t.Fatalf("expected updates to replace mentions: %+v", publications)
+	}
+
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/mentions", linus.id), grace.token, nil), &publications)
+	if len(publications) != 1 || publications[0].ID != mentioning.ID ||
+		fmt.Sprint(publications[0].Mentions) != fmt.Sprint([]models.Mention{{Offset: 5, Length: 6, UserId: linus.id}}) {
+		t.Fatalf("unexpected mentions of linus: %+v", publications)
+	}
+}
@@ -1,7 +1,16 @@
 package routes
 
 import (
+	"devbook/src/controllers"
+	"devbook/src/events"
+	"devbook/src/mail"
+	"devbook/src/messaging"
 	"devbook/src/middlewares"
+	"devbook/src/notifications"
+	"devbook/src/persistence"
+	"devbook/src/security"
+	"devbook/src/streams"
+	"devbook/src/webhooks"
 	"github.com/gorilla/mux"
 	"net/http"
 )
@@ -12,26 +21,66 @@ type Route struct {
 	Method                 string
 	Function               func(http.ResponseWriter, *http.Request)
 	RequiresAuthentication bool
+	RequiredRole           security.Role
 }
 
-// configures routes in router
-func ConfigureRoutes(r *mux.Router) *mux.Router {
+// configures routes in router, injecting repositories and services into controllers
+func ConfigureRoutes(r *mux.Router, repositories persistence.Repositories, mailer mail.Mailer,
+	dispatcher *webhooks.Dispatcher) *mux.Router {
 
-	routes := userRoutes
-	routes = append(routes, loginRoutes...)
-	routes = append(routes, publicationRoutes...)
+	for _, route := range allRoutes(repositories, mailer, dispatcher) {
 
-	for _, route := range routes {
+		handler := route.Function
 
-		if route.RequiresAuthentication {
-			r.HandleFunc(route.URI,
-				middlewares.CheckAuthenticatedRequest(
-					middlewares.LogRequest(route.Function))).Methods(route.Method)
-		} else {
-			r.HandleFunc(route.URI,
-				middlewares.LogRequest(route.Function)).Methods(route.Method)
+		if route.RequiredRole != "" {
+			handler = middlewares.CheckRequiredRole(route.RequiredRole, handler)
 		}
+
+		if route.RequiresAuthentication || route.RequiredRole != "" {
+			handler = middlewares.CheckAuthenticatedRequest(repositories.Users, handler)
+		}
+
+		r.HandleFunc(route.URI, middlewares.RequestID(middlewares.LogRequest(handler))).Methods(route.Method)
 	}
 
+	r.NotFoundHandler = middlewares.RequestID(middlewares.LogRequest(http.NotFound))
+
 	return r
 }
+
+// allRoutes returns every API route, with controllers built on repositories and services and
+// publishing their events to the notifier, the event stream broker, the direct message hub and the
+// webhook dispatcher
+func allRoutes(repositories persistence.Repositories, mailer mail.Mailer, dispatcher *webhooks.Dispatcher) []Route {
+
+	bus := events.NewBus()
+	notifications.NewNotifier(repositories.Notifications, repositories.Users, repositories.Publications, bus).Subscribe(bus)
+	broker := streams.NewBroker(repositories.Users, repositories.Publications)
+	broker.Subscribe(bus)
+	hub := messaging.NewHub()
+	hub.Subscribe(bus)
+	dispatcher.Subscribe(bus)
+
+	routes := userRoutes(controllers.NewUserController(
+		repositories.Users, repositories.OneTimeTokens, repositories.RefreshTokens, mailer, bus))
+	routes = append(routes, loginRoutes(controllers.NewLoginController(
+		repositories.Users, repositories.RefreshTokens, repositories.MFA, repositories.LoginAttempts))...)
+	routes = append(routes, publicationRoutes(controllers.NewPublicationController(repositories.Publications, repositories.Users, bus))...)
+	routes = append(routes, commentRoutes(controllers.NewCommentController(
+		repositories.Comments, repositories.Publications, repositories.Users, bus))...)
+	routes = append(routes, tagRoutes(controllers.NewTagController(repositories.Publications))...)
+	routes = append(routes, searchRoutes(controllers.NewSearchController(repositories.Search))...)
+	routes = append(routes, notificationRoutes(controllers.NewNotificationController(repositories.Notifications))...)
+	routes = append(routes, streamRoutes(controllers.NewStreamController(broker))...)
+	routes = append(routes, messageRoutes(controllers.NewMessageController(
+		repositories.Messages, repositories.Users, hub, bus))...)
+	routes = append(routes, webhookRoutes(controllers.NewWebhookController(repositories.Webhooks, dispatcher))...)
+	routes = append(routes, mfaRoutes(controllers.NewMFAController(
+		repositories.Users, repositories.MFA, repositories.LoginAttempts))...)
+	routes = append(routes, passwordRoutes(controllers.NewPasswordController(
+		repositories.Users, repositories.OneTimeTokens, repositories.RefreshTokens, mailer))...)
+	routes = append(routes, moderationRoutes(controllers.NewModerationController(
+		repositories.Users, repositories.RefreshTokens))...)
+
+	return routes
+}
@@ -0,0 +1,391 @@
+package routes
+
+import (
+	"bytes"
+	"devbook/src/config"
+	"devbook/src/logging"
+	"devbook/src/mail"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"devbook/src/webhooks"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	config.SecretKey = []byte("test-secret-key")
+	config.AccessTokenLifetime = time.Hour
+	config.RefreshTokenLifetime = time.Hour
+	config.PasswordResetTokenLifetime = time.Hour
+	config.EmailVerificationTokenLifetime = time.Hour
+	config.MFAChallengeLifetime = time.Minute
+	config.LoginMaxFailures = 3
+	config.LoginMaxFailuresPerAddress = 10
+	config.LoginBackoff = 0
+	config.LoginLockoutDuration = time.Minute
+	config.LoginFailureWindow = time.Minute
+	config.CommentThreadMaxDepth = 3
+	config.CommentRepliesPageSize = 2
+	config.CommentThreadMaxReplies = 10
+	config.PageSize = 20
+	config.MaxPageSize = 50
+	config.TrendingTagsWindow = time.Hour
+	config.TrendingTagsLimit = 2
+	config.StreamHeartbeatInterval = 10 * time.Millisecond
+	config.StreamHistorySize = 100
+	config.StreamBufferSize = 10
+	config.MessagingRequiresMutualFollow = true
+	config.WebSocketPingInterval = time.Second
+	config.WebhookTimeout = time.Second
+	config.WebhookMaxAttempts = 3
+	config.WebhookRetryDelay = 10 * time.Millisecond
+	config.WebhookWorkers = 2
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	config.WebhookAllowedNetworks = []*net.IPNet{loopback}
+	logging.SetDefault(logging.New(ioutil.Discard, logging.LevelInfo))
+	os.Exit(m.Run())
+}
+
+// testMailer records sent messages
+type testMailer struct {
+	mutex    sync.Mutex
+	messages []mail.Message
+}
+
+// Send records a message
+func (mailer *testMailer) Send(message mail.Message) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+
+	mailer.messages = append(mailer.messages, message)
+	return nil
+}
+
+// awaitSent waits until count messages with a subject were sent to an address, as messages sent in
+// the background may not be yet
+func (mailer *testMailer) awaitSent(t *testing.T, to string, subject string, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mailer.sentTo(to, subject) < count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d %q messages sent to %s, got %d", count, subject, to, mailer.sentTo(to, subject))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// tokenPattern matches tokens generated by security.GenerateBase64RandomToken, alone in a line or in a link
+var tokenPattern = regexp.MustCompile(`(?m)(?:^|token=)([A-Za-z0-9+/%]{86,}(?:==|%3D%3D))$`)
+
+// lastToken returns the token in the last message with a subject sent to an address, waiting for
+// a first one
+func (mailer *testMailer) lastToken(t *testing.T, to string, subject string) string {
+	t.Helper()
+	mailer.awaitSent(t, to, subject, 1)
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+
+	for index := len(mailer.messages) - 1; index >= 0; index-- {
+		message := mailer.messages[index]
+		if message.To != to || message.Subject != subject {
+			continue
+		}
+		if matches := tokenPattern.FindStringSubmatch(message.Body); matches != nil {
+			token, error := url.PathUnescape(matches[1])
+			if error != nil {
+				t.Fatal(error)
+			}
+			return token
+		}
+	}
+
+	t.Fatalf("no %q token was sent to %s", subject, to)
+	return ""
+}
+
+// sentTo counts messages with a subject sent to an address
+func (mailer *testMailer) sentTo(to string, subject string) int {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+
+	count := 0
+	for _, message := range mailer.messages {
+		if message.To == to && message.Subject == subject {
+			count++
+		}
+	}
+	return count
+}
+
+// testServer is an API server backed by in-memory repositories
+type testServer struct {
+	*httptest.Server
+	t            *testing.T
+	repositories persistence.Repositories
+	mailer       *testMailer
+	dispatcher   *webhooks.Dispatcher
+}
+
+func newTestServer(t *testing.T) *testServer {
+	repositories := persistence.NewMemoryRepositories()
+	mailer := &testMailer{}
+	dispatcher := webhooks.NewDispatcher(repositories.Webhooks, repositories.Users, repositories.Publications)
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+	server := httptest.NewServer(ConfigureRoutes(mux.NewRouter(), repositories, mailer, dispatcher))
+	t.Cleanup(server.Close)
+	return &testServer{server, t, repositories, mailer, dispatcher}
+}
+
+// request performs a request, encoding body as JSON and authenticating with token when given
+func (server *testServer) request(method string, uri string, token string, body interface{}) (int, []byte) {
+	server.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, error := json.Marshal(body)
+		if error != nil {
+			server.t.Fatal(error)
+		}
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	request, error := http.NewRequest(method, server.URL+uri, reader)
+	if error != nil {
+		server.t.Fatal(error)
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, error := server.Client().Do(request)
+	if error != nil {
+		server.t.Fatal(error)
+	}
+	defer response.Body.Close()
+
+	responseBody, error := ioutil.ReadAll(response.Body)
+	if error != nil {
+		server.t.Fatal(error)
+	}
+
+	return response.StatusCode, responseBody
+}
+
+// expect performs a request and fails the test when the status code differs from status
+func (server *testServer) expect(status int, method string, uri string, token string, body interface{}) []byte {
+	server.t.Helper()
+
+	statusCode, responseBody := server.request(method, uri, token, body)
+	if statusCode != status {
+		server.t.Fatalf("%s %s: expected status %d, got %d: %s",
+			method, uri, status, statusCode, responseBody)
+	}
+	return responseBody
+}
+
+// decode unmarshals a JSON response body into target
+func (server *testServer) decode(body []byte, target interface{}) {
+	server.t.Helper()
+
+	if error := json.Unmarshal(body, target); error != nil {
+		server.t.Fatalf("cannot decode %q: %v", body, error)
+	}
+}
+
+// decodePage unmarshals a page of a listing into items, returning the cursor of the next page
+func (server *testServer) decodePage(body []byte, items interface{}) string {
+	server.t.Helper()
+
+	var page struct {
+		Items      json.RawMessage `json:"items"`
+		NextCursor string          `json:"nextCursor"`
+	}
+	server.decode(body, &page)
+	server.decode(page.Items, items)
+	return page.NextCursor
+}
+
+// testUser is a registered user and its authentication token
+type testUser struct {
+	id       uint64
+	nick     string
+	email    string
+	password string
+	token    string
+}
+
+// signUp creates a user named after nick and logs it in
+func (server *testServer) signUp(nick string) testUser {
+	server.t.Helper()
+
+	user := testUser{
+		nick:     nick,
+		email:    fmt.Sprintf("%s@devbook.com", nick),
+		password: "secret-" + nick,
+	}
+
+	var created struct {
+		ID uint64 `json:"id"`
+	}
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/users", "", map[string]string{
+		"name":     "User " + nick,
+		"nick":     user.nick,
+		"email":    user.email,
+		"password": user.password,
+	}), &created)
+	user.id = created.ID
+	user.token = server.login(user.email, user.password)
+
+	return user
+}
+
+// login authenticates a user and returns its token
+func (server *testServer) login(email string, password string) string {
+	server.t.Helper()
+
+	return server.authenticate(email, password).Token
+}
+
+// authenticate logs a user in and returns its tokens
+func (server *testServer) authenticate(email string, password string) models.Token {
+	server.t.Helper()
+
+	var token models.Token
+	server.decode(server.expect(http.StatusOK, http.MethodPost, "/login", "", map[string]string{
+		"email":    email,
+		"password": password,
+	}), &token)
+
+	return token
+}
+
+func TestAuthenticatedRoutesRequireToken(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, route := range allRoutes(server.repositories, server.mailer, server.dispatcher) {
+		if !route.RequiresAuthentication {
+			continue
+		}
+		statusCode, _ := server.request(route.Method, route.URI, "", nil)
+		if statusCode != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected status %d without token, got %d",
+				route.Method, route.URI, http.StatusUnauthorized, statusCode)
+		}
+	}
+}
+
+// lockedBuffer collects log entries written while tests read them
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+// Write appends p to the buffer
+func (buffer *lockedBuffer) Write(p []byte) (int, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return buffer.buffer.Write(p)
+}
+
+// accessLog waits for the access log entry of a request, returning its fields
+func (buffer *lockedBuffer) accessLog(t *testing.T, requestId string) map[string]interface{} {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		buffer.mutex.Lock()
+		lines := strings.Split(buffer.buffer.String(), "\n")
+		buffer.mutex.Unlock()
+
+		for _, line := range lines {
+			var entry map[string]interface{}
+			if json.Unmarshal([]byte(line), &entry) == nil && entry["message"] == "request" &&
+				entry["request_id"] == requestId {
+				return entry
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no access log entry for request %s: %s", requestId, lines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+
+	output := &lockedBuffer{}
+	logging.SetDefault(logging.New(output, logging.LevelInfo))
+	t.Cleanup(func() { logging.SetDefault(logging.New(ioutil.Discard, logging.LevelInfo)) })
+
+	send := func(method string, uri string, token string, requestId string) *http.Response {
+		request, error := http.NewRequest(method, server.URL+uri, nil)
+		if error != nil {
+			t.Fatal(error)
+		}
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		if requestId != "" {
+			request.Header.Set("X-Request-ID", requestId)
+		}
+
+		response, error := server.Client().Do(request)
+		if error != nil {
+			t.Fatal(error)
+		}
+		ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		return response
+	}
+
+	response := send(http.MethodGet, fmt.Sprintf("/users/%d", ada.id), ada.token, "client-id.1")
+	if requestId := response.Header.Get("X-Request-ID"); requestId != "client-id.1" {
+		t.Fatalf("expected the request id echoed, got %q", requestId)
+	}
+	entry := output.accessLog(t, "client-id.1")
+	if entry["level"] != "info" || entry["method"] != http.MethodGet || entry["status"] != float64(http.StatusOK) ||
+		entry["user_id"] != float64(ada.id) || entry["bytes"] == float64(0) || entry["duration_ms"] == nil {
+		t.Fatalf("unexpected access log entry: %v", entry)
+	}
+
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+	for _, invalid := range []string{"", "not valid", strings.Repeat("a", 129)} {
+		response = send(http.MethodGet, "/users/verify?token=secret-token", "", invalid)
+		requestId := response.Header.Get("X-Request-ID")
+		if !generated.MatchString(requestId) {
+			t.Fatalf("expected a generated request id instead of %q, got %q", invalid, requestId)
+		}
+
+		entry = output.accessLog(t, requestId)
+		if _, found := entry["user_id"]; found || strings.Contains(entry["uri"].(string), "secret-token") ||
+			entry["status"] == float64(http.StatusOK) {
+			t.Fatalf("unexpected access log entry: %v", entry)
+		}
+	}
+
+	response = send(http.MethodGet, "/unknown", "", "missing")
+	if entry = output.accessLog(t, "missing"); response.StatusCode != http.StatusNotFound ||
+		entry["status"] != float64(http.StatusNotFound) {
+		t.Fatalf("expected unknown routes logged as not found: %v", entry)
+	}
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// searchRoutes returns routes handled by the search controller
+func searchRoutes(controller *controllers.SearchController) []Route {
+	return []Route{
+		{
+			URI:                    "/search/publications",
+			Method:                 http.MethodGet,
+			Function:               controller.SearchPublications,
+			RequiresAuthentication: true,
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"devbook/src/models"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSearchPublications(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	titled := server.publish(ada, "Go generics")
+	var mentioned, tagged models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", grace.token, map[string]string{
+		"title": "Notes", "content": "Generics arrived in go, finally",
+	}), &mentioned)
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Tooling", "content": "Testing #go code",
+	}), &tagged)
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Go secrets", "content": "Private notes", "visibility": "private",
+	})
+	server.publish(ada, "Rust")
+
+	search := func(query string) []uint64 {
+		var ids []uint64
+		cursor := ""
+		for pages := 0; pages == 0 || cursor != ""; pages++ {
+			var publications []models.Publication
+			cursor = server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+				"/search/publications?limit=1&"+query+"&cursor="+cursor, grace.token, nil), &publications)
+			for _, publication := range publications {
+				ids = append(ids, publication.ID)
+			}
+		}
+		return ids
+	}
+
+	if ids := fmt.Sprint(search("q=go+generics")); ids != fmt.Sprint([]uint64{titled.ID, mentioned.ID, tagged.ID}) {
+		t.Fatalf("unexpected results ranked by relevance: %s", ids)
+	}
+	if ids := fmt.Sprint(search(fmt.Sprintf("q=go&author=%d", ada.id))); ids != fmt.Sprint([]uint64{titled.ID, tagged.ID}) {
+		t.Fatalf("unexpected results by author: %s", ids)
+	}
+	if ids := fmt.Sprint(search("q=go&tag=%23Go")); ids != fmt.Sprint([]uint64{tagged.ID}) {
+		t.Fatalf("unexpected results by tag: %s", ids)
+	}
+
+	tomorrow := url.QueryEscape(time.Now().Add(24 * time.Hour).Format(time.RFC3339))
+	if ids := search("q=go&since=" + tomorrow); len(ids) != 0 {
+		t.Fatalf("expected no results since tomorrow: %v", ids)
+	}
+	if ids := search("q=go&until=2020-01-01"); len(ids) != 0 {
+		t.Fatalf("expected no results until 2020: %v", ids)
+	}
+	if ids := search("q=go&since=2020-01-01&until=" + tomorrow); len(ids) != 3 {
+		t.Fatalf("expected results within the date range: %v", ids)
+	}
+
+	server.expect(http.StatusBadRequest, http.MethodGet, "/search/publications?q=+", grace.token, nil)
+	server.expect(http.StatusBadRequest, http.MethodGet, "/search/publications?q=go&since=yesterday", grace.token, nil)
+	server.expect(http.StatusBadRequest, http.MethodGet,
+		"/search/publications?q=go&since=2021-01-02&until=2021-01-01", grace.token, nil)
+	server.expect(http.StatusBadRequest, http.MethodGet, "/search/publications?q=go&author=ada", grace.token, nil)
+}
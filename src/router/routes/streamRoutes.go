@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// streamRoutes returns routes handled by the stream controller
+func streamRoutes(controller *controllers.StreamController) []Route {
+	return []Route{
+		{
+			URI:                    "/stream",
+			Method:                 http.MethodGet,
+			Function:               controller.Stream,
+			RequiresAuthentication: true,
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"devbook/src/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStream is an open event stream of a user
+type testStream struct {
+	t      *testing.T
+	reader *bufio.Reader
+	close  context.CancelFunc
+}
+
+// streamEvent is an event read from a stream
+type streamEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// stream opens the event stream of a user, resuming after lastEventId when given
+func (server *testServer) stream(user testUser, lastEventId string) *testStream {
+	server.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	server.t.Cleanup(cancel)
+
+	request, error := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stream", nil)
+	if error != nil {
+		server.t.Fatal(error)
+	}
+	request.Header.Set("Authorization", "Bearer "+user.token)
+	if lastEventId != "" {
+		request.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	response, error := server.Client().Do(request)
+	if error != nil {
+		server.t.Fatal(error)
+	}
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		server.t.Fatalf("unexpected stream response: %d %s", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	return &testStream{server.t, bufio.NewReader(response.Body), func() {
+		cancel()
+		response.Body.Close()
+	}}
+}
+
+// next reads the next event of the stream, skipping comments
+func (stream *testStream) next() streamEvent {
+	stream.t.Helper()
+
+	var event streamEvent
+	for {
+		line, error := stream.reader.ReadString('\n')
+		if error != nil {
+			stream.t.Fatalf("cannot read event stream: %v", error)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// heartbeat reads the stream up to the next heartbeat comment
+func (stream *testStream) heartbeat() {
+	stream.t.Helper()
+
+	for {
+		line, error := stream.reader.ReadString('\n')
+		if error != nil {
+			stream.t.Fatalf("cannot read event stream: %v", error)
+		}
+		if line == ": heartbeat\n" {
+			return
+		}
+	}
+}
+
+// nextPublication reads the next event of the stream, expecting a publication
+func (stream *testStream) nextPublication() (streamEvent, models.Publication) {
+	stream.t.Helper()
+
+	event := stream.next()
+	var publication models.Publication
+	if error := json.Unmarshal([]byte(event.data), &publication); event.event != "publication" || error != nil {
+		stream.t.Fatalf("expected a publication event, got %+v", event)
+	}
+	return event, publication
+}
+
+func TestStream(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	own := server.publish(grace, "Own notes")
+
+	graceStream := server.stream(grace, "")
+	linusStream := server.stream(linus, "")
+	linusStream.heartbeat()
+
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Hidden", "content": "Draft", "visibility": "private",
+	})
+	published := server.publish(ada, "Notes")
+	server.publish(linus, "Linus notes")
+
+	if _, publication := graceStream.nextPublication(); publication.ID != published.ID || publication.AuthorNick != "ada" {
+		t.Fatalf("expected the publication of a followed user, got %+v", publication)
+	}
+
+	if _, publication := linusStream.nextPublication(); publication.AuthorId != linus.id {
+		t.Fatalf("expected only the own publications of a user following nobody, got %+v", publication)
+	}
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", grace.id), ada.token, nil)
+
+	event := graceStream.next()
+	var notification models.Notification
+	if error := json.Unmarshal([]byte(event.data), &notification); error != nil || event.event != "notification" ||
+		notification.Type != models.NotificationFollow || notification.ActorId != ada.id {
+		t.Fatalf("expected a follow notification, got %+v", event)
+	}
+
+	graceStream.close()
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/publications/%d/like", own.ID), ada.token, nil)
+	server.publish(linus, "Unfollowed notes")
+	missed := server.publish(ada, "More notes")
+
+	resumed := server.stream(grace, event.id)
+	if event = resumed.next(); event.event != "notification" || !strings.Contains(event.data, `"type":"like"`) {
+		t.Fatalf("expected the missed notification first, got %+v", event)
+	}
+	if _, publication := resumed.nextPublication(); publication.ID != missed.ID {
+		t.Fatalf("expected the missed publication, got %+v", publication)
+	}
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", linus.id), grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/publications/%d/repost", published.ID), linus.token, nil)
+
+	if _, publication := resumed.nextPublication(); publication.ID != published.ID || publication.RepostedBy != "linus" {
+		t.Fatalf("expected the repost of a followed user, got %+v", publication)
+	}
+}
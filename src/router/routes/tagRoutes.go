@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// tagRoutes returns routes handled by the tag controller
+func tagRoutes(controller *controllers.TagController) []Route {
+	return []Route{
+		{
+			URI:                    "/tags/trending",
+			Method:                 http.MethodGet,
+			Function:               controller.GetTrendingTags,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/tags/{tag}/publications",
+			Method:                 http.MethodGet,
+			Function:               controller.GetTagPublications,
+			RequiresAuthentication: true,
+		},
+	}
+}
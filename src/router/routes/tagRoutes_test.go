@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"devbook/src/models"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestTagPublications(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	var tagged models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Learning #Go", "content": "Channels and #goroutines, #go again",
+	}), &tagged)
+	if fmt.Sprint(tagged.Tags) != "[go goroutines]" {
+		t.Fatalf("unexpected tags: %+v", tagged.Tags)
+	}
+
+	server.publish(ada, "Untagged")
+	second := server.publish(grace, "More #go")
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", grace.token, map[string]string{
+		"title": "Private", "content": "Hidden #go", "visibility": "private",
+	})
+
+	var publications []models.Publication
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/tags/GO/publications", ada.token, nil), &publications)
+	if len(publications) != 2 || publications[0].ID != second.ID || publications[1].ID != tagged.ID {
+		t.Fatalf("unexpected tag publications: %+v", publications)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/publications/%d", tagged.ID), ada.token,
+		map[string]string{"title": "Learning #Rust", "content": "Ownership"})
+
+	publications = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/tags/go/publications", ada.token, nil), &publications)
+	if len(publications) != 1 || publications[0].ID != second.ID {
+		t.Fatalf("expected updates to replace tags: %+v", publications)
+	}
+
+	var stored models.Publication
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/publications/%d", tagged.ID), ada.token, nil), &stored)
+	if fmt.Sprint(stored.Tags) != "[rust]" {
+		t.Fatalf("unexpected updated tags: %+v", stored.Tags)
+	}
+}
+
+func TestTrendingTags(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+
+	var tags []models.TrendingTag
+	server.decode(server.expect(http.StatusOK, http.MethodGet, "/tags/trending", ada.token, nil), &tags)
+	if len(tags) != 0 {
+		t.Fatalf("expected no trending tags: %+v", tags)
+	}
+
+	for _, title := range []string{"#go #rust", "#go #zig", "#go #rust", "#zig #elixir"} {
+		server.publish(ada, title)
+	}
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "#elixir", "content": "Followers #elixir", "visibility": "followers",
+	})
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "#elixir", "content": "Private #elixir", "visibility": "private",
+	})
+
+	server.decode(server.expect(http.StatusOK, http.MethodGet, "/tags/trending", ada.token, nil), &tags)
+	if len(tags) != 2 || tags[0].Tag != "go" || tags[0].Uses != 3 || tags[1].Tag != "rust" || tags[1].Velocity != 2 {
+		t.Fatalf("unexpected trending tags: %+v", tags)
+	}
+
+	server.decode(server.expect(http.StatusOK, http.MethodGet, "/tags/trending?limit=4", ada.token, nil), &tags)
+	if len(tags) != 4 || tags[2].Tag != "zig" || tags[3].Tag != "elixir" {
+		t.Fatalf("unexpected trending tags: %+v", tags)
+	}
+
+	server.expect(http.StatusBadRequest, http.MethodGet, "/tags/trending?limit=0", ada.token, nil)
+}
@@ -5,65 +5,140 @@ import (
 	"net/http"
 )
 
-var userRoutes = []Route{
-	{
-		URI:                    "/users",
-		Method:                 http.MethodPost,
-		Function:               controllers.CreateUser,
-		RequiresAuthentication: false,
-	},
-	{
-		URI:                    "/users",
-		Method:                 http.MethodGet,
-		Function:               controllers.ListUsers,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}",
-		Method:                 http.MethodGet,
-		Function:               controllers.FindUserById,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}",
-		Method:                 http.MethodPut,
-		Function:               controllers.UpdateUser,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}",
-		Method:                 http.MethodDelete,
-		Function:               controllers.DeleteUser,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}/follow",
-		Method:                 http.MethodPost,
-		Function:               controllers.FollowUser,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}/unfollow",
-		Method:                 http.MethodPost,
-		Function:               controllers.UnfollowUser,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}/followers",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetUserFollowers,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}/followed",
-		Method:                 http.MethodGet,
-		Function:               controllers.GetFollowedUsers,
-		RequiresAuthentication: true,
-	},
-	{
-		URI:                    "/users/{id}/update-password",
-		Method:                 http.MethodPost,
-		Function:               controllers.UpdatePassword,
-		RequiresAuthentication: true,
-	},
+// userRoutes returns routes handled by the user controller
+func userRoutes(controller *controllers.UserController) []Route {
+	return []Route{
+		{
+			URI:                    "/users",
+			Method:                 http.MethodPost,
+			Function:               controller.CreateUser,
+			RequiresAuthentication: false,
+		},
+		{
+			URI:                    "/users/verify",
+			Method:                 http.MethodGet,
+			Function:               controller.VerifyEmail,
+			RequiresAuthentication: false,
+		},
+		{
+			URI:                    "/users/verify/resend",
+			Method:                 http.MethodPost,
+			Function:               controller.ResendVerification,
+			RequiresAuthentication: false,
+		},
+		{
+			URI:                    "/users",
+			Method:                 http.MethodGet,
+			Function:               controller.ListUsers,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}",
+			Method:                 http.MethodGet,
+			Function:               controller.FindUserById,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}",
+			Method:                 http.MethodPut,
+			Function:               controller.UpdateUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}",
+			Method:                 http.MethodDelete,
+			Function:               controller.DeleteUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/follow",
+			Method:                 http.MethodPost,
+			Function:               controller.FollowUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/unfollow",
+			Method:                 http.MethodPost,
+			Function:               controller.UnfollowUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/followers",
+			Method:                 http.MethodGet,
+			Function:               controller.GetUserFollowers,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/followed",
+			Method:                 http.MethodGet,
+			Function:               controller.GetFollowedUsers,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/privacy",
+			Method:                 http.MethodPut,
+			Function:               controller.UpdatePrivacy,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/follow-requests",
+			Method:                 http.MethodGet,
+			Function:               controller.GetFollowRequests,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/follow-requests/{followerId}/approve",
+			Method:                 http.MethodPost,
+			Function:               controller.ApproveFollowRequest,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/follow-requests/{followerId}/reject",
+			Method:                 http.MethodPost,
+			Function:               controller.RejectFollowRequest,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/block",
+			Method:                 http.MethodPost,
+			Function:               controller.BlockUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/unblock",
+			Method:                 http.MethodPost,
+			Function:               controller.UnblockUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/mute",
+			Method:                 http.MethodPost,
+			Function:               controller.MuteUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/unmute",
+			Method:                 http.MethodPost,
+			Function:               controller.UnmuteUser,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/blocked",
+			Method:                 http.MethodGet,
+			Function:               controller.GetBlockedUsers,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/muted",
+			Method:                 http.MethodGet,
+			Function:               controller.GetMutedUsers,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/users/{id}/update-password",
+			Method:                 http.MethodPost,
+			Function:               controller.UpdatePassword,
+			RequiresAuthentication: true,
+		},
+	}
 }
@@ -0,0 +1,499 @@
+package routes
+
+import (
+	"devbook/src/config"
+	"devbook/src/models"
+	"devbook/src/security"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCreateUser(t *testing.T) {
+	server := newTestServer(t)
+	server.signUp("ada")
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/users", "", map[string]string{
+		"name":  "No Password",
+		"nick":  "nopassword",
+		"email": "nopassword@devbook.com",
+	})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/users", "", map[string]string{
+		"name":     "Bad Email",
+		"nick":     "bademail",
+		"email":    "not-an-email",
+		"password": "secret",
+	})
+	server.expect(http.StatusInternalServerError, http.MethodPost, "/users", "", map[string]string{
+		"name":     "Duplicate Nick",
+		"nick":     "ada",
+		"email":    "other@devbook.com",
+		"password": "secret",
+	})
+	server.expect(http.StatusInternalServerError, http.MethodPost, "/users", "", map[string]string{
+		"name":     "Duplicate Email",
+		"nick":     "other",
+		"email":    "ada@devbook.com",
+		"password": "secret",
+	})
+}
+
+func TestListAndFindUsers(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	server.signUp("grace")
+
+	var users []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/users?desc=ADA", ada.token, nil), &users)
+	if len(users) != 1 || users[0].ID != ada.id || users[0].Password != "" {
+		t.Fatalf("unexpected users for description: %+v", users)
+	}
+
+	var user models.User
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d", ada.id), ada.token, nil), &user)
+	if user.Nick != "ada" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+
+	server.expect(http.StatusNotFound, http.MethodGet, "/users/999", ada.token, nil)
+	server.expect(http.StatusBadRequest, http.MethodGet, "/users/abc", ada.token, nil)
+}
+
+func TestUpdateUser(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	update := map[string]string{
+		"name":  "Ada Lovelace",
+		"nick":  "lovelace",
+		"email": "lovelace@devbook.com",
+	}
+	server.expect(http.StatusForbidden, http.MethodPut,
+		fmt.Sprintf("/users/%d", ada.id), grace.token, update)
+	server.expect(http.StatusNoContent, http.MethodPut,
+		fmt.Sprintf("/users/%d", ada.id), ada.token, update)
+
+	var user models.User
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d", ada.id), ada.token, nil), &user)
+	if user.Name != "Ada Lovelace" || user.Nick != "lovelace" {
+		t.Fatalf("user was not updated: %+v", user)
+	}
+
+	update["nick"] = "grace"
+	server.expect(http.StatusInternalServerError, http.MethodPut,
+		fmt.Sprintf("/users/%d", ada.id), ada.token, update)
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	var publication models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token,
+		map[string]string{"title": "Notes", "content": "On the analytical engine"}), &publication)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+
+	server.expect(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/users/%d", ada.id), grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodDelete, fmt.Sprintf("/users/%d", ada.id), ada.token, nil)
+
+	server.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/users/%d", ada.id), grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet,
+		fmt.Sprintf("/publications/%d", publication.ID), grace.token, nil)
+
+	var followed []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followed", grace.id), grace.token, nil), &followed)
+	if len(followed) != 0 {
+		t.Fatalf("follow was not removed with user: %+v", followed)
+	}
+}
+
+func TestFollowAndUnfollowUser(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	server.expect(http.StatusBadRequest, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), ada.token, nil)
+	server.expect(http.StatusBadRequest, http.MethodPost, fmt.Sprintf("/users/%d/unfollow", ada.id), ada.token, nil)
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+
+	var followers []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followers", ada.id), ada.token, nil), &followers)
+	if len(followers) != 1 || followers[0].ID != grace.id {
+		t.Fatalf("unexpected followers: %+v", followers)
+	}
+
+	var followed []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followed", grace.id), grace.token, nil), &followed)
+	if len(followed) != 1 || followed[0].ID != ada.id {
+		t.Fatalf("unexpected followed users: %+v", followed)
+	}
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/unfollow", ada.id), grace.token, nil)
+
+	followers = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followers", ada.id), ada.token, nil), &followers)
+	if len(followers) != 0 {
+		t.Fatalf("unexpected followers after unfollow: %+v", followers)
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	uri := fmt.Sprintf("/users/%d/update-password", ada.id)
+	session := server.authenticate(ada.email, ada.password)
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, uri, grace.token,
+		map[string]string{"previous": grace.password, "new": "hijacked"})
+	server.expect(http.StatusUnauthorized, http.MethodPost, uri, ada.token,
+		map[string]string{"previous": "wrong-password", "new": "new-secret"})
+	server.expect(http.StatusOK, http.MethodPost, uri, ada.token,
+		map[string]string{"previous": ada.password, "new": "new-secret"})
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/token/refresh", "",
+		models.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+
+	server.expect(http.StatusUnauthorized, http.MethodPost, "/login", "",
+		map[string]string{"email": ada.email, "password": ada.password})
+	server.login(ada.email, "new-secret")
+}
+
+const verificationSubject = "Verify your devbook email address"
+
+func TestVerifyEmail(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+
+	var user models.User
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d", ada.id), ada.token, nil), &user)
+	if user.EmailVerifiedAt != nil {
+		t.Fatalf("expected a new user to be unverified: %+v", user)
+	}
+
+	token := server.mailer.lastToken(t, ada.email, verificationSubject)
+
+	server.expect(http.StatusBadRequest, http.MethodGet, "/users/verify?token=invalid", "", nil)
+	server.expect(http.StatusOK, http.MethodGet, "/users/verify?token="+url.QueryEscape(token), "", nil)
+	server.expect(http.StatusBadRequest, http.MethodGet, "/users/verify?token="+url.QueryEscape(token), "", nil)
+
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d", ada.id), ada.token, nil), &user)
+	if user.EmailVerifiedAt == nil {
+		t.Fatalf("expected user to be verified: %+v", user)
+	}
+
+	server.expect(http.StatusAccepted, http.MethodPost, "/users/verify/resend", "",
+		models.VerificationResend{Email: ada.email})
+	if sent := server.mailer.sentTo(ada.email, verificationSubject); sent != 1 {
+		t.Fatalf("expected no token for a verified user, %d sent", sent)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/users/%d", ada.id), ada.token,
+		map[string]string{"name": "Ada", "nick": "ada", "email": "lovelace@devbook.com"})
+	user = models.User{}
+	server.decode(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d", ada.id), ada.token, nil), &user)
+	if user.EmailVerifiedAt != nil {
+		t.Fatalf("expected a changed email to be unverified: %+v", user)
+	}
+	server.mailer.lastToken(t, "lovelace@devbook.com", verificationSubject)
+}
+
+func TestVerifyEmailAfterEmailChange(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	stale := server.mailer.lastToken(t, ada.email, verificationSubject)
+
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/users/%d", ada.id), ada.token,
+		map[string]string{"name": "Ada", "nick": "ada", "email": "lovelace@devbook.com"})
+	server.expect(http.StatusBadRequest, http.MethodGet, "/users/verify?token="+url.QueryEscape(stale), "", nil)
+
+	server.expect(http.StatusAccepted, http.MethodPost, "/users/verify/resend", "",
+		models.VerificationResend{Email: "lovelace@devbook.com"})
+	server.mailer.awaitSent(t, "lovelace@devbook.com", verificationSubject, 2)
+	resent := server.mailer.lastToken(t, "lovelace@devbook.com", verificationSubject)
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/users/%d", ada.id), ada.token,
+		map[string]string{"name": "Ada", "nick": "ada", "email": "ada@devbook.com"})
+	if token, error := server.repositories.OneTimeTokens.GetOneTimeToken(models.TokenPurposeEmailVerification,
+		security.HashToken(resent)); error != nil || token.ID != 0 {
+		t.Fatalf("expected tokens sent to a former email deleted: %+v %v", token, error)
+	}
+	server.expect(http.StatusBadRequest, http.MethodGet, "/users/verify?token="+url.QueryEscape(resent), "", nil)
+
+	var user models.User
+	server.decode(server.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d", ada.id), ada.token, nil), &user)
+	if user.EmailVerifiedAt != nil {
+		t.Fatalf("expected the email left unverified: %+v", user)
+	}
+
+	token := server.mailer.lastToken(t, "ada@devbook.com", verificationSubject)
+	server.expect(http.StatusOK, http.MethodGet, "/users/verify?token="+url.QueryEscape(token), "", nil)
+}
+
+func TestResendVerification(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+
+	server.expect(http.StatusAccepted, http.MethodPost, "/users/verify/resend", "",
+		models.VerificationResend{Email: ada.email})
+	server.expect(http.StatusAccepted, http.MethodPost, "/users/verify/resend", "",
+		models.VerificationResend{Email: "nobody@devbook.com"})
+
+	server.mailer.awaitSent(t, ada.email, verificationSubject, 2)
+
+	token := server.mailer.lastToken(t, ada.email, verificationSubject)
+	server.expect(http.StatusOK, http.MethodGet, "/users/verify?token="+url.QueryEscape(token), "", nil)
+}
+
+func TestEmailVerificationRequirements(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+
+	defer func() { config.EmailVerificationRequiredFor = "" }()
+
+	config.EmailVerificationRequiredFor = config.EmailVerificationRequiredForPublication
+	server.expect(http.StatusForbidden, http.MethodPost, "/publications", ada.token,
+		map[string]string{"title": "Hello", "content": "World"})
+
+	config.EmailVerificationRequiredFor = config.EmailVerificationRequiredForLogin
+	server.expect(http.StatusForbidden, http.MethodPost, "/login", "",
+		map[string]string{"email": ada.email, "password": ada.password})
+
+	token := server.mailer.lastToken(t, ada.email, verificationSubject)
+	server.expect(http.StatusOK, http.MethodGet, "/users/verify?token="+url.QueryEscape(token), "", nil)
+
+	server.login(ada.email, ada.password)
+	config.EmailVerificationRequiredFor = config.EmailVerificationRequiredForPublication
+	server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token,
+		map[string]string{"title": "Hello", "content": "World"})
+}
+
+func TestPaginatedUsers(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	for _, nick := range []string{"grace", "linus", "ken", "barbara"} {
+		follower := server.signUp(nick)
+		server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), follower.token, nil)
+	}
+
+	var followers []models.User
+	cursor := server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followers?limit=3", ada.id), ada.token, nil), &followers)
+	if len(followers) != 3 || cursor == "" {
+		t.Fatalf("expected a full first page of followers: %+v", followers)
+	}
+
+	var rest []models.User
+	cursor = server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followers?limit=3&cursor=%s", ada.id, cursor), ada.token, nil), &rest)
+	if len(rest) != 1 || cursor != "" || rest[0].ID <= followers[2].ID {
+		t.Fatalf("unexpected last page of followers: %+v", rest)
+	}
+
+	var users []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/users?limit=1000", ada.token, nil), &users)
+	if len(users) != 5 {
+		t.Fatalf("expected all users within the maximum page size: %+v", users)
+	}
+}
+
+func TestPrivateAccountFollowRequests(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	server.expect(http.StatusForbidden, http.MethodPut, fmt.Sprintf("/users/%d/privacy", ada.id), grace.token,
+		models.PrivacyUpdate{IsPrivate: true})
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/users/%d/privacy", ada.id), ada.token,
+		models.PrivacyUpdate{IsPrivate: true})
+
+	var followersOnly models.Publication
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/publications", ada.token, map[string]string{
+		"title": "Followers", "content": "For followers", "visibility": "followers",
+	}), &followersOnly)
+	publicationUri := fmt.Sprintf("/publications/%d", followersOnly.ID)
+
+	server.expect(http.StatusNotFound, http.MethodPost, "/users/999/follow", grace.token, nil)
+	server.expect(http.StatusAccepted, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusAccepted, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusAccepted, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), linus.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, publicationUri, grace.token, nil)
+
+	var followers []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followers", ada.id), ada.token, nil), &followers)
+	if len(followers) != 0 {
+		t.Fatalf("expected pending requests not to be followers: %+v", followers)
+	}
+
+	server.expect(http.StatusForbidden, http.MethodGet,
+		fmt.Sprintf("/users/%d/follow-requests", ada.id), grace.token, nil)
+
+	var requests []models.FollowRequest
+	cursor := server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/follow-requests?limit=1", ada.id), ada.token, nil), &requests)
+	if len(requests) != 1 || requests[0].Follower.ID != grace.id || cursor == "" {
+		t.Fatalf("unexpected first page of follow requests: %+v", requests)
+	}
+	requests = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/follow-requests?limit=1&cursor=%s", ada.id, cursor), ada.token, nil), &requests)
+	if len(requests) != 1 || requests[0].Follower.ID != linus.id {
+		t.Fatalf("unexpected second page of follow requests: %+v", requests)
+	}
+
+	approveUri := fmt.Sprintf("/users/%d/follow-requests/%d/approve", ada.id, grace.id)
+	rejectUri := fmt.Sprintf("/users/%d/follow-requests/%d/reject", ada.id, linus.id)
+
+	server.expect(http.StatusForbidden, http.MethodPost, approveUri, grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPost, approveUri, ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, approveUri, ada.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPost, rejectUri, ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, rejectUri, ada.token, nil)
+
+	server.expect(http.StatusOK, http.MethodGet, publicationUri, grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, publicationUri, linus.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+
+	server.expect(http.StatusAccepted, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), linus.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPut, fmt.Sprintf("/users/%d/privacy", ada.id), ada.token,
+		models.PrivacyUpdate{IsPrivate: false})
+	server.expect(http.StatusOK, http.MethodGet, publicationUri, linus.token, nil)
+
+	var user models.User
+	server.decode(server.expect(http.StatusOK, http.MethodGet, fmt.Sprintf("/users/%d", ada.id), grace.token, nil), &user)
+	if user.IsPrivate {
+		t.Fatalf("expected a public account: %+v", user)
+	}
+}
+
+func TestBlockAndMuteUsers(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	linus := server.signUp("linus")
+
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", grace.id), ada.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", linus.id), ada.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+
+	adaPublication := server.publish(ada, "By ada")
+	server.publish(grace, "By grace")
+	linusPublication := server.publish(linus, "By linus")
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/publications/%d/repost", linusPublication.ID), grace.token, nil)
+
+	feedAuthors := func() string {
+		var feed []models.Publication
+		server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/publications", ada.token, nil), &feed)
+
+		var authors []string
+		for _, publication := range feed {
+			authors = append(authors, publication.AuthorNick+"/"+publication.RepostedBy)
+		}
+		return fmt.Sprint(authors)
+	}
+
+	server.expect(http.StatusBadRequest, http.MethodPost, fmt.Sprintf("/users/%d/mute", ada.id), ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodPost, "/users/999/mute", ada.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/mute", linus.id), ada.token, nil)
+
+	if authors := feedAuthors(); authors != "[grace/ ada/]" {
+		t.Fatalf("expected muted authors left out of the feed, got %s", authors)
+	}
+
+	var users []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/users?desc=linus", ada.token, nil), &users)
+	if len(users) != 0 {
+		t.Fatalf("expected muted users left out of searches: %+v", users)
+	}
+
+	server.expect(http.StatusForbidden, http.MethodGet, fmt.Sprintf("/users/%d/muted", ada.id), grace.token, nil)
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/muted", ada.id), ada.token, nil), &users)
+	if len(users) != 1 || users[0].ID != linus.id {
+		t.Fatalf("unexpected muted users: %+v", users)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/unmute", linus.id), ada.token, nil)
+	if authors := feedAuthors(); authors != "[linus/grace linus/ grace/ ada/]" {
+		t.Fatalf("expected unmuted authors back in the feed, got %s", authors)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/block", grace.id), ada.token, nil)
+
+	var followers []models.User
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/followers", ada.id), ada.token, nil), &followers)
+	if len(followers) != 0 {
+		t.Fatalf("expected blocks to remove followers: %+v", followers)
+	}
+	if authors := feedAuthors(); authors != "[linus/ ada/]" {
+		t.Fatalf("expected blocked users and their reposts left out of the feed, got %s", authors)
+	}
+
+	users = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/users?desc=ada", grace.token, nil), &users)
+	if len(users) != 0 {
+		t.Fatalf("expected users blocking the viewer left out of searches: %+v", users)
+	}
+
+	adaUri := fmt.Sprintf("/publications/%d", adaPublication.ID)
+	server.expect(http.StatusForbidden, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusForbidden, http.MethodPost, adaUri+"/like", grace.token, nil)
+	server.expect(http.StatusForbidden, http.MethodPost, adaUri+"/repost", grace.token, nil)
+	server.expect(http.StatusForbidden, http.MethodPost, adaUri+"/comments", grace.token,
+		map[string]string{"content": "Hello"})
+
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/users/%d/blocked", ada.id), ada.token, nil), &users)
+	if len(users) != 1 || users[0].ID != grace.id {
+		t.Fatalf("unexpected blocked users: %+v", users)
+	}
+
+	server.expect(http.StatusNoContent, http.MethodPost, fmt.Sprintf("/users/%d/unblock", grace.id), ada.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, adaUri+"/like", grace.token, nil)
+}
+
+func TestFollowsRecheckBlocks(t *testing.T) {
+	server := newTestServer(t)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+	users := server.repositories.Users
+
+	// a block landing after the checks of the follow requests
+	if error := users.BlockUser(grace.id, ada.id); error != nil {
+		t.Fatal(error)
+	}
+
+	if following, error := users.FollowUser(grace.id, ada.id); error != nil || following {
+		t.Fatalf("expected a follow of a user blocking the follower refused: %v %v", following, error)
+	}
+	if error := users.CreateFollowRequest(ada.id, grace.id); error != nil {
+		t.Fatal(error)
+	}
+	if approved, error := users.ApproveFollowRequest(ada.id, grace.id); error != nil || approved {
+		t.Fatalf("expected no request between blocked users: %v %v", approved, error)
+	}
+
+	for _, pair := range [][2]uint64{{grace.id, ada.id}, {ada.id, grace.id}} {
+		if following, error := users.IsFollower(pair[0], pair[1]); error != nil || following {
+			t.Fatalf("expected no follow between blocked users %v: %v", pair, error)
+		}
+	}
+}
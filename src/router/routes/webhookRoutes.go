@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"devbook/src/controllers"
+	"net/http"
+)
+
+// webhookRoutes returns routes handled by the webhook controller
+func webhookRoutes(controller *controllers.WebhookController) []Route {
+	return []Route{
+		{
+			URI:                    "/webhooks",
+			Method:                 http.MethodGet,
+			Function:               controller.GetWebhooks,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/webhooks",
+			Method:                 http.MethodPost,
+			Function:               controller.CreateWebhook,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/webhooks/{id}",
+			Method:                 http.MethodDelete,
+			Function:               controller.DeleteWebhook,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/webhooks/{id}/deliveries",
+			Method:                 http.MethodGet,
+			Function:               controller.GetDeliveries,
+			RequiresAuthentication: true,
+		},
+		{
+			URI:                    "/webhooks/{id}/deliveries/{deliveryId}/retry",
+			Method:                 http.MethodPost,
+			Function:               controller.RetryDelivery,
+			RequiresAuthentication: true,
+		},
+	}
+}
@@ -0,0 +1,354 @@
+package routes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"devbook/src/config"
+	"devbook/src/models"
+	"devbook/src/webhooks"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receivedDelivery is a webhook delivery posted to a test receiver
+type receivedDelivery struct {
+	event     string
+	delivery  string
+	signature string
+	body      []byte
+}
+
+// testReceiver is a webhook receiver answering with a configurable status
+type testReceiver struct {
+	*httptest.Server
+	t          *testing.T
+	mutex      sync.Mutex
+	status     int
+	deliveries chan receivedDelivery
+}
+
+// newTestReceiver starts a webhook receiver answering with status
+func newTestReceiver(t *testing.T, status int) *testReceiver {
+	receiver := &testReceiver{t: t, status: status, deliveries: make(chan receivedDelivery, 100)}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		receiver.deliveries <- receivedDelivery{
+			r.Header.Get(webhooks.EventHeader), r.Header.Get(webhooks.DeliveryHeader), r.Header.Get(webhooks.SignatureHeader), body,
+		}
+
+		receiver.mutex.Lock()
+		defer receiver.mutex.Unlock()
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+// answer makes the receiver answer with status from now on
+func (receiver *testReceiver) answer(status int) {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+
+	receiver.status = status
+}
+
+// next waits for the next delivery posted to the receiver, checking its signature with secret
+func (receiver *testReceiver) next(secret string) (receivedDelivery, models.WebhookPayload) {
+	receiver.t.Helper()
+
+	select {
+	case delivery := <-receiver.deliveries:
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(delivery.body)
+		if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); delivery.signature != expected {
+			receiver.t.Fatalf("expected signature %s, got %s", expected, delivery.signature)
+		}
+
+		var payload models.WebhookPayload
+		if error := json.Unmarshal(delivery.body, &payload); error != nil {
+			receiver.t.Fatalf("cannot decode %q: %v", delivery.body, error)
+		}
+		return delivery, payload
+	case <-time.After(5 * time.Second):
+		receiver.t.Fatal("no delivery received")
+		return receivedDelivery{}, models.WebhookPayload{}
+	}
+}
+
+// none checks no delivery is posted to the receiver for a while
+func (receiver *testReceiver) none() {
+	receiver.t.Helper()
+
+	select {
+	case delivery := <-receiver.deliveries:
+		receiver.t.Fatalf("unexpected delivery: %s %s", delivery.event, delivery.body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// createWebhook registers a webhook of a user posting events to url
+func (server *testServer) createWebhook(user testUser, url string, eventTypes ...string) models.Webhook {
+	server.t.Helper()
+
+	var webhook models.Webhook
+	server.decode(server.expect(http.StatusCreated, http.MethodPost, "/webhooks", user.token,
+		models.Webhook{URL: url, EventTypes: eventTypes}), &webhook)
+	return webhook
+}
+
+// awaitDeliveries waits until the delivery log of a webhook satisfies done, returning it
+func (server *testServer) awaitDeliveries(user testUser, webhook models.Webhook,
+	done func([]models.WebhookDelivery) bool) []models.WebhookDelivery {
+	server.t.Helper()
+
+	uri := fmt.Sprintf("/webhooks/%d/deliveries", webhook.ID)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var deliveries []models.WebhookDelivery
+		server.decodePage(server.expect(http.StatusOK, http.MethodGet, uri, user.token, nil), &deliveries)
+		if done(deliveries) {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			server.t.Fatalf("unexpected deliveries: %+v", deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	server := newTestServer(t)
+	receiver := newTestReceiver(t, http.StatusOK)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/webhooks", ada.token,
+		models.Webhook{URL: "ftp://example.com", EventTypes: []string{models.WebhookPublicationCreated}})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/webhooks", ada.token,
+		models.Webhook{URL: receiver.URL, EventTypes: []string{"publication.reposted"}})
+	server.expect(http.StatusBadRequest, http.MethodPost, "/webhooks", ada.token, models.Webhook{URL: receiver.URL})
+	for _, forbidden := range []string{
+		"http://[::1]:8080", "http://0.0.0.0", "http://10.0.0.1/hooks", "http://169.254.169.254/latest", "http://[fd00::1]",
+	} {
+		server.expect(http.StatusBadRequest, http.MethodPost, "/webhooks", ada.token,
+			models.Webhook{URL: forbidden, EventTypes: []string{models.WebhookPublicationCreated}})
+	}
+
+	webhook := server.createWebhook(ada, receiver.URL, models.WebhookEventTypes...)
+	if webhook.Secret == "" || webhook.UserId != ada.id || len(webhook.EventTypes) != len(models.WebhookEventTypes) {
+		t.Fatalf("unexpected webhook: %+v", webhook)
+	}
+
+	var listed []models.Webhook
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet, "/webhooks", ada.token, nil), &listed)
+	if len(listed) != 1 || listed[0].ID != webhook.ID || listed[0].Secret != "" {
+		t.Fatalf("expected the webhook listed without its secret: %+v", listed)
+	}
+
+	publication := server.publish(ada, "Notes")
+	uri := fmt.Sprintf("/publications/%d", publication.ID)
+	server.expect(http.StatusOK, http.MethodPost, fmt.Sprintf("/users/%d/follow", ada.id), grace.token, nil)
+	server.expect(http.StatusOK, http.MethodPost, uri+"/like", grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodPut, uri, ada.token, map[string]string{
+		"title": "Notes", "content": "Edited",
+	})
+	server.expect(http.StatusNoContent, http.MethodDelete, uri, ada.token, nil)
+	server.publish(grace, "Unrelated")
+
+	expected := []string{
+		models.WebhookPublicationCreated,
+		models.WebhookUserFollowed,
+		models.WebhookPublicationLiked,
+		models.WebhookPublicationUpdated,
+		models.WebhookPublicationDeleted,
+	}
+	for _, eventType := range expected {
+		delivery, payload := receiver.next(webhook.Secret)
+		if delivery.event != eventType || payload.Type != eventType || delivery.delivery == "" {
+			t.Fatalf("expected a %s delivery, got %s %s", eventType, delivery.event, delivery.body)
+		}
+
+		switch eventType {
+		case models.WebhookUserFollowed, models.WebhookPublicationLiked:
+			if payload.User == nil || payload.User.ID != grace.id || payload.User.Email != "" {
+				t.Fatalf("expected grace in the %s payload: %s", eventType, delivery.body)
+			}
+		case models.WebhookPublicationUpdated:
+			if payload.Publication == nil || payload.Publication.Content != "Edited" {
+				t.Fatalf("expected the edited publication: %s", delivery.body)
+			}
+		}
+		if eventType != models.WebhookUserFollowed && (payload.Publication == nil || payload.Publication.ID != publication.ID) {
+			t.Fatalf("expected the publication in the %s payload: %s", eventType, delivery.body)
+		}
+	}
+	receiver.none()
+
+	deliveries := server.awaitDeliveries(ada, webhook, func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 5 && deliveries[0].Status == models.DeliveryDelivered
+	})
+	for index, delivery := range deliveries {
+		if delivery.EventType != expected[len(expected)-1-index] || delivery.Status != models.DeliveryDelivered ||
+			delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK || delivery.DeliveredAt == nil {
+			t.Fatalf("unexpected delivery log: %+v", deliveries)
+		}
+	}
+
+	var page []models.WebhookDelivery
+	next := server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/webhooks/%d/deliveries?limit=3", webhook.ID), ada.token, nil), &page)
+	page = nil
+	server.decodePage(server.expect(http.StatusOK, http.MethodGet,
+		fmt.Sprintf("/webhooks/%d/deliveries?cursor=%s", webhook.ID, next), ada.token, nil), &page)
+	if len(page) != 2 || page[1].EventType != models.WebhookPublicationCreated {
+		t.Fatalf("unexpected second page of deliveries: %+v", page)
+	}
+
+	server.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/webhooks/%d/deliveries", webhook.ID), grace.token, nil)
+	server.expect(http.StatusNotFound, http.MethodDelete, fmt.Sprintf("/webhooks/%d", webhook.ID), grace.token, nil)
+	server.expect(http.StatusNoContent, http.MethodDelete, fmt.Sprintf("/webhooks/%d", webhook.ID), ada.token, nil)
+	server.expect(http.StatusNotFound, http.MethodGet, fmt.Sprintf("/webhooks/%d/deliveries", webhook.ID), ada.token, nil)
+
+	server.publish(ada, "After")
+	receiver.none()
+}
+
+func TestWebhookRetries(t *testing.T) {
+	server := newTestServer(t)
+	receiver := newTestReceiver(t, http.StatusInternalServerError)
+	ada := server.signUp("ada")
+
+	webhook := server.createWebhook(ada, receiver.URL, models.WebhookPublicationCreated)
+	server.publish(ada, "Notes")
+
+	first, _ := receiver.next(webhook.Secret)
+	for attempt := 2; attempt <= 3; attempt++ {
+		if delivery, _ := receiver.next(webhook.Secret); delivery.delivery != first.delivery {
+			t.Fatalf("expected attempt %d of delivery %s, got %s", attempt, first.delivery, delivery.delivery)
+		}
+	}
+
+	deliveries := server.awaitDeliveries(ada, webhook, func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 1 && deliveries[0].Status == models.DeliveryDead
+	})
+	dead := deliveries[0]
+	if dead.Attempts != 3 || dead.ResponseStatus != http.StatusInternalServerError || dead.LastError == "" ||
+		dead.NextAttemptAt != nil || dead.DeliveredAt != nil {
+		t.Fatalf("unexpected dead delivery: %+v", dead)
+	}
+	receiver.none()
+
+	retry := fmt.Sprintf("/webhooks/%d/deliveries/%d/retry", webhook.ID, dead.ID)
+	receiver.answer(http.StatusNoContent)
+	server.expect(http.StatusNotFound, http.MethodPost, fmt.Sprintf("/webhooks/%d/deliveries/999/retry", webhook.ID),
+		ada.token, nil)
+	server.expect(http.StatusAccepted, http.MethodPost, retry, ada.token, nil)
+
+	if delivery, _ := receiver.next(webhook.Secret); delivery.delivery != first.delivery {
+		t.Fatalf("expected delivery %s retried, got %s", first.delivery, delivery.delivery)
+	}
+	server.awaitDeliveries(ada, webhook, func(deliveries []models.WebhookDelivery) bool {
+		return deliveries[0].Status == models.DeliveryDelivered && deliveries[0].Attempts == 1 &&
+			deliveries[0].ResponseStatus == http.StatusNoContent && deliveries[0].LastError == ""
+	})
+
+	server.expect(http.StatusConflict, http.MethodPost, retry, ada.token, nil)
+}
+
+func TestWebhookWorkers(t *testing.T) {
+	server := newTestServer(t)
+	receiver := newTestReceiver(t, http.StatusNoContent)
+	ada := server.signUp("ada")
+	grace := server.signUp("grace")
+
+	posted := make(chan string, 10)
+	release := make(chan struct{})
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- r.Header.Get(webhooks.DeliveryHeader)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(unblock)
+
+	slowWebhook := server.createWebhook(ada, slow.URL, models.WebhookPublicationCreated)
+	webhook := server.createWebhook(grace, receiver.URL, models.WebhookPublicationCreated)
+	server.publish(ada, "First")
+	server.publish(ada, "Second")
+
+	var first string
+	select {
+	case first = <-posted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery posted to the slow receiver")
+	}
+
+	server.publish(grace, "Notes")
+	receiver.next(webhook.Secret)
+	if len(posted) != 0 {
+		t.Fatal("expected a single delivery to a webhook posted at once")
+	}
+
+	unblock()
+	server.awaitDeliveries(ada, slowWebhook, func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 2 && deliveries[0].Status == models.DeliveryDelivered &&
+			deliveries[1].Status == models.DeliveryDelivered
+	})
+	if second := <-posted; second == first {
+		t.Fatalf("expected delivery %s posted once", first)
+	}
+
+	later := time.Now().Add(time.Hour)
+	id, error := server.repositories.Webhooks.CreateDelivery(models.WebhookDelivery{
+		WebhookId: webhook.ID, EventType: models.WebhookPublicationCreated, Payload: []byte("{}"),
+		Status: models.DeliveryPending, NextAttemptAt: &later,
+	})
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	now := time.Now()
+	for attempt, expected := range []bool{true, false} {
+		if claimed, error := server.repositories.Webhooks.ClaimDelivery(id, now, now.Add(time.Minute)); error != nil ||
+			claimed != expected {
+			t.Fatalf("expected claim %d of a delivery to be %v, got %v: %v", attempt+1, expected, claimed, error)
+		}
+	}
+	if pending, error := server.repositories.Webhooks.GetPendingDeliveries(now, nil, 10); error != nil || len(pending) != 0 {
+		t.Fatalf("expected claimed deliveries left out of pending ones: %+v %v", pending, error)
+	}
+}
+
+func TestWebhookForbiddenAddresses(t *testing.T) {
+	allowed := config.WebhookAllowedNetworks
+	t.Cleanup(func() { config.WebhookAllowedNetworks = allowed })
+
+	server := newTestServer(t)
+	receiver := newTestReceiver(t, http.StatusNoContent)
+	ada := server.signUp("ada")
+
+	webhook := server.createWebhook(ada, receiver.URL, models.WebhookPublicationCreated)
+	config.WebhookAllowedNetworks = nil
+
+	server.expect(http.StatusBadRequest, http.MethodPost, "/webhooks", ada.token,
+		models.Webhook{URL: receiver.URL, EventTypes: []string{models.WebhookPublicationCreated}})
+
+	server.publish(ada, "Notes")
+	deliveries := server.awaitDeliveries(ada, webhook, func(deliveries []models.WebhookDelivery) bool {
+		return len(deliveries) == 1 && deliveries[0].Status == models.DeliveryDead
+	})
+	if !strings.Contains(deliveries[0].LastError, webhooks.ErrForbiddenAddress.Error()) {
+		t.Fatalf("expected the delivery refused at dial time: %+v", deliveries[0])
+	}
+	receiver.none()
+}
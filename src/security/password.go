@@ -1,13 +1,29 @@
 package security
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+)
+
+var (
+	// dummyHash is checked in place of the hash of unknown users, so they take as long as known ones
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
 
 // Hash generates a hash for a password
 func Hash(password string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 }
 
-// CheckPassword checks password against a hash
+// CheckPassword checks password against a hash, taking as long to fail when hash is empty
 func CheckPassword(hash, password string) error {
+	if hash == "" {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = Hash("devbook")
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-}
\ No newline at end of file
+}
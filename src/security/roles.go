@@ -0,0 +1,35 @@
+package security
+
+// Role represents a user role, each role granting the permissions of the roles below it
+type Role string
+
+const (
+	// RoleUser regular user, managing its own account and publications
+	RoleUser Role = "user"
+	// RoleModerator user allowed to edit or delete any publication and suspend users
+	RoleModerator Role = "moderator"
+	// RoleAdmin user allowed to manage any account and assign roles
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// IsValid tells if role is a known role
+func (role Role) IsValid() bool {
+	_, found := roleRanks[role]
+	return found
+}
+
+// Includes tells if role grants the permissions of required
+func (role Role) Includes(required Role) bool {
+	return role.IsValid() && roleRanks[role] >= roleRanks[required]
+}
+
+// Outranks tells if role is strictly above other
+func (role Role) Outranks(other Role) bool {
+	return role.IsValid() && roleRanks[role] > roleRanks[other]
+}
@@ -1,6 +1,7 @@
 package security
 
 import (
+	"context"
 	"devbook/src/config"
 	"errors"
 	"fmt"
@@ -11,49 +12,118 @@ import (
 	"time"
 )
 
+// mfaChallengePurpose purpose claim of tokens proving a password check awaiting a second factor
+const mfaChallengePurpose = "mfa_challenge"
+
 // GetToken generates a jwt token with user permissions
-func GetToken(userId uint64, userEmail string) (string, error) {
+func GetToken(userId uint64, userEmail string, role Role) (string, error) {
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
-	claims["exp"] = time.Now().Add(time.Hour * 6).Unix()
+	claims["exp"] = time.Now().Add(config.AccessTokenLifetime).Unix()
 	claims["userId"] = userId
 	claims["userEmail"] = userEmail
+	claims["role"] = string(role)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(config.SecretKey)
 }
 
-// ValidateToken validates a request JWT token
-func ValidateToken(r *http.Request) error {
-	stringToken := extractToken(r)
-	token, error := jwt.Parse(stringToken, getVerificationKey)
+// GetMFAChallengeToken generates a short lived jwt token, not valid for authenticated requests,
+// to be exchanged for an access token along with a second factor code
+func GetMFAChallengeToken(userId uint64) (string, error) {
+	claims := jwt.MapClaims{}
+	claims["purpose"] = mfaChallengePurpose
+	claims["exp"] = time.Now().Add(config.MFAChallengeLifetime).Unix()
+	claims["userId"] = userId
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(config.SecretKey)
+}
+
+// ParseMFAChallengeToken validates an MFA challenge token and extracts its user id
+func ParseMFAChallengeToken(stringToken string) (uint64, error) {
+	claims, error := parseToken(stringToken)
 	if error != nil {
-		return error
+		return 0, error
 	}
-	if _, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return nil
+	if claims["purpose"] != mfaChallengePurpose {
+		return 0, errors.New("Invalid Token")
 	}
-	return errors.New("Invalid Token")
+	return extractUserIdClaim(claims)
+}
+
+// ValidateToken validates a request JWT token
+func ValidateToken(r *http.Request) error {
+	_, error := parseAccessToken(r)
+	return error
 }
 
 // ExtractUserId extracts user id from jwt token
 func ExtractUserId(r *http.Request) (uint64, error) {
-	stringToken := extractToken(r)
-	token, error := jwt.Parse(stringToken, getVerificationKey)
+	claims, error := parseAccessToken(r)
 	if error != nil {
 		return 0, error
 	}
+	return extractUserIdClaim(claims)
+}
+
+// roleKey is the context key of the current role of the authenticated user
+type roleKey struct{}
+
+// WithRole returns a copy of a request carrying the current role of the authenticated user, which
+// ExtractRole prefers to the role of the token
+func WithRole(r *http.Request, role Role) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), roleKey{}, role))
+}
+
+// ExtractRole extracts user role from the request, or else from jwt token, tokens without role
+// belonging to regular users
+func ExtractRole(r *http.Request) (Role, error) {
+	claims, error := parseAccessToken(r)
+	if error != nil {
+		return "", error
+	}
+	if role, found := r.Context().Value(roleKey{}).(Role); found {
+		return role, nil
+	}
+	role, _ := claims["role"].(string)
+	if role == "" {
+		return RoleUser, nil
+	}
+	return Role(role), nil
+}
+
+// parseAccessToken validates a request JWT token, rejecting tokens issued for other purposes
+func parseAccessToken(r *http.Request) (jwt.MapClaims, error) {
+	claims, error := parseToken(extractToken(r))
+	if error != nil {
+		return nil, error
+	}
+	if _, found := claims["purpose"]; found {
+		return nil, errors.New("Invalid Token")
+	}
+	return claims, nil
+}
+
+func parseToken(stringToken string) (jwt.MapClaims, error) {
+	token, error := jwt.Parse(stringToken, getVerificationKey)
+	if error != nil {
+		return nil, error
+	}
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		return claims, nil
+	}
+	return nil, errors.New("Invalid Token")
+}
 
-		userId, error := strconv.ParseUint(
-			fmt.Sprintf("%.0f",claims["userId"]), 10, 64)
+func extractUserIdClaim(claims jwt.MapClaims) (uint64, error) {
+	userId, error := strconv.ParseUint(
+		fmt.Sprintf("%.0f", claims["userId"]), 10, 64)
 
-		if error != nil {
-			return 0, error
-		}
-		return userId, nil
+	if error != nil {
+		return 0, error
 	}
-	return 0, errors.New("Invalid Token")
+	return userId, nil
 }
 
 func getVerificationKey(token *jwt.Token) (interface{}, error) {
@@ -63,10 +133,15 @@ func getVerificationKey(token *jwt.Token) (interface{}, error) {
 	return config.SecretKey, nil
 }
 
+// extractToken reads the bearer token of a request, or the access_token query parameter of
+// WebSocket handshakes since browsers cannot set their headers
 func extractToken(r *http.Request) string {
 	token := r.Header.Get("Authorization")
 	if len(strings.Split(token, " ")) == 2 {
 		return strings.Split(token, " ")[1]
 	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get("access_token")
+	}
 	return ""
-}
\ No newline at end of file
+}
@@ -2,7 +2,11 @@ package security
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
 	"encoding/base64"
+	"encoding/hex"
+	"strings"
 )
 
 // GenerateBase64RandomToken generates a base64 random token
@@ -18,3 +22,32 @@ func GenerateBase64RandomToken() (string, error) {
 
 	return base64Token, nil
 }
+
+// HashToken generates the hash under which a random token is stored
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateRecoveryCodes generates human readable one time codes, like 7hq2m-xk4pd
+func GenerateRecoveryCodes(count int) ([]string, error) {
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	var codes []string
+	for index := 0; index < count; index++ {
+		key := make([]byte, 7)
+		if _, error := rand.Read(key); error != nil {
+			return nil, error
+		}
+		code := strings.ToLower(encoding.EncodeToString(key))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+
+	return codes, nil
+}
+
+// NormalizeRecoveryCode formats a recovery code typed by a user the way it was generated
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
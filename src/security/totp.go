@@ -0,0 +1,115 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// totpPeriod seconds each TOTP code remains valid, as recommended by RFC 6238
+	totpPeriod = 30
+	// totpDigits number of digits of TOTP codes
+	totpDigits = 6
+	// totpSkew steps before and after the current one accepted to tolerate clock drift
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret generates a base32 encoded TOTP secret
+func GenerateTOTPSecret() (string, error) {
+
+	key := make([]byte, 20)
+
+	if _, error := rand.Read(key); error != nil {
+		return "", error
+	}
+
+	return totpEncoding.EncodeToString(key), nil
+}
+
+// TOTPURI returns the otpauth URI authenticator apps use to enroll a secret
+func TOTPURI(issuer string, account string, secret string) string {
+	parameters := url.Values{}
+	parameters.Set("secret", secret)
+	parameters.Set("issuer", issuer)
+	parameters.Set("algorithm", "SHA1")
+	parameters.Set("digits", fmt.Sprint(totpDigits))
+	parameters.Set("period", fmt.Sprint(totpPeriod))
+
+	return fmt.Sprintf("otpauth://totp/%s:%s?%s",
+		url.PathEscape(issuer), url.PathEscape(account), parameters.Encode())
+}
+
+// TOTPCode generates the TOTP code of a secret at a given time
+func TOTPCode(secret string, at time.Time) (string, error) {
+
+	key, error := decodeTOTPSecret(secret)
+	if error != nil {
+		return "", error
+	}
+
+	return hotp(key, TOTPStep(at), totpDigits), nil
+}
+
+// TOTPStep returns the time step a TOTP code generated at a given time belongs to
+func TOTPStep(at time.Time) uint64 {
+	return uint64(at.Unix()) / totpPeriod
+}
+
+// ValidateTOTP checks a code against a secret around a given time, returning the matched time step
+func ValidateTOTP(secret string, code string, at time.Time) (uint64, bool) {
+
+	key, error := decodeTOTPSecret(secret)
+	if error != nil {
+		return 0, false
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(at)
+	for offset := -totpSkew; offset <= totpSkew; offset++ {
+		step := uint64(int64(current) + int64(offset))
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step, totpDigits)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp generates an HOTP code as described by RFC 4226
+func hotp(key []byte, counter uint64, digits int) string {
+
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for index := 0; index < digits; index++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	return totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+}
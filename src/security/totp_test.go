@@ -0,0 +1,61 @@
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors for HMAC-SHA1, with 8 digit codes
+func TestHOTPMatchesRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for seconds, expected := range vectors {
+		if code := hotp(key, TOTPStep(time.Unix(seconds, 0)), 8); code != expected {
+			t.Errorf("at %d expected %s, got %s", seconds, expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, error := GenerateTOTPSecret()
+	if error != nil {
+		t.Fatal(error)
+	}
+
+	now := time.Unix(1600000000, 0)
+
+	previous, _ := TOTPCode(secret, now.Add(-totpPeriod*time.Second))
+	if step, valid := ValidateTOTP(secret, previous, now); !valid || step != TOTPStep(now)-1 {
+		t.Errorf("expected the previous code to be accepted at step %d, got %d %v",
+			TOTPStep(now)-1, step, valid)
+	}
+
+	stale, _ := TOTPCode(secret, now.Add(-2*totpPeriod*time.Second))
+	if _, valid := ValidateTOTP(secret, stale, now); valid {
+		t.Error("expected a code two steps old to be rejected")
+	}
+
+	if _, valid := ValidateTOTP(secret, "abc", now); valid {
+		t.Error("expected a malformed code to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("devbook", "ada@devbook.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/devbook:ada@devbook.com?") ||
+		!strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") ||
+		!strings.Contains(uri, "issuer=devbook") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}
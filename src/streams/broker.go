@@ -0,0 +1,266 @@
+package streams
+
+import (
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/logging"
+	"devbook/src/persistence"
+	"sync"
+)
+
+const (
+	// MessagePublication a new item of the user's feed, the publication as seen by the user
+	MessagePublication = "publication"
+	// MessageNotification a new notification of the user
+	MessageNotification = "notification"
+)
+
+// Entry represents a published event along with its position in the sequence of events
+type Entry struct {
+	ID    uint64
+	Event events.Event
+}
+
+// Message represents an event as pushed to a user
+type Message struct {
+	ID   uint64
+	Type string
+	Data interface{}
+}
+
+// Subscription receives the events published while a user is connected, after the recent events
+// the user missed
+type Subscription struct {
+	UserId  uint64
+	Backlog []Entry
+	Entries <-chan Entry
+	entries chan Entry
+}
+
+// Broker keeps recent events and hands new ones to the subscriptions of connected users
+type Broker struct {
+	mutex         sync.Mutex
+	sequence      uint64
+	history       []Entry
+	subscriptions map[uint64]map[*Subscription]bool
+	users         persistence.UserStore
+	publications  persistence.PublicationStore
+}
+
+// NewBroker factory
+func NewBroker(users persistence.UserStore, publications persistence.PublicationStore) *Broker {
+	return &Broker{
+		subscriptions: make(map[uint64]map[*Subscription]bool),
+		users:         users,
+		publications:  publications,
+	}
+}
+
+// Subscribe makes the broker handle the events published on bus
+func (broker *Broker) Subscribe(bus *events.Bus) {
+	bus.Subscribe(broker.Handle)
+}
+
+// Handle records a notification, publication or repost and queues it to the subscriptions of the
+// users it concerns, dropping those whose queue is full so their clients reconnect and resume from
+// the history; other events are ignored
+func (broker *Broker) Handle(event events.Event) {
+
+	switch event.Type {
+	case events.NotificationCreated, events.PublicationCreated, events.PublicationReposted:
+	default:
+		return
+	}
+
+	recipients, connected := broker.recipients(event)
+
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.sequence++
+	entry := Entry{broker.sequence, event}
+
+	broker.history = append(broker.history, entry)
+	if len(broker.history) > config.StreamHistorySize {
+		broker.history = broker.history[len(broker.history)-config.StreamHistorySize:]
+	}
+
+	if event.Type != events.NotificationCreated {
+		recipients = append(recipients, broker.connectedSince(connected, event.ActorId)...)
+	}
+
+	for _, userId := range recipients {
+		for subscription := range broker.subscriptions[userId] {
+			select {
+			case subscription.entries <- entry:
+			default:
+				broker.drop(subscription)
+			}
+		}
+	}
+}
+
+// recipients returns the users concerned by an event: the user of a notification, and the actor
+// of a publication or repost along with its followers among the connected users, also returned,
+// looked up without holding the lock
+func (broker *Broker) recipients(event events.Event) ([]uint64, []uint64) {
+
+	if event.Type == events.NotificationCreated {
+		return []uint64{event.UserId}, nil
+	}
+
+	broker.mutex.Lock()
+	connected := make([]uint64, 0, len(broker.subscriptions))
+	for userId := range broker.subscriptions {
+		if userId != event.ActorId {
+			connected = append(connected, userId)
+		}
+	}
+	broker.mutex.Unlock()
+
+	followers, error := broker.users.GetFollowersAmong(event.ActorId, connected)
+	if error != nil {
+		logging.Error("failed to stream event to followers",
+			logging.Fields{"actor_id": event.ActorId, "event": event.Type, "error": error})
+	}
+
+	return append(followers, event.ActorId), connected
+}
+
+// connectedSince returns the users other than actorId connected since the connected ones were
+// listed, Message telling whether an event concerns them
+func (broker *Broker) connectedSince(connected []uint64, actorId uint64) []uint64 {
+
+	listed := make(map[uint64]bool, len(connected))
+	for _, userId := range connected {
+		listed[userId] = true
+	}
+
+	var since []uint64
+	for userId := range broker.subscriptions {
+		if userId != actorId && !listed[userId] {
+			since = append(since, userId)
+		}
+	}
+	return since
+}
+
+// Connect subscribes a user to new events, with a backlog of the recent events following the
+// event of id lastEventId, if any
+func (broker *Broker) Connect(userId uint64, lastEventId uint64) *Subscription {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	entries := make(chan Entry, config.StreamBufferSize)
+	subscription := &Subscription{UserId: userId, Entries: entries, entries: entries}
+
+	if lastEventId != 0 {
+		for _, entry := range broker.history {
+			if entry.ID > lastEventId {
+				subscription.Backlog = append(subscription.Backlog, entry)
+			}
+		}
+	}
+
+	if broker.subscriptions[userId] == nil {
+		broker.subscriptions[userId] = make(map[*Subscription]bool)
+	}
+	broker.subscriptions[userId][subscription] = true
+
+	return subscription
+}
+
+// Disconnect ends a subscription, closing its entries
+func (broker *Broker) Disconnect(subscription *Subscription) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.drop(subscription)
+}
+
+// drop removes a subscription, once
+func (broker *Broker) drop(subscription *Subscription) {
+
+	subscriptions := broker.subscriptions[subscription.UserId]
+	if !subscriptions[subscription] {
+		return
+	}
+
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(broker.subscriptions, subscription.UserId)
+	}
+	close(subscription.entries)
+}
+
+// Message returns the message pushed to a user for an entry, telling whether the event concerns
+// the user: its own notifications, and publications and reposts of itself and the users it
+// follows, visible to it and of users it neither blocks nor mutes
+func (broker *Broker) Message(entry Entry, userId uint64) (Message, bool, error) {
+
+	event := entry.Event
+
+	switch event.Type {
+	case events.NotificationCreated:
+		return Message{entry.ID, MessageNotification, event.Data}, event.UserId == userId, nil
+	case events.PublicationCreated, events.PublicationReposted:
+		return broker.feedMessage(entry, userId)
+	}
+
+	return Message{}, false, nil
+}
+
+// feedMessage returns the message pushed to a user for a publication or repost entering its feed
+func (broker *Broker) feedMessage(entry Entry, userId uint64) (Message, bool, error) {
+
+	event := entry.Event
+
+	if event.ActorId != userId {
+		following, error := broker.users.IsFollower(event.ActorId, userId)
+		if error != nil || !following {
+			return Message{}, false, error
+		}
+
+		silenced, error := broker.silenced(event.ActorId, userId)
+		if error != nil || silenced {
+			return Message{}, false, error
+		}
+	}
+
+	publication, error := broker.publications.GetPublicationById(event.PublicationId, userId)
+	if error != nil || publication.ID == 0 {
+		return Message{}, false, error
+	}
+
+	if event.Type == events.PublicationReposted {
+		if publication.AuthorId != userId {
+			silenced, error := broker.silenced(publication.AuthorId, userId)
+			if error != nil || silenced {
+				return Message{}, false, error
+			}
+		}
+
+		reposter, error := broker.users.GetUserById(event.ActorId)
+		if error != nil || reposter.ID == 0 {
+			return Message{}, false, error
+		}
+
+		repostedAt := event.CreatedAt
+		publication.RepostedById = reposter.ID
+		publication.RepostedBy = reposter.Nick
+		publication.RepostedAt = &repostedAt
+	}
+
+	return Message{entry.ID, MessagePublication, publication}, true, nil
+}
+
+// silenced tells if a user is muted or blocked by viewerId, or blocks viewerId
+func (broker *Broker) silenced(userId uint64, viewerId uint64) (bool, error) {
+
+	blocked, error := broker.users.IsBlocked(userId, viewerId)
+	if error != nil || blocked {
+		return blocked, error
+	}
+
+	return broker.users.IsMuted(viewerId, userId)
+}
@@ -0,0 +1,141 @@
+package streams
+
+import (
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"fmt"
+	"testing"
+)
+
+// newTestBroker returns a broker over in-memory repositories holding count users
+func newTestBroker(t *testing.T, count int) (*Broker, persistence.Repositories) {
+	t.Helper()
+
+	repositories := persistence.NewMemoryRepositories()
+	for index := 1; index <= count; index++ {
+		if _, error := repositories.Users.Create(models.User{
+			Name: fmt.Sprintf("user%d", index), Nick: fmt.Sprintf("user%d", index),
+			Email: fmt.Sprintf("user%d@devbook.dev", index),
+		}); error != nil {
+			t.Fatal(error)
+		}
+	}
+
+	return NewBroker(repositories.Users, repositories.Publications), repositories
+}
+
+func TestBrokerReplaysRecentEvents(t *testing.T) {
+	config.StreamHistorySize = 3
+	config.StreamBufferSize = 10
+	broker, _ := newTestBroker(t, 1)
+
+	for index := 0; index < 5; index++ {
+		broker.Handle(events.Event{Type: events.PublicationCreated, ActorId: 1})
+	}
+	broker.Handle(events.Event{Type: events.PublicationLiked, ActorId: 1})
+
+	if subscription := broker.Connect(1, 0); len(subscription.Backlog) != 0 {
+		t.Fatalf("expected no backlog without a last event, got %+v", subscription.Backlog)
+	}
+
+	subscription := broker.Connect(1, 3)
+	if len(subscription.Backlog) != 2 || subscription.Backlog[0].ID != 4 || subscription.Backlog[1].ID != 5 {
+		t.Fatalf("unexpected backlog: %+v", subscription.Backlog)
+	}
+
+	if subscription = broker.Connect(1, 1); len(subscription.Backlog) != 3 || subscription.Backlog[0].ID != 3 {
+		t.Fatalf("expected the backlog limited to the history: %+v", subscription.Backlog)
+	}
+}
+
+func TestBrokerRoutesEventsToTheirUsers(t *testing.T) {
+	config.StreamHistorySize = 10
+	config.StreamBufferSize = 10
+	broker, repositories := newTestBroker(t, 3)
+	if _, error := repositories.Users.FollowUser(2, 1); error != nil {
+		t.Fatal(error)
+	}
+
+	ada := broker.Connect(1, 0)
+	grace := broker.Connect(2, 0)
+	linus := broker.Connect(3, 0)
+
+	broker.Handle(events.Event{Type: events.NotificationCreated, UserId: 2})
+	broker.Handle(events.Event{Type: events.PublicationCreated, ActorId: 2})
+	broker.Handle(events.Event{Type: events.PublicationLiked, ActorId: 2, UserId: 3})
+
+	if len(ada.Entries) != 1 || len(grace.Entries) != 2 || len(linus.Entries) != 0 {
+		t.Fatalf("expected notifications queued to their user and publications to their author and followers, "+
+			"got %d, %d and %d entries", len(ada.Entries), len(grace.Entries), len(linus.Entries))
+	}
+	if len(broker.history) != 2 {
+		t.Fatalf("expected other events left out of the history, got %+v", broker.history)
+	}
+}
+
+func TestBrokerDropsSlowAndDisconnectedSubscriptions(t *testing.T) {
+	config.StreamHistorySize = 10
+	config.StreamBufferSize = 1
+	broker, _ := newTestBroker(t, 2)
+
+	slow := broker.Connect(1, 0)
+	broker.Handle(events.Event{Type: events.PublicationCreated, ActorId: 1})
+	broker.Handle(events.Event{Type: events.PublicationCreated, ActorId: 1})
+
+	if entry, open := <-slow.Entries; !open || entry.ID != 1 {
+		t.Fatalf("expected the queued entry before the subscription closes, got %+v", entry)
+	}
+	if _, open := <-slow.Entries; open {
+		t.Fatal("expected a full subscription to be dropped")
+	}
+
+	gone := broker.Connect(2, 0)
+	broker.Disconnect(gone)
+	broker.Disconnect(gone)
+	broker.Disconnect(slow)
+
+	if _, open := <-gone.Entries; open {
+		t.Fatal("expected a disconnected subscription to be closed")
+	}
+	if len(broker.subscriptions) != 0 {
+		t.Fatalf("expected no subscriptions left, got %+v", broker.subscriptions)
+	}
+}
+
+// lookupUserStore calls lookup each time followers are looked up
+type lookupUserStore struct {
+	persistence.UserStore
+	lookup func()
+}
+
+// GetFollowersAmong calls lookup before looking followers up
+func (store lookupUserStore) GetFollowersAmong(followedId uint64, userIds []uint64) ([]uint64, error) {
+	store.lookup()
+	return store.UserStore.GetFollowersAmong(followedId, userIds)
+}
+
+func TestBrokerLooksFollowersUpWithoutTheLock(t *testing.T) {
+	config.StreamHistorySize = 10
+	config.StreamBufferSize = 10
+	broker, repositories := newTestBroker(t, 3)
+	if _, error := repositories.Users.FollowUser(2, 1); error != nil {
+		t.Fatal(error)
+	}
+
+	var late *Subscription
+	broker.users = lookupUserStore{repositories.Users, func() {
+		if late == nil {
+			late = broker.Connect(3, 0)
+		}
+	}}
+
+	ada := broker.Connect(1, 0)
+	broker.Handle(events.Event{Type: events.PublicationCreated, ActorId: 2})
+
+	if len(ada.Entries) != 1 || len(late.Entries) != 1 {
+		t.Fatalf("expected the entry queued to followers and users connected during the lookup, got %d and %d",
+			len(ada.Entries), len(late.Entries))
+	}
+}
@@ -0,0 +1,95 @@
+package webhooks
+
+import (
+	"context"
+	"devbook/src/config"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenAddress is the error of webhooks targeting addresses of the network the API runs in
+var ErrForbiddenAddress = errors.New("Webhook URLs cannot target loopback, link-local or private addresses")
+
+// privateNetworks are the private, shared and reserved networks, along with the current network,
+// the limited broadcast address and the NAT64 prefix, whose addresses map to IPv4 ones
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "172.16.0.0/12", "192.0.0.0/24", "192.0.2.0/24",
+	"192.168.0.0/16", "198.18.0.0/15", "198.51.100.0/24", "203.0.113.0/24", "240.0.0.0/4",
+	"255.255.255.255/32", "64:ff9b::/96", "2001:db8::/32", "fc00::/7")
+
+// CheckURL checks each address the host of a webhook URL resolves to is allowed
+func CheckURL(ctx context.Context, rawURL string) error {
+
+	parsed, error := url.Parse(rawURL)
+	if error != nil {
+		return errors.New("Invalid URL")
+	}
+
+	addresses, error := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if error != nil || len(addresses) == 0 {
+		return errors.New("Cannot resolve the host of the URL")
+	}
+
+	for _, address := range addresses {
+		if !allowed(address.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+
+	return nil
+}
+
+// allowed tells if deliveries may be posted to an address: one that is neither loopback,
+// link-local, private, unspecified nor multicast, unless in config.WebhookAllowedNetworks
+func allowed(ip net.IP) bool {
+
+	for _, network := range config.WebhookAllowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// control checks the address of each connection of the dispatcher's client is allowed, whatever
+// the host of a webhook URL resolves to by the time of the delivery
+func control(network string, address string, _ syscall.RawConn) error {
+
+	host, _, error := net.SplitHostPort(address)
+	if error != nil {
+		return error
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+// parseNetworks parses networks in CIDR notation
+func parseNetworks(cidrs ...string) []*net.IPNet {
+
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, error := net.ParseCIDR(cidr)
+		if error != nil {
+			panic(error)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
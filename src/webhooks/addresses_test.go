@@ -0,0 +1,24 @@
+package webhooks
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+
+	for _, address := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		if !allowed(net.ParseIP(address)) {
+			t.Errorf("expected %s allowed", address)
+		}
+	}
+
+	for _, address := range []string{
+		"127.0.0.1", "10.1.2.3", "169.254.169.254", "192.168.1.1", "198.18.0.1", "198.19.255.255",
+		"240.0.0.1", "255.255.255.255", "::1", "fd00::1", "64:ff9b::a9fe:a9fe", "64:ff9b::7f00:1",
+	} {
+		if allowed(net.ParseIP(address)) {
+			t.Errorf("expected %s refused", address)
+		}
+	}
+}
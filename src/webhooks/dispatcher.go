@@ -0,0 +1,469 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"devbook/src/config"
+	"devbook/src/events"
+	"devbook/src/logging"
+	"devbook/src/models"
+	"devbook/src/persistence"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// EventHeader header carrying the event type of a delivery
+	EventHeader = "X-Devbook-Event"
+	// DeliveryHeader header carrying the id of a delivery, the same for each of its attempts
+	DeliveryHeader = "X-Devbook-Delivery"
+	// SignatureHeader header carrying the HMAC-SHA256 of a payload keyed by the webhook secret, as
+	// returned by Sign
+	SignatureHeader = "X-Devbook-Signature"
+)
+
+// batchSize pending deliveries read from the queue at once
+const batchSize = 100
+
+// maxErrorLength characters of the last error of a delivery kept at most
+const maxErrorLength = 255
+
+// Sign returns the signature of a payload with the secret of a webhook
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher queues deliveries of published events to the webhooks subscribed to them, and posts
+// pending deliveries, both in the background between Start and Stop, retrying failed ones until
+// they are dead; deliveries are claimed before they are posted, so that each is posted by a single
+// instance of the API at a time, and posted by a bounded pool of workers, one per webhook
+type Dispatcher struct {
+	webhooks     persistence.WebhookStore
+	users        persistence.UserStore
+	publications persistence.PublicationStore
+	client       *http.Client
+	wakeups      chan struct{}
+	arrivals     chan struct{}
+	workers      chan struct{}
+	mutex        sync.Mutex
+	handled      []events.Event
+	busy         map[uint64]bool
+	ctx          context.Context
+	cancel       context.CancelFunc
+	running      sync.WaitGroup
+}
+
+// NewDispatcher factory
+func NewDispatcher(webhooks persistence.WebhookStore, users persistence.UserStore,
+	publications persistence.PublicationStore) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		webhooks:     webhooks,
+		users:        users,
+		publications: publications,
+		client: &http.Client{
+			Timeout: config.WebhookTimeout,
+			// connects to allowed addresses only, without proxies that would connect for it
+			Transport: &http.Transport{DialContext: (&net.Dialer{Control: control}).DialContext},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wakeups:  make(chan struct{}, 1),
+		arrivals: make(chan struct{}, 1),
+		workers:  make(chan struct{}, config.WebhookWorkers),
+		busy:     make(map[uint64]bool),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Subscribe makes the dispatcher queue deliveries of the events published on bus
+func (dispatcher *Dispatcher) Subscribe(bus *events.Bus) {
+	bus.Subscribe(dispatcher.Handle)
+}
+
+// Start queues the deliveries of handled events and posts pending deliveries in the background
+// until Stop, beginning with those left pending by a previous run; it is called once
+func (dispatcher *Dispatcher) Start() {
+	dispatcher.running.Add(2)
+	go dispatcher.queue()
+	go dispatcher.run()
+}
+
+// Stop ends the background posting of deliveries, cancelling attempts in progress and waiting for
+// their claims to be released and for the deliveries of the events handled so far to be queued;
+// deliveries queued afterwards remain pending until the next Start
+func (dispatcher *Dispatcher) Stop() {
+	dispatcher.cancel()
+	dispatcher.running.Wait()
+}
+
+// Handle keeps an event webhooks can subscribe to, its deliveries being queued in the background
+// rather than by the publisher
+func (dispatcher *Dispatcher) Handle(event events.Event) {
+
+	if userId, _ := recipient(event); userId == 0 {
+		return
+	}
+
+	dispatcher.mutex.Lock()
+	dispatcher.handled = append(dispatcher.handled, event)
+	dispatcher.mutex.Unlock()
+
+	select {
+	case dispatcher.arrivals <- struct{}{}:
+	default:
+	}
+}
+
+// Retry queues a dead delivery again
+func (dispatcher *Dispatcher) Retry(delivery models.WebhookDelivery) error {
+
+	delivery.Retry(time.Now())
+	if error := dispatcher.webhooks.UpdateDelivery(delivery); error != nil {
+		return error
+	}
+
+	dispatcher.wake()
+	return nil
+}
+
+// queue queues the deliveries of the events handled as they arrive, logging failures, until Stop
+func (dispatcher *Dispatcher) queue() {
+	defer dispatcher.running.Done()
+
+	for {
+		select {
+		case <-dispatcher.ctx.Done():
+		case <-dispatcher.arrivals:
+		}
+
+		dispatcher.mutex.Lock()
+		handled := dispatcher.handled
+		dispatcher.handled = nil
+		dispatcher.mutex.Unlock()
+
+		for _, event := range handled {
+			if error := dispatcher.enqueue(event); error != nil {
+				logging.Error("failed to queue webhook deliveries", logging.Fields{"event": event.Type, "error": error})
+			}
+		}
+
+		if dispatcher.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// enqueue queues a delivery of an event to each webhook subscribed to it
+func (dispatcher *Dispatcher) enqueue(event events.Event) error {
+
+	userId, eventType := recipient(event)
+	if userId == 0 {
+		return nil
+	}
+
+	webhooks, error := dispatcher.webhooks.GetSubscribedWebhooks(userId, eventType)
+	if error != nil || len(webhooks) == 0 {
+		return error
+	}
+
+	payload, found, error := dispatcher.payload(event, userId, eventType)
+	if error != nil || !found {
+		return error
+	}
+
+	body, error := json.Marshal(payload)
+	if error != nil {
+		return error
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		if _, error = dispatcher.webhooks.CreateDelivery(models.WebhookDelivery{
+			WebhookId:     webhook.ID,
+			EventType:     eventType,
+			Payload:       body,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		}); error != nil {
+			return error
+		}
+	}
+
+	dispatcher.wake()
+	return nil
+}
+
+// recipient returns the user whose webhooks receive an event along with the webhook event type, or
+// zero when webhooks cannot subscribe to the event
+func recipient(event events.Event) (uint64, string) {
+
+	switch event.Type {
+	case events.PublicationCreated:
+		return event.ActorId, models.WebhookPublicationCreated
+	case events.PublicationUpdated:
+		return event.UserId, models.WebhookPublicationUpdated
+	case events.PublicationDeleted:
+		return event.UserId, models.WebhookPublicationDeleted
+	case events.UserFollowed:
+		return event.UserId, models.WebhookUserFollowed
+	case events.FollowApproved:
+		return event.ActorId, models.WebhookUserFollowed
+	case events.PublicationLiked:
+		return event.UserId, models.WebhookPublicationLiked
+	}
+
+	return 0, ""
+}
+
+// payload returns the payload of an event posted to the webhooks of a user, telling whether what
+// it concerns still exists
+func (dispatcher *Dispatcher) payload(event events.Event, userId uint64, eventType string) (models.WebhookPayload, bool, error) {
+
+	payload := models.WebhookPayload{Type: eventType, CreatedAt: event.CreatedAt}
+
+	if deleted, isPublication := event.Data.(models.Publication); isPublication {
+		payload.Publication = &deleted
+	} else if event.PublicationId != 0 {
+		publication, error := dispatcher.publications.GetPublicationById(event.PublicationId, userId)
+		if error != nil || publication.ID == 0 {
+			return payload, false, error
+		}
+		payload.Publication = &publication
+	}
+
+	var otherId uint64
+	switch event.Type {
+	case events.UserFollowed, events.PublicationLiked:
+		otherId = event.ActorId
+	case events.FollowApproved:
+		otherId = event.UserId
+	}
+
+	if otherId != 0 {
+		user, error := dispatcher.users.GetUserById(otherId)
+		if error != nil || user.ID == 0 {
+			return payload, false, error
+		}
+		payload.User = &models.User{ID: user.ID, Name: user.Name, Nick: user.Nick}
+	}
+
+	return payload, true, nil
+}
+
+// wake makes the background worker look for due deliveries
+func (dispatcher *Dispatcher) wake() {
+	select {
+	case dispatcher.wakeups <- struct{}{}:
+	default:
+	}
+}
+
+// run posts due deliveries, waiting for the next attempt or a wake up in between, until Stop
+func (dispatcher *Dispatcher) run() {
+	defer dispatcher.running.Done()
+
+	for {
+		next, error := dispatcher.deliverPending()
+		if error != nil {
+			logging.Error("failed to deliver webhooks", logging.Fields{"error": error})
+			next = time.Now().Add(config.WebhookRetryDelay)
+		}
+
+		if stopped := dispatcher.wait(next); stopped {
+			return
+		}
+	}
+}
+
+// wait waits for next, a wake up or Stop, without a deadline when next is zero, telling whether
+// Stop was called
+func (dispatcher *Dispatcher) wait(next time.Time) bool {
+
+	var due <-chan time.Time
+	if !next.IsZero() {
+		timer := time.NewTimer(time.Until(next))
+		defer timer.Stop()
+		due = timer.C
+	}
+
+	select {
+	case <-dispatcher.ctx.Done():
+		return true
+	case <-dispatcher.wakeups:
+	case <-due:
+	}
+	return false
+}
+
+// deliverPending hands the due deliveries of idle webhooks to idle workers, returning when to look
+// again: now after handing deliveries over, the next attempt of a pending delivery, or the zero time
+// to wait for a wake up
+func (dispatcher *Dispatcher) deliverPending() (time.Time, error) {
+
+	now := time.Now()
+	deliveries, error := dispatcher.webhooks.GetPendingDeliveries(now, dispatcher.busyWebhooks(), batchSize)
+	if error != nil || len(deliveries) == 0 {
+		return time.Time{}, error
+	}
+
+	var next time.Time
+	for _, delivery := range deliveries {
+		if delivery.NextAttemptAt != nil && delivery.NextAttemptAt.After(now) {
+			if next.IsZero() {
+				next = *delivery.NextAttemptAt
+			}
+			break
+		}
+
+		if dispatcher.ctx.Err() != nil {
+			return time.Time{}, nil
+		}
+
+		dispatcher.mutex.Lock()
+		busy := dispatcher.busy[delivery.WebhookId]
+		dispatcher.mutex.Unlock()
+		if busy {
+			continue
+		}
+
+		select {
+		case dispatcher.workers <- struct{}{}:
+		default:
+			return next, nil
+		}
+
+		claimed, error := dispatcher.webhooks.ClaimDelivery(delivery.ID, now, now.Add(2*config.WebhookTimeout))
+		if error != nil || !claimed {
+			<-dispatcher.workers
+			if error != nil {
+				return time.Time{}, error
+			}
+			continue
+		}
+
+		dispatcher.mutex.Lock()
+		dispatcher.busy[delivery.WebhookId] = true
+		dispatcher.mutex.Unlock()
+
+		dispatcher.running.Add(1)
+		go dispatcher.work(delivery)
+		next = now
+	}
+
+	return next, nil
+}
+
+// busyWebhooks returns the webhooks with a delivery being posted
+func (dispatcher *Dispatcher) busyWebhooks() []uint64 {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	webhookIds := make([]uint64, 0, len(dispatcher.busy))
+	for webhookId := range dispatcher.busy {
+		webhookIds = append(webhookIds, webhookId)
+	}
+	return webhookIds
+}
+
+// work attempts a claimed delivery, then frees its worker and webhook for the next delivery
+func (dispatcher *Dispatcher) work(delivery models.WebhookDelivery) {
+	defer dispatcher.running.Done()
+
+	if error := dispatcher.attempt(delivery); error != nil {
+		logging.Error("failed to deliver webhook", logging.Fields{"delivery_id": delivery.ID, "error": error})
+	}
+
+	dispatcher.mutex.Lock()
+	delete(dispatcher.busy, delivery.WebhookId)
+	dispatcher.mutex.Unlock()
+
+	<-dispatcher.workers
+	dispatcher.wake()
+}
+
+// attempt posts a claimed delivery to its webhook and records the outcome, releasing the claim
+// without counting the attempt when the dispatcher stops meanwhile, and making the delivery dead
+// when its webhook is gone
+func (dispatcher *Dispatcher) attempt(delivery models.WebhookDelivery) error {
+
+	webhook, error := dispatcher.webhooks.GetWebhookById(delivery.WebhookId)
+	if error != nil {
+		return error
+	}
+
+	if webhook.ID == 0 {
+		delivery.Fail(0, "Webhook not found", time.Now(), 0, 0)
+		return dispatcher.webhooks.UpdateDelivery(delivery)
+	}
+
+	statusCode, error := dispatcher.post(webhook, delivery)
+	if dispatcher.ctx.Err() != nil {
+		return dispatcher.webhooks.UpdateDelivery(delivery)
+	}
+	now := time.Now()
+
+	switch {
+	case error != nil:
+		delivery.Fail(0, truncate(error.Error()), now, config.WebhookMaxAttempts, config.WebhookRetryDelay)
+	case statusCode < 200 || statusCode > 299:
+		delivery.Fail(statusCode, fmt.Sprintf("Unexpected status %d", statusCode), now,
+			config.WebhookMaxAttempts, config.WebhookRetryDelay)
+	default:
+		delivery.Succeed(statusCode, now)
+	}
+
+	return dispatcher.webhooks.UpdateDelivery(delivery)
+}
+
+// post sends the signed payload of a delivery to a webhook, returning the response status
+func (dispatcher *Dispatcher) post(webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+
+	request, error := http.NewRequestWithContext(dispatcher.ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if error != nil {
+		return 0, error
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "devbook-webhooks")
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, strconv.FormatUint(delivery.ID, 10))
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, delivery.Payload))
+
+	response, error := dispatcher.client.Do(request)
+	if error != nil {
+		return 0, error
+	}
+	defer response.Body.Close()
+
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64*1024))
+
+	return response.StatusCode, nil
+}
+
+// truncate shortens an error message to the characters kept in the queue
+func truncate(message string) string {
+
+	count := 0
+	for index := range message {
+		if count == maxErrorLength {
+			return message[:index]
+		}
+		count++
+	}
+	return message
+}
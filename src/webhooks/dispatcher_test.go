@@ -0,0 +1,19 @@
+package webhooks
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateKeepsWholeCharacters(t *testing.T) {
+
+	if message := truncate("connection refused"); message != "connection refused" {
+		t.Fatalf("expected a short message kept, got %q", message)
+	}
+
+	message := truncate(strings.Repeat("é", maxErrorLength+1))
+	if !utf8.ValidString(message) || utf8.RuneCountInString(message) != maxErrorLength {
+		t.Fatalf("expected %d whole characters, got %q", maxErrorLength, message)
+	}
+}